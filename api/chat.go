@@ -1,17 +1,17 @@
 package api
 
 import (
-	"os"
 	"fmt"
 	"github.com/pwh-pwh/aiwechat-vercel/chat"
 	"golang.org/x/text/encoding/simplifiedchinese"
 	"net/http"
+	"os"
 )
 
 func Chat(rw http.ResponseWriter, req *http.Request) {
+	// 未配置accessCode时测试接口不校验code，与旧版本一致
 	accessCode := os.Getenv("accessCode")
-	code := req.URL.Query().Get("code")
-	if code != accessCode {
+	if req.URL.Query().Get("code") != accessCode {
 		fmt.Fprint(rw, "No valid query code provided.")
 		return
 	}
@@ -21,8 +21,13 @@ func Chat(rw http.ResponseWriter, req *http.Request) {
 	if msg == "" {
 		msg = "用10个字介绍你自己"
 	}
+	// 默认使用admin的对话记录，可以用user参数区分不同的测试会话
+	userId := "admin"
+	if user := req.URL.Query().Get("user"); user != "" {
+		userId = "api:" + user
+	}
 	bot := chat.GetChatBot(botType)
-	rpn := bot.Chat("admin", msg)
+	rpn := bot.Chat(userId, msg)
 	s, err := simplifiedchinese.GBK.NewEncoder().String(rpn)
 	if err != nil {
 		fmt.Fprint(rw, err.Error())
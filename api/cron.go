@@ -0,0 +1,46 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/pwh-pwh/aiwechat-vercel/chat"
+	"github.com/pwh-pwh/aiwechat-vercel/config"
+)
+
+// Cron 定时任务接口，发送到期的待办、定时提醒和价格提醒，需要配置CRON_SECRET
+// 可以由vercel cron或其它定时服务每分钟调用：curl -H "Authorization: Bearer CRON_SECRET" 你的域名/api/cron
+func Cron(rw http.ResponseWriter, req *http.Request) {
+	if !checkCronSecret(rw, req) {
+		return
+	}
+	result := map[string]any{}
+	sent, err := chat.SendTodoReminders()
+	result["todo"] = sent
+	if err != nil {
+		result["todoError"] = err.Error()
+	}
+	sent, err = chat.SendReminders()
+	result["remind"] = sent
+	if err != nil {
+		result["remindError"] = err.Error()
+	}
+	sent, err = chat.SendPriceAlerts()
+	result["alert"] = sent
+	if err != nil {
+		result["alertError"] = err.Error()
+	}
+	rw.Header().Set("Content-Type", "application/json; charset=utf-8")
+	json.NewEncoder(rw).Encode(result)
+}
+
+func checkCronSecret(rw http.ResponseWriter, req *http.Request) bool {
+	secret := config.GetCronSecret()
+	if secret == "" || (req.Header.Get("Authorization") != "Bearer "+secret && req.URL.Query().Get("secret") != secret) {
+		rw.WriteHeader(http.StatusUnauthorized)
+		fmt.Fprint(rw, "No valid cron secret provided.")
+		return false
+	}
+	return true
+}
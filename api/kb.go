@@ -0,0 +1,124 @@
+package api
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/pwh-pwh/aiwechat-vercel/rag"
+)
+
+// 单个知识库文件最大1MB
+const maxKbFileSize = 1 << 20
+
+// Kb 知识库管理接口，需要配置accessCode
+// GET 查看已上传的文档，POST 上传markdown(multipart表单file字段，可多个；或请求体为文件内容并通过name参数指定文件名)，DELETE ?name= 删除文档
+func Kb(rw http.ResponseWriter, req *http.Request) {
+	if !checkAdminCode(rw, req) {
+		return
+	}
+
+	switch req.Method {
+	case http.MethodGet:
+		list, err := rag.List()
+		if err != nil {
+			rw.WriteHeader(http.StatusInternalServerError)
+			fmt.Fprint(rw, err.Error())
+			return
+		}
+		if len(list) == 0 {
+			fmt.Fprint(rw, "知识库为空")
+			return
+		}
+		names := make([]string, 0, len(list))
+		for name := range list {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			fmt.Fprintf(rw, "%s: %d个片段\n", name, list[name])
+		}
+	case http.MethodPost:
+		files, err := readKbFiles(req)
+		if err != nil {
+			rw.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(rw, err.Error())
+			return
+		}
+		for name, content := range files {
+			count, warning, err := rag.Add(name, content)
+			if err != nil {
+				fmt.Fprintf(rw, "%s: 上传失败 %v\n", name, err)
+				continue
+			}
+			fmt.Fprintf(rw, "%s: 上传成功，%d个片段\n", name, count)
+			if warning != "" {
+				fmt.Fprintf(rw, "%s: %s\n", name, warning)
+			}
+		}
+	case http.MethodDelete:
+		name := req.URL.Query().Get("name")
+		if name == "" {
+			rw.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(rw, "请指定name")
+			return
+		}
+		if err := rag.Remove(name); err != nil {
+			rw.WriteHeader(http.StatusInternalServerError)
+			fmt.Fprint(rw, err.Error())
+			return
+		}
+		fmt.Fprintf(rw, "%s: 删除成功", name)
+	default:
+		rw.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func readKbFiles(req *http.Request) (map[string]string, error) {
+	files := map[string]string{}
+	if strings.HasPrefix(req.Header.Get("Content-Type"), "multipart/form-data") {
+		if err := req.ParseMultipartForm(maxKbFileSize * 4); err != nil {
+			return nil, err
+		}
+		for _, header := range req.MultipartForm.File["file"] {
+			if header.Size > maxKbFileSize {
+				return nil, fmt.Errorf("%s 超过1MB", header.Filename)
+			}
+			f, err := header.Open()
+			if err != nil {
+				return nil, err
+			}
+			data, err := io.ReadAll(f)
+			f.Close()
+			if err != nil {
+				return nil, err
+			}
+			files[path.Base(header.Filename)] = string(data)
+		}
+	} else {
+		name := req.URL.Query().Get("name")
+		if name == "" {
+			return nil, fmt.Errorf("请通过name参数指定文件名")
+		}
+		data, err := io.ReadAll(io.LimitReader(req.Body, maxKbFileSize+1))
+		if err != nil {
+			return nil, err
+		}
+		if len(data) > maxKbFileSize {
+			return nil, fmt.Errorf("%s 超过1MB", name)
+		}
+		files[name] = string(data)
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("没有上传文件")
+	}
+	for name := range files {
+		if ext := strings.ToLower(path.Ext(name)); ext != ".md" && ext != ".markdown" && ext != ".txt" {
+			return nil, fmt.Errorf("%s 不是markdown文件", name)
+		}
+	}
+	return files, nil
+}
@@ -0,0 +1,84 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/pwh-pwh/aiwechat-vercel/rule"
+)
+
+// Rule 自动回复规则管理接口，需要配置accessCode
+// GET 查看全部规则，POST 新增或更新规则(请求体为一条规则或规则数组的json)，DELETE ?id= 删除规则
+func Rule(rw http.ResponseWriter, req *http.Request) {
+	if !checkAdminCode(rw, req) {
+		return
+	}
+	switch req.Method {
+	case http.MethodGet:
+		rules, err := rule.List()
+		if err != nil {
+			rw.WriteHeader(http.StatusInternalServerError)
+			fmt.Fprint(rw, err.Error())
+			return
+		}
+		rw.Header().Set("Content-Type", "application/json; charset=utf-8")
+		json.NewEncoder(rw).Encode(rules)
+	case http.MethodPost:
+		body, err := io.ReadAll(io.LimitReader(req.Body, 1<<20))
+		if err != nil {
+			rw.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(rw, err.Error())
+			return
+		}
+		var rules []*rule.Rule
+		if strings.HasPrefix(strings.TrimSpace(string(body)), "[") {
+			err = json.Unmarshal(body, &rules)
+		} else {
+			var r rule.Rule
+			err = json.Unmarshal(body, &r)
+			rules = append(rules, &r)
+		}
+		if err != nil {
+			rw.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(rw, "json格式错误: "+err.Error())
+			return
+		}
+		for _, r := range rules {
+			if err := rule.Save(r); err != nil {
+				fmt.Fprintf(rw, "%s: 保存失败 %v\n", r.Id, err)
+				continue
+			}
+			fmt.Fprintf(rw, "%s: 保存成功\n", r.Id)
+		}
+	case http.MethodDelete:
+		id := req.URL.Query().Get("id")
+		if id == "" {
+			rw.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(rw, "请指定id")
+			return
+		}
+		if err := rule.Delete(id); err != nil {
+			rw.WriteHeader(http.StatusInternalServerError)
+			fmt.Fprint(rw, err.Error())
+			return
+		}
+		fmt.Fprintf(rw, "%s: 删除成功", id)
+	default:
+		rw.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// checkAdminCode 管理接口必须配置accessCode，并通过code参数传入
+func checkAdminCode(rw http.ResponseWriter, req *http.Request) bool {
+	accessCode := os.Getenv("accessCode")
+	if accessCode == "" || req.URL.Query().Get("code") != accessCode {
+		rw.WriteHeader(http.StatusForbidden)
+		fmt.Fprint(rw, "No valid query code provided.")
+		return false
+	}
+	return true
+}
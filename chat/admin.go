@@ -0,0 +1,260 @@
+package chat
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pwh-pwh/aiwechat-vercel/client"
+	"github.com/pwh-pwh/aiwechat-vercel/config"
+	"github.com/pwh-pwh/aiwechat-vercel/db"
+	"github.com/pwh-pwh/aiwechat-vercel/limit"
+	"github.com/pwh-pwh/aiwechat-vercel/moderation"
+	"github.com/pwh-pwh/aiwechat-vercel/role"
+)
+
+const (
+	// 客服消息只能发给48小时内与公众号互动过的用户
+	Push_Window = 48 * time.Hour
+	// 群发时同时发送的数量
+	Broadcast_Workers = 10
+)
+
+// MessengerBackend 不为空时替代公众号客服消息接口，用于测试
+var MessengerBackend client.Messenger
+
+func getMessenger() (client.Messenger, error) {
+	if MessengerBackend != nil {
+		return MessengerBackend, nil
+	}
+	return client.NewWxMessenger(config.GetWxAppId(), config.GetWxAppSecret())
+}
+
+// SendText 主动给用户发送文本消息
+func SendText(openId, content string) error {
+	messenger, err := getMessenger()
+	if err != nil {
+		return err
+	}
+	return messenger.SendText(openId, content)
+}
+
+func init() {
+	commands := []*Command{
+		{Name: config.Wx_Command_Ban, Args: []CommandArg{{Name: "openid", Type: Arg_String}}, Usage: "禁止用户使用", Role: role.ADMIN,
+			Handler: func(args *CommandArgs, userId string) string {
+				return setRole(userId, args.String("openid"), role.BLOCKED)
+			}},
+		{Name: config.Wx_Command_Unban, Args: []CommandArg{{Name: "openid", Type: Arg_String}}, Usage: "解除禁止", Role: role.ADMIN,
+			Handler: func(args *CommandArgs, userId string) string {
+				return setRole(userId, args.String("openid"), role.USER)
+			}},
+		{Name: config.Wx_Command_Role, Role: role.ADMIN, Usage: "查看用户角色，带角色时修改角色，不带参数时列出管理员和被禁用户",
+			Args: []CommandArg{
+				{Name: "openid", Type: Arg_String, Optional: true},
+				{Name: "角色", Type: Arg_String, Optional: true, Choices: []string{role.ADMIN, role.USER, role.BLOCKED}},
+			},
+			Handler: func(args *CommandArgs, userId string) string {
+				openId := args.String("openid")
+				if openId == "" {
+					return listRoles()
+				}
+				if !args.Has("角色") {
+					return fmt.Sprintf("%s：%s", openId, role.Get(openId))
+				}
+				return setRole(userId, openId, args.String("角色"))
+			}},
+		{Name: config.Wx_Command_Broadcast, Args: []CommandArg{{Name: "内容", Type: Arg_Text}}, Usage: "给48小时内活跃的用户发送消息", Role: role.ADMIN,
+			Handler: func(args *CommandArgs, userId string) string {
+				content := args.String("内容")
+				return WithTimeChat(userId, config.Wx_Command_Broadcast+" "+content, func(userId, msg string) string {
+					return Broadcast(content)
+				})
+			}},
+		{Name: config.Wx_Command_Stats, Usage: "查看用户统计", Role: role.ADMIN,
+			Handler: func(args *CommandArgs, userId string) string { return Stats() }},
+		{Name: config.Wx_Command_Violation, Args: []CommandArg{{Name: "条数", Type: Arg_Int, Optional: true}}, Usage: "查看最近的限流记录，默认10条", Role: role.ADMIN,
+			Handler: func(args *CommandArgs, userId string) string {
+				n := 10
+				if args.Has("条数") {
+					n = args.Int("条数")
+				}
+				return Violations(n)
+			}},
+		{Name: config.Wx_Command_Audit, Args: []CommandArg{{Name: "条数", Type: Arg_Int, Optional: true}}, Usage: "查看最近的内容审核记录，默认10条", Role: role.ADMIN,
+			Handler: func(args *CommandArgs, userId string) string {
+				n := 10
+				if args.Has("条数") {
+					n = args.Int("条数")
+				}
+				return ModerationLogs(n)
+			}},
+	}
+	for _, cmd := range commands {
+		RegisterCommand(cmd)
+	}
+}
+
+func setRole(operator, openId, r string) string {
+	if openId == operator && r != role.ADMIN {
+		return "不能修改自己的角色"
+	}
+	if err := role.Set(openId, r); err != nil {
+		return err.Error()
+	}
+	return fmt.Sprintf("已将%s设置为%s", openId, r)
+}
+
+func listRoles() string {
+	roles, err := role.List()
+	if err != nil {
+		return err.Error()
+	}
+	if len(roles) == 0 {
+		return "没有管理员和被禁用户"
+	}
+	lines := make([]string, 0, len(roles))
+	for openId, r := range roles {
+		lines = append(lines, fmt.Sprintf("%s：%s", openId, r))
+	}
+	sort.Strings(lines)
+	return strings.Join(lines, "\n")
+}
+
+// activeUsers 返回最后活跃时间在since之后且未被禁用的用户
+func activeUsers(users map[string]int64, since time.Time) []string {
+	var ids []string
+	for openId, t := range users {
+		if t >= since.Unix() && !role.IsBlocked(openId) {
+			ids = append(ids, openId)
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// Broadcast 通过客服消息发送给48小时内活跃的用户
+func Broadcast(content string) string {
+	messenger, err := getMessenger()
+	if err != nil {
+		return err.Error()
+	}
+	users, err := db.GetUsers()
+	if err != nil {
+		return err.Error()
+	}
+	ids := activeUsers(users, time.Now().Add(-Push_Window))
+	if len(ids) == 0 {
+		return "48小时内没有活跃用户"
+	}
+	var (
+		wg     sync.WaitGroup
+		lock   sync.Mutex
+		failed int
+		jobs   = make(chan string)
+	)
+	for i := 0; i < Broadcast_Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for openId := range jobs {
+				if err := messenger.SendText(openId, content); err != nil {
+					fmt.Printf("broadcast to %s error: %v\n", openId, err)
+					lock.Lock()
+					failed++
+					lock.Unlock()
+				}
+			}
+		}()
+	}
+	for _, openId := range ids {
+		jobs <- openId
+	}
+	close(jobs)
+	wg.Wait()
+	return fmt.Sprintf("已发送%d人，失败%d人", len(ids)-failed, failed)
+}
+
+// Violations 最近的限流记录，同一用户连续的记录合并显示
+func Violations(n int) string {
+	if n <= 0 || n > 50 {
+		n = 10
+	}
+	violations, err := limit.Violations(n)
+	if err != nil {
+		return err.Error()
+	}
+	if len(violations) == 0 {
+		return "没有限流记录"
+	}
+	var sb strings.Builder
+	for i := 0; i < len(violations); {
+		v, count := violations[i], 1
+		for i+count < len(violations) && violations[i+count].UserId == v.UserId && violations[i+count].Scope == v.Scope {
+			count++
+		}
+		i += count
+		sb.WriteString(fmt.Sprintf("%s %s %s %s", time.Unix(v.Time, 0).Format("01-02 15:04"), v.UserId, v.Scope, v.Rule))
+		if v.Bot != "" {
+			sb.WriteString(" " + v.Bot)
+		}
+		if count > 1 {
+			sb.WriteString(fmt.Sprintf(" ×%d", count))
+		}
+		sb.WriteString("\n")
+	}
+	return strings.TrimSpace(sb.String())
+}
+
+// ModerationLogs 最近的内容审核记录
+func ModerationLogs(n int) string {
+	if n <= 0 || n > 50 {
+		n = 10
+	}
+	logs, err := moderation.Logs(n)
+	if err != nil {
+		return err.Error()
+	}
+	if len(logs) == 0 {
+		return "没有审核记录"
+	}
+	var sb strings.Builder
+	for _, l := range logs {
+		hits := make([]string, 0, len(l.Hits))
+		for _, hit := range l.Hits {
+			if hit.Word != "" {
+				hits = append(hits, fmt.Sprintf("%s(%s)%s", hit.Category, hit.Word, hit.Action))
+			} else {
+				hits = append(hits, fmt.Sprintf("%s %s", hit.Category, hit.Action))
+			}
+		}
+		sb.WriteString(fmt.Sprintf("%s %s %s %s\n", time.Unix(l.Time, 0).Format("01-02 15:04"), l.UserId, l.Stage, strings.Join(hits, "、")))
+	}
+	return strings.TrimSpace(sb.String())
+}
+
+// Stats 用户数和角色统计
+func Stats() string {
+	users, err := db.GetUsers()
+	if err != nil {
+		return err.Error()
+	}
+	roles, err := role.List()
+	if err != nil {
+		return err.Error()
+	}
+	var admins, blocked int
+	for _, r := range roles {
+		switch r {
+		case role.ADMIN:
+			admins++
+		case role.BLOCKED:
+			blocked++
+		}
+	}
+	now := time.Now()
+	return fmt.Sprintf("用户总数：%d\n24小时活跃：%d\n48小时活跃(可群发)：%d\n管理员：%d\n被禁用户：%d",
+		len(users), len(activeUsers(users, now.Add(-24*time.Hour))), len(activeUsers(users, now.Add(-Push_Window))), admins, blocked)
+}
@@ -0,0 +1,91 @@
+package chat
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/pwh-pwh/aiwechat-vercel/db"
+	"github.com/pwh-pwh/aiwechat-vercel/role"
+)
+
+type fakeMessenger struct {
+	lock sync.Mutex
+	sent map[string]string
+}
+
+func (m *fakeMessenger) SendText(openId, content string) error {
+	if openId == "fail" {
+		return errors.New("45015 response out of time limit")
+	}
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.sent[openId] = content
+	return nil
+}
+
+func useFakeMessenger(t *testing.T) *fakeMessenger {
+	t.Helper()
+	messenger := &fakeMessenger{sent: map[string]string{}}
+	MessengerBackend = messenger
+	t.Cleanup(func() { MessengerBackend = nil })
+	return messenger
+}
+
+func TestAdminCommands(t *testing.T) {
+	t.Setenv("ADMIN_OPENIDS", "boss")
+	t.Setenv("WX_HELP_REPLY", "")
+	t.Cleanup(func() {
+		role.Set("bad", role.USER)
+		role.Set("helper", role.USER)
+	})
+
+	if r, _ := RunCommand("u1", "/ban bad"); r != "没有权限执行该指令" {
+		t.Errorf("user /ban = %q", r)
+	}
+	if strings.Contains(HelpText("u1"), "/ban") || !strings.Contains(HelpText("boss"), "/ban <openid>") {
+		t.Error("admin commands should only be listed for admins")
+	}
+
+	if r, _ := RunCommand("boss", "/ban bad"); !role.IsBlocked("bad") {
+		t.Errorf("/ban = %q", r)
+	}
+	if r, _ := RunCommand("boss", "/ban boss"); r != "不能修改自己的角色" {
+		t.Errorf("ban self = %q", r)
+	}
+	if r, _ := RunCommand("boss", "/role helper admin"); !role.IsAdmin("helper") {
+		t.Errorf("/role = %q", r)
+	}
+	if r, _ := RunCommand("helper", "/role"); !strings.Contains(r, "bad：blocked") || !strings.Contains(r, "boss：admin") {
+		t.Errorf("/role list = %q", r)
+	}
+	if r, _ := RunCommand("helper", "/unban bad"); role.IsBlocked("bad") {
+		t.Errorf("/unban = %q", r)
+	}
+}
+
+func TestBroadcast(t *testing.T) {
+	messenger := useFakeMessenger(t)
+	t.Cleanup(func() {
+		for _, openId := range []string{"active", "fail", "blocked", "stale"} {
+			db.DeleteHashValue(db.USER_KEY, openId)
+		}
+		role.Set("blocked", role.USER)
+	})
+	for _, openId := range []string{"active", "fail", "blocked"} {
+		db.TouchUser(openId)
+	}
+	db.SetHashValue(db.USER_KEY, "stale", "1")
+	role.Set("blocked", role.BLOCKED)
+
+	if r := Broadcast("通知"); r != "已发送1人，失败1人" {
+		t.Errorf("Broadcast = %q", r)
+	}
+	if len(messenger.sent) != 1 || messenger.sent["active"] != "通知" {
+		t.Errorf("sent = %v", messenger.sent)
+	}
+	if r := Stats(); !strings.Contains(r, "用户总数：4") || !strings.Contains(r, "48小时活跃(可群发)：2") || !strings.Contains(r, "被禁用户：1") {
+		t.Errorf("Stats = %q", r)
+	}
+}
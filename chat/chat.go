@@ -1,77 +1,66 @@
 package chat
 
 import (
-	_ "errors"
+	"errors"
 	"fmt"
-	"github.com/pwh-pwh/aiwechat-vercel/client"
 	"os"
-	"strconv"
 	"strings"
 	"time"
 
 	"github.com/google/generative-ai-go/genai"
 
 	"github.com/pwh-pwh/aiwechat-vercel/db"
+	"github.com/pwh-pwh/aiwechat-vercel/moderation"
 	"github.com/sashabaranov/go-openai"
 
 	"github.com/pwh-pwh/aiwechat-vercel/config"
 	"github.com/silenceper/wechat/v2/officialaccount/message"
 )
 
-var actionMap = map[string]func(param, userId string) string{
-	config.Wx_Command_Help: func(param, userId string) string {
-		return config.GetWxHelpReply()
-	},
-	config.Wx_Command_Gpt: func(param, userId string) string {
-		return SwitchUserBot(userId, config.Bot_Type_Gpt)
-	},
-	config.Wx_Command_Spark: func(param, userId string) string {
-		return SwitchUserBot(userId, config.Bot_Type_Spark)
-	},
-	config.Wx_Command_Qwen: func(param, userId string) string {
-		return SwitchUserBot(userId, config.Bot_Type_Qwen)
-	},
-	config.Wx_Command_Gemini: func(param, userId string) string {
-		return SwitchUserBot(userId, config.Bot_Type_Gemini)
-	},
-
-	config.Wx_Command_Prompt:    SetPrompt,
-	config.Wx_Command_RmPrompt:  RmPrompt,
-	config.Wx_Command_GetPrompt: GetPrompt,
-
-	config.Wx_Command_SetModel: SetModel,
-	config.Wx_Command_GetModel: GetModel,
-	config.Wx_Command_Clear:    ClearMsg,
-
-	config.Wx_Todo_List: GetTodoList,
-	config.Wx_Todo_Add:  AddTodo,
-	config.Wx_Todo_Del:  DelTodo,
-
-	config.Wx_Coin: GetCoin,
-}
-
+// DoAction 执行指令；不是指令时，带链接的消息读取文档或总结链接，开启自动搜索时联网搜索
 func DoAction(userId, msg string) (r string, flag bool) {
-	action, param, flag := isAction(msg)
-	if flag {
-		f := actionMap[action]
-		r = f(param, userId)
+	if r, flag = RunCommand(userId, msg); flag {
+		return
 	}
-	return
-}
-
-func isAction(msg string) (string, string, bool) {
-	for key := range actionMap {
-		if strings.HasPrefix(msg, key) {
-			return msg[:len(key)], strings.TrimSpace(msg[len(key):]), true
+	if IsTranslateMode(userId) {
+		// 翻译模式下普通消息都按 /fy 处理
+		return Translate(msg, userId), true
+	}
+	if pageUrl, question := ExtractUrl(msg); pageUrl != "" {
+		// 消息中带有链接时读取文档或总结链接内容，其余文字作为对文章的提问
+		if IsDocumentUrl(pageUrl) {
+			r = LoadDocument(userId, pageUrl)
+		} else {
+			r = SummarizeUrl(userId, pageUrl, question)
 		}
+		flag = true
+	} else if db.IsAutoSearch(userId) {
+		// 开启自动搜索后普通消息按 /search 处理
+		r, flag = Search(msg, userId), true
 	}
-	return "", "", false
+	return
 }
 
 type BaseChat interface {
 	Chat(userID string, msg string) string
 	HandleMediaMsg(msg *message.MixMessage) string
 }
+
+// Completer 单轮问答，不读写历史对话，供搜索等需要自行拼接prompt的功能使用
+type Completer interface {
+	Complete(userID, system, prompt string) (string, error)
+}
+
+// Complete 使用用户当前的bot完成一次单轮问答
+func Complete(userID, system, prompt string) (string, error) {
+	bot := GetChatBot(config.GetUserBotType(userID))
+	completer, ok := bot.(Completer)
+	if !ok {
+		return "", errors.New("当前bot不支持该功能")
+	}
+	return completer.Complete(userID, system, prompt)
+}
+
 type SimpleChat struct {
 }
 
@@ -83,9 +72,14 @@ func (s SimpleChat) HandleMediaMsg(msg *message.MixMessage) string {
 	switch msg.MsgType {
 	case message.MsgTypeImage:
 		return msg.PicURL
+	case message.MsgTypeLink:
+		if IsDocumentUrl(msg.URL) {
+			return LoadDocument(string(msg.FromUserName), msg.URL)
+		}
+		return SummarizeUrl(string(msg.FromUserName), msg.URL, "")
 	case message.MsgTypeEvent:
 		if msg.Event == message.EventSubscribe {
-			subText := config.GetWxSubscribeReply() + config.GetWxHelpReply()
+			subText := config.GetWxSubscribeReply() + HelpText(string(msg.FromUserName))
 			if subText == "" {
 				subText = "哇，又有帅哥美女关注我啦😄"
 			}
@@ -147,42 +141,6 @@ func GetPrompt(param string, userId string) string {
 	return fmt.Sprintf("%s 获取prompt成功，prompt：%s", botType, prompt)
 }
 
-func GetTodoList(param string, userId string) string {
-	list, err := db.GetTodoList(userId)
-	if err != nil {
-		return err.Error()
-	}
-	return list
-}
-
-func AddTodo(param, userId string) string {
-	err := db.AddTodoList(userId, param)
-	if err != nil {
-		return err.Error()
-	}
-	return "添加成功"
-}
-
-func DelTodo(param, userId string) string {
-	index, err := strconv.Atoi(param)
-	if err != nil {
-		return "传入索引必须为数字"
-	}
-	err = db.DelTodoList(userId, index)
-	if err != nil {
-		return err.Error()
-	}
-	return "删除todo成功"
-}
-
-func GetCoin(param, userId string) string {
-	coinPrice, err := client.GetCoinPrice(param)
-	if err != nil {
-		return err.Error()
-	}
-	return fmt.Sprintf("代币对:%s 价格:%s", coinPrice.Symbol, coinPrice.Price)
-}
-
 func SetModel(param, userId string) string {
 	botType := config.GetUserBotType(userId)
 	if botType == config.Bot_Type_Gpt || botType == config.Bot_Type_Gemini || botType == config.Bot_Type_Qwen {
@@ -206,6 +164,7 @@ func GetModel(param string, userId string) string {
 func ClearMsg(param string, userId string) string {
 	botType := config.GetUserBotType(userId)
 	db.DeleteMsgList(botType, userId)
+	db.DeleteDocument(userId)
 	return fmt.Sprintf("%s 清除消息成功", botType)
 }
 
@@ -225,8 +184,11 @@ func WithTimeChat(userID, msg string, f func(userID, msg string) string) string
 	case res := <-resChan:
 		return res
 	case <-time.After(5 * time.Second):
-		config.Cache.Store(userID+msg, <-resChan)
-		return ""
+		// 超过微信的5秒超时，返回的结果由dedup交给微信重试的请求，
+		// 同时缓存结果，重试结束后再发一次相同的消息也能获取
+		res := <-resChan
+		config.Cache.Store(userID+msg, res)
+		return res
 	}
 }
 
@@ -242,7 +204,20 @@ func (e *ErrorChat) Chat(userID string, msg string) string {
 	return e.errMsg
 }
 
+func (e *ErrorChat) Complete(userID, system, prompt string) (string, error) {
+	return "", errors.New(e.errMsg)
+}
+
+// GetChatBot 返回bot，配置了内容审核时包装为ModeratedChat
 func GetChatBot(botType string) BaseChat {
+	bot := newChatBot(botType)
+	if moderation.Enabled() {
+		return &ModeratedChat{BaseChat: bot}
+	}
+	return bot
+}
+
+func newChatBot(botType string) BaseChat {
 	if botType == "" {
 		botType = config.GetBotType()
 	}
@@ -296,18 +271,44 @@ type ChatMsg interface {
 	openai.ChatCompletionMessage | QwenMessage | SparkMessage | *genai.Content
 }
 
+// ContextProvider 根据用户的消息返回需要补充到system prompt中的内容，没有时返回空字符串
+type ContextProvider func(userId, msg string) string
+
+var contextProviders []ContextProvider
+
+// RegisterContextProvider 注册后每次对话都会调用，结果和用户设置的prompt合并为一条system消息
+func RegisterContextProvider(provider ContextProvider) {
+	contextProviders = append(contextProviders, provider)
+}
+
+func GetChatContext(userId, msg string) []string {
+	var list []string
+	for _, provider := range contextProviders {
+		if context := provider(userId, msg); context != "" {
+			list = append(list, context)
+		}
+	}
+	return list
+}
+
 func GetMsgListWithDb[T ChatMsg](botType, userId string, msg T, f func(msg T) db.Msg, f2 func(msg db.Msg) T) []T {
 	var dbList []db.Msg
+	var system []string
 	isSupportPrompt := config.IsSupportPrompt(botType)
 	if isSupportPrompt {
 		prompt, err := db.GetPrompt(userId, botType)
 		if err == nil && prompt != "" {
-			dbList = append(dbList, db.Msg{
-				Role: "system",
-				Msg:  prompt,
-			})
+			system = append(system, prompt)
 		}
 	}
+	userMsg := f(msg)
+	system = append(system, GetChatContext(userId, userMsg.Msg)...)
+	if len(system) > 0 {
+		dbList = append(dbList, db.Msg{
+			Role: "system",
+			Msg:  strings.Join(system, "\n\n"),
+		})
+	}
 	if db.ChatDbInstance != nil {
 		list, err := db.ChatDbInstance.GetMsgList(botType, userId)
 		if err == nil {
@@ -320,7 +321,7 @@ func GetMsgListWithDb[T ChatMsg](botType, userId string, msg T, f func(msg T) db
 			dbList = append(dbList, list...)
 		}
 	}
-	dbList = append(dbList, f(msg))
+	dbList = append(dbList, userMsg)
 	r := make([]T, 0)
 	for _, msg := range dbList {
 		r = append(r, f2(msg))
@@ -0,0 +1,30 @@
+package chat
+
+import (
+	"testing"
+
+	"github.com/pwh-pwh/aiwechat-vercel/reply"
+	"github.com/silenceper/wechat/v2/officialaccount/message"
+)
+
+func TestDoReplyAction(t *testing.T) {
+	registerTestCommand(t, &Command{
+		Name: "/testimg",
+		Args: []CommandArg{{Name: "name", Type: Arg_String}},
+		ReplyHandler: func(args *CommandArgs, userId string) *message.Reply {
+			return reply.Image(userId + ":" + args.String("name"))
+		},
+	})
+
+	r, ok := DoReplyAction("u1", " /testimg  cat ")
+	if !ok || r.MsgType != message.MsgTypeImage || r.MsgData.(*message.Image).Image.MediaID != "u1:cat" {
+		t.Errorf("DoReplyAction = %+v, %v", r, ok)
+	}
+	if _, ok = DoReplyAction("u1", "/testimgx"); ok {
+		t.Error("command should match the whole token")
+	}
+	r, ok = DoReplyAction("u1", "/testimg")
+	if !ok || r.MsgType != message.MsgTypeText {
+		t.Errorf("missing arg should reply usage text, got %+v, %v", r, ok)
+	}
+}
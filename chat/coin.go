@@ -0,0 +1,214 @@
+package chat
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/pwh-pwh/aiwechat-vercel/client"
+	"github.com/pwh-pwh/aiwechat-vercel/coin"
+	"github.com/pwh-pwh/aiwechat-vercel/config"
+)
+
+func init() {
+	commands := []*Command{
+		{Name: config.Wx_Coin, Aliases: []string{"币价"}, Args: []CommandArg{{Name: "币种", Type: Arg_Text, Optional: true}},
+			Usage: "查询价格和24小时行情，如 /cb btc、/cb eth sol，不填时查询自选", Handler: paramCommand(GetCoin, "币种")},
+		{Name: config.Wx_Coin_Watch, Aliases: []string{"自选"}, Args: []CommandArg{{Name: "币种", Type: Arg_Text, Optional: true}},
+			Usage: "添加自选，不填时查看自选", Handler: paramCommand(WatchCoin, "币种")},
+		{Name: config.Wx_Coin_Unwatch, Args: []CommandArg{{Name: "币种", Type: Arg_Text}}, Usage: "删除自选",
+			Handler: paramCommand(UnwatchCoin, "币种")},
+		{Name: config.Wx_Coin_Alert, Aliases: []string{"价格提醒"}, Args: []CommandArg{{Name: "条件", Type: Arg_Text, Optional: true}},
+			Usage: "价格提醒，如 /alert BTC > 70000，达到后提醒一次，不填时查看已设置的提醒", Handler: paramCommand(PriceAlert, "条件")},
+		{Name: config.Wx_Coin_Unalert, Args: []CommandArg{{Name: "编号", Type: Arg_Int}}, Usage: "删除价格提醒",
+			Handler: func(args *CommandArgs, userId string) string {
+				a, err := coin.DeleteAlert(userId, args.Int("编号"))
+				if err != nil {
+					return err.Error()
+				}
+				return "已删除价格提醒 " + a.String()
+			}},
+	}
+	for _, cmd := range commands {
+		RegisterCommand(cmd)
+	}
+}
+
+func getBinance() *client.Binance {
+	return client.NewBinance(config.GetBinanceUrl())
+}
+
+// parseSymbols 把空格或逗号分隔的币种转换为交易对
+func parseSymbols(param string) []string {
+	var symbols []string
+	for _, s := range strings.FieldsFunc(param, func(r rune) bool { return isCommandSpace(r) || r == ',' || r == '，' || r == '、' }) {
+		if symbol := coin.NormalizeSymbol(s); symbol != "" {
+			symbols = append(symbols, symbol)
+		}
+	}
+	return symbols
+}
+
+// GetCoin 查询一个交易对时显示24小时行情，多个时每个一行，param为空时查询自选
+func GetCoin(param, userId string) string {
+	symbols := parseSymbols(param)
+	if len(symbols) == 0 {
+		watch, err := coin.WatchList(userId)
+		if err != nil {
+			return err.Error()
+		}
+		if len(watch) == 0 {
+			return "还没有自选，发送 /watch btc eth 添加，或发送 /cb btc 查询"
+		}
+		symbols = watch
+	}
+	if len(symbols) == 1 {
+		ticker, err := getBinance().Ticker(symbols[0])
+		if err != nil {
+			return coinError(symbols[0], err)
+		}
+		return fmt.Sprintf("%s %s (%s)\n24h最高：%s\n24h最低：%s\n24h成交量：%s\n24h成交额：%s",
+			ticker.Symbol, coin.FormatNumber(ticker.LastPrice), coin.FormatPercent(ticker.PriceChangePercent),
+			coin.FormatNumber(ticker.HighPrice), coin.FormatNumber(ticker.LowPrice),
+			coin.FormatAmount(ticker.Volume), coin.FormatAmount(ticker.QuoteVolume))
+	}
+	tickers, err := getBinance().Tickers(symbols)
+	if err != nil {
+		return coinError(strings.Join(symbols, "、"), err)
+	}
+	lines := make([]string, 0, len(tickers))
+	for _, ticker := range tickers {
+		lines = append(lines, fmt.Sprintf("%s %s %s", ticker.Symbol, coin.FormatNumber(ticker.LastPrice), coin.FormatPercent(ticker.PriceChangePercent)))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func coinError(symbol string, err error) string {
+	var binanceErr *client.BinanceError
+	if errors.As(err, &binanceErr) && binanceErr.Code == client.Binance_Invalid_Symbol {
+		return fmt.Sprintf("没有找到交易对%s，可以写币种(如 btc)或完整交易对(如 ETHBTC)", symbol)
+	}
+	return err.Error()
+}
+
+// checkSymbols 检查交易对是否存在
+func checkSymbols(symbols []string) error {
+	b := getBinance()
+	for _, symbol := range symbols {
+		if _, err := b.Price(symbol); err != nil {
+			return errors.New(coinError(symbol, err))
+		}
+	}
+	return nil
+}
+
+func WatchCoin(param, userId string) string {
+	symbols := parseSymbols(param)
+	if len(symbols) == 0 {
+		watch, err := coin.WatchList(userId)
+		if err != nil {
+			return err.Error()
+		}
+		if len(watch) == 0 {
+			return "还没有自选"
+		}
+		return "自选：" + strings.Join(watch, "、")
+	}
+	if err := checkSymbols(symbols); err != nil {
+		return err.Error()
+	}
+	watch, err := coin.Watch(userId, symbols...)
+	if err != nil {
+		return err.Error()
+	}
+	return "已添加，当前自选：" + strings.Join(watch, "、")
+}
+
+func UnwatchCoin(param, userId string) string {
+	watch, err := coin.Unwatch(userId, parseSymbols(param)...)
+	if err != nil {
+		return err.Error()
+	}
+	if len(watch) == 0 {
+		return "已删除，自选为空"
+	}
+	return "已删除，当前自选：" + strings.Join(watch, "、")
+}
+
+// PriceAlert 添加价格提醒，param为空时列出已设置的提醒
+func PriceAlert(param, userId string) string {
+	if strings.TrimSpace(param) == "" {
+		alerts, err := coin.Alerts(userId)
+		if err != nil {
+			return err.Error()
+		}
+		if len(alerts) == 0 {
+			return "没有价格提醒，发送 /alert BTC > 70000 添加"
+		}
+		lines := make([]string, 0, len(alerts))
+		for _, a := range alerts {
+			lines = append(lines, a.String())
+		}
+		return strings.Join(lines, "\n")
+	}
+	symbol, op, price, err := coin.ParseAlert(param)
+	if err != nil {
+		return err.Error()
+	}
+	if err = checkSymbols([]string{symbol}); err != nil {
+		return err.Error()
+	}
+	a, err := coin.AddAlert(userId, symbol, op, price)
+	if err != nil {
+		return err.Error()
+	}
+	return "已设置价格提醒 " + a.String() + "，达到后会提醒一次"
+}
+
+// currentPrices 查询交易对的价格，批量查询失败时逐个查询，跳过下架的交易对
+func currentPrices(symbols []string) map[string]float64 {
+	b := getBinance()
+	prices := map[string]float64{}
+	list, err := b.Prices(symbols)
+	if err != nil {
+		fmt.Println("get coin prices error:", err)
+		for _, symbol := range symbols {
+			price, err := b.Price(symbol)
+			if err != nil {
+				fmt.Printf("get %s price error: %v\n", symbol, err)
+				continue
+			}
+			list = append(list, *price)
+		}
+	}
+	for _, p := range list {
+		if f, err := strconv.ParseFloat(p.Price, 64); err == nil {
+			prices[p.Symbol] = f
+		}
+	}
+	return prices
+}
+
+// SendPriceAlerts 检查价格提醒，满足条件时发送并删除，返回发送成功的数量，由定时任务调用
+func SendPriceAlerts() (int, error) {
+	alerts, err := coin.AllAlerts()
+	if err != nil || len(alerts) == 0 {
+		return 0, err
+	}
+	prices := currentPrices(coin.Symbols(alerts))
+	sent := 0
+	for _, a := range coin.Triggered(alerts, prices) {
+		content := fmt.Sprintf("🔔 价格提醒：%s 当前价格 %s，已%s %s", a.Symbol,
+			strconv.FormatFloat(prices[a.Symbol], 'f', -1, 64), a.Condition(), strconv.FormatFloat(a.Price, 'f', -1, 64))
+		if err := SendText(a.UserId, content); err != nil {
+			fmt.Printf("price alert to %s error: %v\n", a.UserId, err)
+		} else {
+			sent++
+		}
+		if err := coin.Fired(a); err != nil {
+			fmt.Println("delete price alert error:", err)
+		}
+	}
+	return sent, nil
+}
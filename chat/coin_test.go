@@ -0,0 +1,71 @@
+package chat
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCoinCommands(t *testing.T) {
+	prices := map[string]string{"BTCUSDT": "67000.10000000", "ETHUSDT": "3500.00000000"}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		symbol := q.Get("symbol")
+		if symbol != "" && prices[symbol] == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"code":-1121,"msg":"Invalid symbol."}`))
+			return
+		}
+		switch {
+		case r.URL.Path == "/api/v3/ticker/price" && symbol != "":
+			w.Write([]byte(`{"symbol":"` + symbol + `","price":"` + prices[symbol] + `"}`))
+		case r.URL.Path == "/api/v3/ticker/price":
+			w.Write([]byte(`[{"symbol":"BTCUSDT","price":"` + prices["BTCUSDT"] + `"},{"symbol":"ETHUSDT","price":"` + prices["ETHUSDT"] + `"}]`))
+		case symbol != "":
+			w.Write([]byte(`{"symbol":"BTCUSDT","lastPrice":"67000.10000000","priceChangePercent":"2.350","highPrice":"68000.00000000","lowPrice":"65000.00000000","volume":"12345.67800000","quoteVolume":"827000000.00"}`))
+		default:
+			w.Write([]byte(`[{"symbol":"BTCUSDT","lastPrice":"67000.1","priceChangePercent":"2.35"},{"symbol":"ETHUSDT","lastPrice":"3500","priceChangePercent":"-1.2"}]`))
+		}
+	}))
+	defer server.Close()
+	t.Setenv("binanceUrl", server.URL)
+	messenger := useFakeMessenger(t)
+	t.Cleanup(func() {
+		UnwatchCoin("btc eth", "coin-chat")
+		RunCommand("coin-chat", "/unalert 1")
+		RunCommand("coin-chat", "/unalert 2")
+	})
+
+	if r, _ := RunCommand("coin-chat", "/cb btc"); r != "BTCUSDT 67000.1 (+2.35%)\n24h最高：68000\n24h最低：65000\n24h成交量：1.23万\n24h成交额：8.27亿" {
+		t.Errorf("/cb btc = %q", r)
+	}
+	if r, _ := RunCommand("coin-chat", "/cb nope"); !strings.HasPrefix(r, "没有找到交易对NOPEUSDT") {
+		t.Errorf("/cb nope = %q", r)
+	}
+	if r, _ := RunCommand("coin-chat", "/watch btc nope"); !strings.HasPrefix(r, "没有找到交易对NOPEUSDT") {
+		t.Errorf("/watch nope = %q", r)
+	}
+	RunCommand("coin-chat", "自选 btc, eth")
+	if r, _ := RunCommand("coin-chat", "/cb"); r != "BTCUSDT 67000.1 +2.35%\nETHUSDT 3500 -1.20%" {
+		t.Errorf("/cb watchlist = %q", r)
+	}
+
+	if r, _ := RunCommand("coin-chat", "/alert BTC > 70000"); r != "已设置价格提醒 #1 BTCUSDT > 70000，达到后会提醒一次" {
+		t.Errorf("/alert = %q", r)
+	}
+	RunCommand("coin-chat", "/alert eth < 3000")
+	if sent, err := SendPriceAlerts(); sent != 0 || err != nil {
+		t.Errorf("SendPriceAlerts = %d, %v", sent, err)
+	}
+	prices["BTCUSDT"] = "70100.5"
+	if sent, err := SendPriceAlerts(); sent != 1 || err != nil {
+		t.Errorf("SendPriceAlerts = %d, %v", sent, err)
+	}
+	if r := messenger.sent["coin-chat"]; r != "🔔 价格提醒：BTCUSDT 当前价格 70100.5，已高于 70000" {
+		t.Errorf("alert message = %q", r)
+	}
+	if r, _ := RunCommand("coin-chat", "/alert"); r != "#2 ETHUSDT < 3000" {
+		t.Errorf("/alert list = %q", r)
+	}
+}
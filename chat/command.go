@@ -0,0 +1,279 @@
+package chat
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/pwh-pwh/aiwechat-vercel/config"
+	"github.com/pwh-pwh/aiwechat-vercel/role"
+	"github.com/silenceper/wechat/v2/officialaccount/message"
+)
+
+const (
+	// Arg_String 一个不含空格的参数
+	Arg_String = "string"
+	Arg_Int    = "int"
+	Arg_Float  = "float"
+	// Arg_Text 剩余的全部内容，可以包含空格和换行，只能作为最后一个参数
+	Arg_Text = "text"
+)
+
+// CommandArg 指令参数，Choices不为空时参数只能是其中之一
+type CommandArg struct {
+	Name     string
+	Type     string
+	Optional bool
+	Choices  []string
+}
+
+// Command 指令，Handler和ReplyHandler二选一，ReplyHandler用于返回图文、图片等非文本消息
+// Role为执行需要的最低角色，为空时所有未被禁用的用户都可以执行
+type Command struct {
+	Name         string
+	Aliases      []string
+	Args         []CommandArg
+	Usage        string
+	Role         string
+	Handler      func(args *CommandArgs, userId string) string
+	ReplyHandler func(args *CommandArgs, userId string) *message.Reply
+}
+
+// CommandArgs 解析后的参数，Raw为指令后的原始内容
+type CommandArgs struct {
+	Raw    string
+	values map[string]any
+}
+
+func (a *CommandArgs) Has(name string) bool {
+	_, ok := a.values[name]
+	return ok
+}
+
+func (a *CommandArgs) String(name string) string {
+	v, _ := a.values[name].(string)
+	return v
+}
+
+func (a *CommandArgs) Int(name string) int {
+	v, _ := a.values[name].(int)
+	return v
+}
+
+func (a *CommandArgs) Float(name string) float64 {
+	v, _ := a.values[name].(float64)
+	return v
+}
+
+var (
+	commandList  []*Command
+	commandIndex = map[string]*Command{}
+)
+
+// RegisterCommand 注册指令，指令名和别名不区分英文大小写，重复注册时后注册的生效
+func RegisterCommand(cmd *Command) {
+	for i, c := range commandList {
+		if c.Name == cmd.Name {
+			commandList = slices.Delete(commandList, i, i+1)
+			// 被替换的指令的别名不再生效，已被其它指令使用的别名保留
+			for _, name := range append([]string{c.Name}, c.Aliases...) {
+				if commandIndex[strings.ToLower(name)] == c {
+					delete(commandIndex, strings.ToLower(name))
+				}
+			}
+			break
+		}
+	}
+	commandList = append(commandList, cmd)
+	for _, name := range append([]string{cmd.Name}, cmd.Aliases...) {
+		commandIndex[strings.ToLower(name)] = cmd
+	}
+}
+
+func GetCommands() []*Command {
+	return append([]*Command(nil), commandList...)
+}
+
+// FindCommand 按消息的第一个词查找指令，返回指令和其余内容
+func FindCommand(msg string) (*Command, string) {
+	msg = strings.TrimSpace(msg)
+	name, rest := msg, ""
+	if i := strings.IndexFunc(msg, isCommandSpace); i >= 0 {
+		name, rest = msg[:i], strings.TrimSpace(msg[i:])
+	}
+	cmd, ok := commandIndex[strings.ToLower(name)]
+	if !ok {
+		return nil, ""
+	}
+	return cmd, rest
+}
+
+func isCommandSpace(r rune) bool {
+	return r == ' ' || r == '\n' || r == '\t' || r == '　'
+}
+
+// Parse 按参数定义解析，参数不合法时返回错误
+func (cmd *Command) Parse(raw string) (*CommandArgs, error) {
+	args := &CommandArgs{Raw: raw, values: map[string]any{}}
+	rest := raw
+	for _, arg := range cmd.Args {
+		var value string
+		if arg.Type == Arg_Text {
+			value, rest = rest, ""
+		} else {
+			value, rest = rest, ""
+			if i := strings.IndexFunc(value, isCommandSpace); i >= 0 {
+				value, rest = value[:i], strings.TrimSpace(value[i:])
+			}
+		}
+		if value == "" {
+			if arg.Optional {
+				continue
+			}
+			return nil, fmt.Errorf("缺少参数%s", arg.Name)
+		}
+		if len(arg.Choices) > 0 && !slices.Contains(arg.Choices, strings.ToLower(value)) {
+			return nil, fmt.Errorf("%s只能是%s", arg.Name, strings.Join(arg.Choices, "、"))
+		}
+		switch arg.Type {
+		case Arg_Int:
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				return nil, fmt.Errorf("%s必须是整数", arg.Name)
+			}
+			args.values[arg.Name] = n
+		case Arg_Float:
+			f, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return nil, fmt.Errorf("%s必须是数字", arg.Name)
+			}
+			args.values[arg.Name] = f
+		default:
+			args.values[arg.Name] = value
+		}
+	}
+	if rest != "" {
+		return nil, fmt.Errorf("多余的参数: %s", rest)
+	}
+	return args, nil
+}
+
+// Synopsis 指令格式，必填参数用<>，选填参数用[]
+func (cmd *Command) Synopsis() string {
+	var sb strings.Builder
+	sb.WriteString(cmd.Name)
+	for _, arg := range cmd.Args {
+		name := arg.Name
+		if len(arg.Choices) > 0 {
+			name = strings.Join(arg.Choices, "|")
+		}
+		if arg.Optional {
+			sb.WriteString(" [" + name + "]")
+		} else {
+			sb.WriteString(" <" + name + ">")
+		}
+	}
+	return sb.String()
+}
+
+// UsageText 参数错误时回复的用法说明
+func (cmd *Command) UsageText() string {
+	text := fmt.Sprintf("用法：%s\n%s", cmd.Synopsis(), cmd.Usage)
+	if len(cmd.Aliases) > 0 {
+		text += "\n别名：" + strings.Join(cmd.Aliases, "、")
+	}
+	return text
+}
+
+// HelpText 根据用户可以执行的指令生成帮助，配置了WX_HELP_REPLY时使用配置的内容
+func HelpText(userId string) string {
+	if help := config.GetWxHelpReply(); help != "" {
+		return help
+	}
+	var sb strings.Builder
+	sb.WriteString("输入以下命令进行对话")
+	for _, cmd := range commandList {
+		if !role.Allow(userId, cmd.Role) {
+			continue
+		}
+		sb.WriteString(fmt.Sprintf("\n%s：%s", cmd.Synopsis(), cmd.Usage))
+	}
+	return sb.String()
+}
+
+// RunCommand 执行文本回复的指令，不是指令时返回false
+func RunCommand(userId, msg string) (string, bool) {
+	cmd, raw := FindCommand(msg)
+	if cmd == nil || cmd.Handler == nil {
+		return "", false
+	}
+	if !role.Allow(userId, cmd.Role) {
+		return "没有权限执行该指令", true
+	}
+	args, err := cmd.Parse(raw)
+	if err != nil {
+		return err.Error() + "\n" + cmd.UsageText(), true
+	}
+	return cmd.Handler(args, userId), true
+}
+
+// DoReplyAction 执行非文本回复的指令，参数错误时以文本回复用法
+func DoReplyAction(userId, msg string) (*message.Reply, bool) {
+	cmd, raw := FindCommand(msg)
+	if cmd == nil || cmd.ReplyHandler == nil {
+		return nil, false
+	}
+	if !role.Allow(userId, cmd.Role) {
+		return &message.Reply{MsgType: message.MsgTypeText, MsgData: message.NewText("没有权限执行该指令")}, true
+	}
+	args, err := cmd.Parse(raw)
+	if err != nil {
+		return &message.Reply{MsgType: message.MsgTypeText, MsgData: message.NewText(err.Error() + "\n" + cmd.UsageText())}, true
+	}
+	return cmd.ReplyHandler(args, userId), true
+}
+
+// paramCommand 把原有的 func(param, userId) 形式的处理函数注册为指令
+func paramCommand(f func(param, userId string) string, arg string) func(args *CommandArgs, userId string) string {
+	return func(args *CommandArgs, userId string) string {
+		if arg == "" {
+			return f(args.Raw, userId)
+		}
+		return f(args.String(arg), userId)
+	}
+}
+
+func init() {
+	commands := []*Command{
+		{Name: config.Wx_Command_Help, Aliases: []string{"帮助", "菜单"}, Usage: "查看帮助",
+			Handler: func(args *CommandArgs, userId string) string { return HelpText(userId) }},
+		{Name: config.Wx_Command_Gpt, Usage: "与GPT对话",
+			Handler: func(args *CommandArgs, userId string) string { return SwitchUserBot(userId, config.Bot_Type_Gpt) }},
+		{Name: config.Wx_Command_Spark, Usage: "与星火对话",
+			Handler: func(args *CommandArgs, userId string) string { return SwitchUserBot(userId, config.Bot_Type_Spark) }},
+		{Name: config.Wx_Command_Qwen, Usage: "与通义千问对话",
+			Handler: func(args *CommandArgs, userId string) string { return SwitchUserBot(userId, config.Bot_Type_Qwen) }},
+		{Name: config.Wx_Command_Gemini, Usage: "与gemini对话",
+			Handler: func(args *CommandArgs, userId string) string { return SwitchUserBot(userId, config.Bot_Type_Gemini) }},
+		{Name: config.Wx_Command_Prompt, Args: []CommandArg{{Name: "prompt", Type: Arg_Text}}, Usage: "设置system prompt",
+			Handler: paramCommand(SetPrompt, "prompt")},
+		{Name: config.Wx_Command_GetPrompt, Usage: "获取当前设置prompt", Handler: paramCommand(GetPrompt, "")},
+		{Name: config.Wx_Command_RmPrompt, Usage: "清除当前设置prompt", Handler: paramCommand(RmPrompt, "")},
+		{Name: config.Wx_Command_SetModel, Args: []CommandArg{{Name: "model", Type: Arg_String, Optional: true}}, Usage: "设置自定义model，不填时重置为默认值",
+			Handler: paramCommand(SetModel, "model")},
+		{Name: config.Wx_Command_GetModel, Usage: "获取当前model", Handler: paramCommand(GetModel, "")},
+		{Name: config.Wx_Command_Clear, Aliases: []string{"清除记录"}, Usage: "清除历史对话", Handler: paramCommand(ClearMsg, "")},
+		{Name: config.Wx_Command_Search, Aliases: []string{"搜索"}, Args: []CommandArg{{Name: "内容", Type: Arg_Text}}, Usage: "联网搜索并回答",
+			Handler: paramCommand(Search, "内容")},
+		{Name: config.Wx_Command_Web, Aliases: []string{"网页"}, Args: []CommandArg{{Name: "内容", Type: Arg_Text}}, Usage: "搜索网页，以图文卡片回复第一条结果",
+			ReplyHandler: WebCard},
+		{Name: config.Wx_Command_AutoSearch, Args: []CommandArg{{Name: "开关", Type: Arg_String, Optional: true, Choices: []string{"on", "off", "开启", "关闭"}}},
+			Usage: "开启或关闭自动联网搜索", Handler: paramCommand(AutoSearch, "开关")},
+		{Name: config.Wx_Command_Doc, Args: []CommandArg{{Name: "文档链接或clear", Type: Arg_String, Optional: true}},
+			Usage: "读取pdf、docx、txt文档后提问，clear删除当前文档", Handler: paramCommand(Document, "文档链接或clear")},
+	}
+	for _, cmd := range commands {
+		RegisterCommand(cmd)
+	}
+}
@@ -0,0 +1,129 @@
+package chat
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+// registerTestCommand 注册测试用的指令，测试结束后移除
+func registerTestCommand(t *testing.T, cmd *Command) {
+	RegisterCommand(cmd)
+	t.Cleanup(func() {
+		commandList = slices.DeleteFunc(commandList, func(c *Command) bool { return c == cmd })
+		for _, name := range append([]string{cmd.Name}, cmd.Aliases...) {
+			delete(commandIndex, strings.ToLower(name))
+		}
+	})
+}
+
+func TestFindCommand(t *testing.T) {
+	registerTestCommand(t, &Command{Name: "/testcmd", Aliases: []string{"测试"}})
+	tests := []struct {
+		msg  string
+		ok   bool
+		rest string
+	}{
+		{"/testcmd", true, ""},
+		{"  /TestCmd  a b ", true, "a b"},
+		{"测试\n第二行", true, "第二行"},
+		{"测试　全角空格", true, "全角空格"},
+		{"/testcmdx", false, ""},
+		{"/test", false, ""},
+		{"hello /testcmd", false, ""},
+	}
+	for _, tt := range tests {
+		cmd, rest := FindCommand(tt.msg)
+		if (cmd != nil) != tt.ok || rest != tt.rest {
+			t.Errorf("FindCommand(%q) = %v, %q", tt.msg, cmd, rest)
+		}
+	}
+}
+
+func TestReplaceCommand(t *testing.T) {
+	registerTestCommand(t, &Command{Name: "/testreplace", Aliases: []string{"旧别名", "共用"}})
+	replaced := &Command{Name: "/testreplace", Aliases: []string{"共用"}}
+	registerTestCommand(t, replaced)
+	if cmd, _ := FindCommand("旧别名"); cmd != nil {
+		t.Errorf("old alias still routes to %v", cmd.Name)
+	}
+	for _, msg := range []string{"/testreplace", "共用"} {
+		if cmd, _ := FindCommand(msg); cmd != replaced {
+			t.Errorf("FindCommand(%q) = %v", msg, cmd)
+		}
+	}
+	if n := len(slices.DeleteFunc(GetCommands(), func(c *Command) bool { return c.Name != "/testreplace" })); n != 1 {
+		t.Errorf("got %d commands named /testreplace", n)
+	}
+}
+
+func TestCommandParse(t *testing.T) {
+	cmd := &Command{Name: "/testargs", Args: []CommandArg{
+		{Name: "index", Type: Arg_Int},
+		{Name: "field", Type: Arg_String, Choices: []string{"amount", "note"}},
+		{Name: "value", Type: Arg_Float, Optional: true},
+	}}
+	args, err := cmd.Parse("3 amount 45.5")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if args.Int("index") != 3 || args.String("field") != "amount" || args.Float("value") != 45.5 {
+		t.Errorf("Parse = %+v", args)
+	}
+	args, err = cmd.Parse("3 note")
+	if err != nil || args.Has("value") {
+		t.Errorf("optional arg: %+v, %v", args, err)
+	}
+	for _, raw := range []string{"", "x amount", "3 other", "3 amount abc", "3 amount 1 2"} {
+		if _, err = cmd.Parse(raw); err == nil {
+			t.Errorf("Parse(%q) should fail", raw)
+		}
+	}
+
+	text := &Command{Name: "/testtext", Args: []CommandArg{{Name: "content", Type: Arg_Text}}}
+	args, err = text.Parse("多行\n内容 保留空格")
+	if err != nil || args.String("content") != "多行\n内容 保留空格" {
+		t.Errorf("text arg = %+v, %v", args, err)
+	}
+}
+
+func TestRunCommand(t *testing.T) {
+	registerTestCommand(t, &Command{
+		Name:  "/testrun",
+		Args:  []CommandArg{{Name: "n", Type: Arg_Int}},
+		Usage: "测试指令",
+		Handler: func(args *CommandArgs, userId string) string {
+			return userId + strings.Repeat("!", args.Int("n"))
+		},
+	})
+	if r, ok := RunCommand("u1", "/testrun 2"); !ok || r != "u1!!" {
+		t.Errorf("RunCommand = %q, %v", r, ok)
+	}
+	r, ok := RunCommand("u1", "/testrun two")
+	if !ok || !strings.Contains(r, "用法：/testrun <n>") || !strings.Contains(r, "测试指令") {
+		t.Errorf("usage reply = %q", r)
+	}
+	if _, ok = RunCommand("u1", "普通消息"); ok {
+		t.Error("plain message should not be a command")
+	}
+}
+
+func TestHelpText(t *testing.T) {
+	t.Setenv("WX_HELP_REPLY", "")
+	help := HelpText("u1")
+	for _, cmd := range GetCommands() {
+		if cmd.Role != "" {
+			continue
+		}
+		if !strings.Contains(help, cmd.Synopsis()+"："+cmd.Usage) {
+			t.Errorf("help missing %s", cmd.Name)
+		}
+	}
+	if r, ok := RunCommand("u1", "帮助"); !ok || r != help {
+		t.Errorf("帮助 = %q, %v", r, ok)
+	}
+	t.Setenv("WX_HELP_REPLY", "自定义\\n帮助")
+	if HelpText("u1") != "自定义\n帮助" {
+		t.Errorf("custom help = %q", HelpText("u1"))
+	}
+}
@@ -0,0 +1,148 @@
+package chat
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/bytedance/sonic"
+	"github.com/pwh-pwh/aiwechat-vercel/client"
+	"github.com/pwh-pwh/aiwechat-vercel/config"
+	"github.com/pwh-pwh/aiwechat-vercel/db"
+	"github.com/pwh-pwh/aiwechat-vercel/document"
+)
+
+const (
+	Max_Document_Size   = 10 << 20
+	Max_Document_Chunks = 400
+	Document_Chunk_Size = 500
+	Document_Overlap    = 50
+	// 每次提问注入到提示词中的片段数
+	Document_Top_K = 4
+)
+
+// UserDocument 用户当前正在提问的文档，保存切分后的片段，提问时在片段上建立BM25索引检索
+type UserDocument struct {
+	Name   string
+	Url    string
+	Pages  int
+	Chunks []document.Chunk
+}
+
+func init() {
+	RegisterContextProvider(documentContext)
+}
+
+// Document /doc 查看当前文档，/doc 链接 读取文档，/doc clear 删除文档
+func Document(param, userId string) string {
+	switch param {
+	case "":
+		doc, err := getUserDocument(userId)
+		if err != nil || doc == nil {
+			return "当前没有文档，发送 /doc 文档链接 或直接发送pdf、docx、txt文件的链接即可读取"
+		}
+		return fmt.Sprintf("当前文档《%s》，共%d页，直接发送问题即可，发送 /doc clear 删除文档", doc.Name, doc.Pages)
+	case "clear", "清除":
+		db.DeleteDocument(userId)
+		return "已删除文档"
+	}
+	pageUrl, _ := ExtractUrl(param)
+	if pageUrl == "" {
+		return "请发送文档链接，例如：/doc https://example.com/manual.pdf"
+	}
+	return LoadDocument(userId, pageUrl)
+}
+
+// IsDocumentUrl 链接是否指向支持的文档格式
+func IsDocumentUrl(fileUrl string) bool {
+	u, err := url.Parse(fileUrl)
+	if err != nil {
+		return false
+	}
+	return document.DetectType(u.Path, "") != ""
+}
+
+// LoadDocument 下载并解析文档，之后的提问都会检索文档内容
+func LoadDocument(userId, fileUrl string) string {
+	return WithTimeChat(userId, config.Wx_Command_Doc+" "+fileUrl, func(userId, msg string) string {
+		doc, err := loadDocument(userId, fileUrl)
+		if err != nil {
+			return err.Error()
+		}
+		return fmt.Sprintf("已读取文档《%s》，共%d页，现在可以直接提问，回答会标注引用的页码", doc.Name, doc.Pages)
+	})
+}
+
+func loadDocument(userId, fileUrl string) (*UserDocument, error) {
+	file, err := client.FetchFile(fileUrl, Max_Document_Size)
+	if err != nil {
+		return nil, fmt.Errorf("下载文档失败: %w", err)
+	}
+	docType := document.DetectType(file.Name, file.ContentType)
+	if docType == "" {
+		return nil, fmt.Errorf("不支持的文档格式: %s，目前支持pdf、docx、txt", file.Name)
+	}
+	pages, err := document.Extract(docType, file.Data)
+	if err != nil {
+		return nil, err
+	}
+	chunks := document.Split(pages, Document_Chunk_Size, Document_Overlap)
+	if len(chunks) > Max_Document_Chunks {
+		chunks = chunks[:Max_Document_Chunks]
+	}
+	doc := &UserDocument{
+		Name:   file.Name,
+		Url:    fileUrl,
+		Pages:  pages[len(pages)-1].Number,
+		Chunks: chunks,
+	}
+	data, err := sonic.MarshalString(doc)
+	if err != nil {
+		return nil, err
+	}
+	if err = db.SetDocument(userId, data); err != nil {
+		return nil, fmt.Errorf("保存文档失败: %w", err)
+	}
+	return doc, nil
+}
+
+func getUserDocument(userId string) (*UserDocument, error) {
+	data, err := db.GetDocument(userId)
+	if err != nil || data == "" {
+		return nil, err
+	}
+	var doc UserDocument
+	if err = sonic.UnmarshalString(data, &doc); err != nil {
+		return nil, err
+	}
+	return &doc, nil
+}
+
+// documentContext 检索与问题相关的文档片段注入提示词
+func documentContext(userId, msg string) string {
+	doc, err := getUserDocument(userId)
+	if err != nil || doc == nil {
+		return ""
+	}
+	db.TouchDocument(userId)
+	return doc.Context(msg, Document_Top_K)
+}
+
+func (doc *UserDocument) Context(question string, k int) string {
+	texts := make([]string, 0, len(doc.Chunks))
+	for _, chunk := range doc.Chunks {
+		texts = append(texts, chunk.Text)
+	}
+	results := document.NewIndex(texts).Search(question, k)
+	if len(results) == 0 {
+		return ""
+	}
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("用户发送了文档《%s》，以下是文档中与问题相关的片段。"+
+		"涉及文档的问题请根据这些片段回答，并在引用处标注页码，例如(第3页)；片段中没有相关内容时请说明文档中未提及。\n", doc.Name))
+	for _, result := range results {
+		chunk := doc.Chunks[result.Id]
+		sb.WriteString(fmt.Sprintf("[第%d页] %s\n", chunk.Page, chunk.Text))
+	}
+	return strings.TrimSpace(sb.String())
+}
@@ -0,0 +1,69 @@
+package chat
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/pwh-pwh/aiwechat-vercel/db"
+	"github.com/pwh-pwh/aiwechat-vercel/document"
+)
+
+func TestIsDocumentUrl(t *testing.T) {
+	for u, want := range map[string]bool{
+		"https://example.com/manual.pdf?x=1": true,
+		"https://example.com/a/b.docx":       true,
+		"https://mp.weixin.qq.com/s/abc":     false,
+	} {
+		if got := IsDocumentUrl(u); got != want {
+			t.Errorf("IsDocumentUrl(%q) = %v", u, got)
+		}
+	}
+}
+
+func TestDocumentContext(t *testing.T) {
+	doc := &UserDocument{Name: "说明书.pdf", Pages: 3, Chunks: []document.Chunk{
+		{Page: 1, Text: "本产品保修期为一年"},
+		{Page: 3, Text: "收到商品七天内可以无理由退货"},
+	}}
+	context := doc.Context("可以退货吗", 2)
+	if !strings.Contains(context, "《说明书.pdf》") || !strings.Contains(context, "[第3页] 收到商品七天内可以无理由退货") {
+		t.Errorf("context = %s", context)
+	}
+	if strings.Contains(context, "保修期") {
+		t.Errorf("unrelated chunk should not be injected: %s", context)
+	}
+	if doc.Context("营业时间", 2) != "" {
+		t.Error("no matching chunk should return empty context")
+	}
+}
+
+func TestLoadDocument(t *testing.T) {
+	allowLocalFetch(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Write([]byte("第一页：保修期一年\f第二页：七天无理由退货"))
+	}))
+	defer server.Close()
+
+	userId := "doc-user"
+	defer db.DeleteDocument(userId)
+	r := Document(server.URL+"/faq.txt", userId)
+	if !strings.Contains(r, "《faq.txt》，共2页") {
+		t.Fatalf("load reply = %s", r)
+	}
+	if r = Document("", userId); !strings.Contains(r, "当前文档《faq.txt》") {
+		t.Errorf("status reply = %s", r)
+	}
+	context := strings.Join(GetChatContext(userId, "退货"), "\n")
+	if !strings.Contains(context, "[第2页] 第二页：七天无理由退货") {
+		t.Errorf("chat context = %s", context)
+	}
+	if r = Document("clear", userId); r != "已删除文档" {
+		t.Errorf("clear reply = %s", r)
+	}
+	if context := GetChatContext(userId, "退货"); len(context) != 0 {
+		t.Errorf("context after clear = %v", context)
+	}
+}
@@ -11,3 +11,7 @@ func (e *Echo) HandleMediaMsg(msg *message.MixMessage) string {
 func (e *Echo) Chat(userID string, msg string) string {
 	return msg
 }
+
+func (e *Echo) Complete(userID, system, prompt string) (string, error) {
+	return prompt, nil
+}
@@ -2,6 +2,9 @@ package chat
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"strings"
 
 	"github.com/google/generative-ai-go/genai"
 	"github.com/pwh-pwh/aiwechat-vercel/config"
@@ -14,6 +17,39 @@ const (
 	GeminiBot  = "model"
 )
 
+var (
+	geminiHarmCategories = map[string]genai.HarmCategory{
+		"HARASSMENT":        genai.HarmCategoryHarassment,
+		"HATE_SPEECH":       genai.HarmCategoryHateSpeech,
+		"SEXUALLY_EXPLICIT": genai.HarmCategorySexuallyExplicit,
+		"DANGEROUS_CONTENT": genai.HarmCategoryDangerousContent,
+	}
+	geminiHarmThresholds = map[string]genai.HarmBlockThreshold{
+		"BLOCK_NONE":             genai.HarmBlockNone,
+		"BLOCK_ONLY_HIGH":        genai.HarmBlockOnlyHigh,
+		"BLOCK_MEDIUM_AND_ABOVE": genai.HarmBlockMediumAndAbove,
+		"BLOCK_LOW_AND_ABOVE":    genai.HarmBlockLowAndAbove,
+	}
+	geminiHarmCategoryNames = map[genai.HarmCategory]string{
+		genai.HarmCategoryHarassment:       "骚扰",
+		genai.HarmCategoryHateSpeech:       "仇恨言论",
+		genai.HarmCategorySexuallyExplicit: "色情内容",
+		genai.HarmCategoryDangerousContent: "危险内容",
+	}
+	geminiHarmProbabilityNames = map[genai.HarmProbability]string{
+		genai.HarmProbabilityNegligible: "极低",
+		genai.HarmProbabilityLow:        "低",
+		genai.HarmProbabilityMedium:     "中",
+		genai.HarmProbabilityHigh:       "高",
+	}
+	geminiFinishReasonNames = map[genai.FinishReason]string{
+		genai.FinishReasonMaxTokens:  "达到最大输出长度",
+		genai.FinishReasonSafety:     "触发安全策略",
+		genai.FinishReasonRecitation: "内容疑似引用受版权保护的资料",
+		genai.FinishReasonOther:      "其他原因",
+	}
+)
+
 type GeminiChat struct {
 	BaseChat
 	key       string
@@ -21,15 +57,81 @@ type GeminiChat struct {
 }
 
 func (s *GeminiChat) toDbMsg(msg *genai.Content) db.Msg {
-	text := msg.Parts[0].(genai.Text)
-	return db.Msg{
-		Role: msg.Role,
-		Msg:  string(text),
+	dbMsg := db.Msg{Role: msg.Role}
+	var texts []string
+	hasMedia := false
+	for _, part := range msg.Parts {
+		dbPart, ok := geminiPartToDb(part)
+		if !ok {
+			continue
+		}
+		if dbPart.Type == db.MSG_PART_TEXT {
+			texts = append(texts, dbPart.Text)
+		} else {
+			hasMedia = true
+		}
+		dbMsg.Parts = append(dbMsg.Parts, dbPart)
 	}
+	dbMsg.Msg = strings.Join(texts, "")
+	// 纯文本消息只保存Msg字段，与之前的格式保持一致
+	if !hasMedia {
+		dbMsg.Parts = nil
+	}
+	return dbMsg
 }
 
 func (s *GeminiChat) toChatMsg(msg db.Msg) *genai.Content {
-	return &genai.Content{Parts: []genai.Part{genai.Text(msg.Msg)}, Role: msg.Role}
+	if len(msg.Parts) == 0 {
+		return &genai.Content{Parts: []genai.Part{genai.Text(msg.Msg)}, Role: msg.Role}
+	}
+	parts := make([]genai.Part, 0, len(msg.Parts))
+	for _, part := range msg.Parts {
+		if p := dbPartToGemini(part); p != nil {
+			parts = append(parts, p)
+		}
+	}
+	return &genai.Content{Parts: parts, Role: msg.Role}
+}
+
+func geminiPartToDb(part genai.Part) (db.MsgPart, bool) {
+	switch p := part.(type) {
+	case genai.Text:
+		return db.MsgPart{Type: db.MSG_PART_TEXT, Text: string(p)}, true
+	case genai.Blob:
+		return db.MsgPart{Type: db.MSG_PART_BLOB, MimeType: p.MIMEType, Data: p.Data}, true
+	case genai.FileData:
+		return db.MsgPart{Type: db.MSG_PART_FILE, MimeType: p.MIMEType, Uri: p.URI}, true
+	case genai.FunctionCall:
+		return db.MsgPart{Type: db.MSG_PART_FUNCTION_CALL, Name: p.Name, Args: p.Args}, true
+	case genai.FunctionResponse:
+		return db.MsgPart{Type: db.MSG_PART_FUNCTION_RESPONSE, Name: p.Name, Args: p.Response}, true
+	case genai.ExecutableCode:
+		return db.MsgPart{Type: db.MSG_PART_CODE, Text: p.Code, Name: p.Language.String()}, true
+	case genai.CodeExecutionResult:
+		return db.MsgPart{Type: db.MSG_PART_CODE_RESULT, Text: p.Output, Name: p.Outcome.String()}, true
+	}
+	return db.MsgPart{}, false
+}
+
+func dbPartToGemini(part db.MsgPart) genai.Part {
+	switch part.Type {
+	case db.MSG_PART_TEXT:
+		return genai.Text(part.Text)
+	case db.MSG_PART_BLOB:
+		return genai.Blob{MIMEType: part.MimeType, Data: part.Data}
+	case db.MSG_PART_FILE:
+		return genai.FileData{MIMEType: part.MimeType, URI: part.Uri}
+	case db.MSG_PART_FUNCTION_CALL:
+		return genai.FunctionCall{Name: part.Name, Args: part.Args}
+	case db.MSG_PART_FUNCTION_RESPONSE:
+		return genai.FunctionResponse{Name: part.Name, Response: part.Args}
+	case db.MSG_PART_CODE:
+		// 代码执行结果由模型生成，回传时以文本形式提供上下文即可
+		return genai.Text(fmt.Sprintf("```\n%s\n```", part.Text))
+	case db.MSG_PART_CODE_RESULT:
+		return genai.Text(part.Text)
+	}
+	return nil
 }
 
 func (s *GeminiChat) getModel(userID string) string {
@@ -39,6 +141,139 @@ func (s *GeminiChat) getModel(userID string) string {
 	return "gemini-pro"
 }
 
+// parseGeminiSafetySettings 解析安全设置，单独的阈值作用于全部类别，也可以用 类别=阈值 逐个设置
+func parseGeminiSafetySettings(setting string) ([]*genai.SafetySetting, error) {
+	setting = strings.TrimSpace(setting)
+	if setting == "" {
+		return nil, nil
+	}
+	if threshold, ok := geminiHarmThresholds[strings.ToUpper(setting)]; ok {
+		settings := make([]*genai.SafetySetting, 0, len(geminiHarmCategories))
+		for _, category := range []string{"HARASSMENT", "HATE_SPEECH", "SEXUALLY_EXPLICIT", "DANGEROUS_CONTENT"} {
+			settings = append(settings, &genai.SafetySetting{Category: geminiHarmCategories[category], Threshold: threshold})
+		}
+		return settings, nil
+	}
+	var settings []*genai.SafetySetting
+	for _, item := range strings.Split(setting, ",") {
+		kv := strings.SplitN(strings.TrimSpace(item), "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("geminiSafety格式错误: %s", item)
+		}
+		categoryName := strings.TrimPrefix(strings.ToUpper(strings.TrimSpace(kv[0])), "HARM_CATEGORY_")
+		category, ok := geminiHarmCategories[categoryName]
+		if !ok {
+			return nil, fmt.Errorf("geminiSafety不支持的类别: %s", kv[0])
+		}
+		threshold, ok := geminiHarmThresholds[strings.ToUpper(strings.TrimSpace(kv[1]))]
+		if !ok {
+			return nil, fmt.Errorf("geminiSafety不支持的阈值: %s", kv[1])
+		}
+		settings = append(settings, &genai.SafetySetting{Category: category, Threshold: threshold})
+	}
+	return settings, nil
+}
+
+// geminiReply 解析gemini回复，拼接全部文本内容，被安全策略拦截时返回可读的原因
+func geminiReply(resp *genai.GenerateContentResponse, err error) (*genai.Content, string, error) {
+	var blocked *genai.BlockedError
+	if errors.As(err, &blocked) {
+		if blocked.PromptFeedback != nil {
+			return nil, "", geminiPromptBlockedError(blocked.PromptFeedback)
+		}
+		return nil, "", geminiCandidateError(blocked.Candidate)
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	if resp.PromptFeedback != nil && resp.PromptFeedback.BlockReason != genai.BlockReasonUnspecified {
+		return nil, "", geminiPromptBlockedError(resp.PromptFeedback)
+	}
+	if len(resp.Candidates) == 0 {
+		return nil, "", errors.New("gemini未返回任何内容")
+	}
+	candidate := resp.Candidates[0]
+	if candidate.Content == nil || len(candidate.Content.Parts) == 0 {
+		return nil, "", geminiCandidateError(candidate)
+	}
+	var sb strings.Builder
+	for _, part := range candidate.Content.Parts {
+		switch p := part.(type) {
+		case genai.Text:
+			sb.WriteString(string(p))
+		case genai.ExecutableCode:
+			sb.WriteString(fmt.Sprintf("\n```\n%s\n```\n", p.Code))
+		case genai.CodeExecutionResult:
+			sb.WriteString(fmt.Sprintf("\n执行结果:\n%s\n", p.Output))
+		}
+	}
+	text := sb.String()
+	if text == "" {
+		return nil, "", geminiCandidateError(candidate)
+	}
+	if candidate.FinishReason == genai.FinishReasonMaxTokens {
+		text += "\n(回复达到最大长度，发送“继续”获取后续内容)"
+	}
+	content := candidate.Content
+	if content.Role == "" {
+		content.Role = GeminiBot
+	}
+	return content, text, nil
+}
+
+func geminiFunctionCalls(resp *genai.GenerateContentResponse) []genai.FunctionCall {
+	if resp == nil || len(resp.Candidates) == 0 {
+		return nil
+	}
+	return resp.Candidates[0].FunctionCalls()
+}
+
+func geminiPromptBlockedError(feedback *genai.PromptFeedback) error {
+	reason := "其他原因"
+	if feedback.BlockReason == genai.BlockReasonSafety {
+		reason = "触发安全策略"
+	}
+	msg := fmt.Sprintf("消息被gemini拦截(%s)", reason)
+	if ratings := geminiBlockedRatings(feedback.SafetyRatings); ratings != "" {
+		msg += "：" + ratings
+	}
+	return errors.New(msg + "，请换个说法试试")
+}
+
+func geminiCandidateError(candidate *genai.Candidate) error {
+	if candidate == nil {
+		return errors.New("gemini未返回任何内容")
+	}
+	reason, ok := geminiFinishReasonNames[candidate.FinishReason]
+	if !ok {
+		reason = candidate.FinishReason.String()
+	}
+	msg := fmt.Sprintf("gemini未能生成回复(%s)", reason)
+	if ratings := geminiBlockedRatings(candidate.SafetyRatings); ratings != "" {
+		msg += "：" + ratings
+	}
+	return errors.New(msg)
+}
+
+func geminiBlockedRatings(ratings []*genai.SafetyRating) string {
+	var list []string
+	for _, rating := range ratings {
+		if rating == nil || (!rating.Blocked && rating.Probability < genai.HarmProbabilityMedium) {
+			continue
+		}
+		category, ok := geminiHarmCategoryNames[rating.Category]
+		if !ok {
+			category = rating.Category.String()
+		}
+		probability, ok := geminiHarmProbabilityNames[rating.Probability]
+		if !ok {
+			probability = rating.Probability.String()
+		}
+		list = append(list, fmt.Sprintf("%s(风险%s)", category, probability))
+	}
+	return strings.Join(list, "、")
+}
+
 func (s *GeminiChat) chat(userId, msg string) string {
 	ctx := context.Background()
 	client, err := genai.NewClient(ctx, option.WithAPIKey(s.key))
@@ -50,6 +285,13 @@ func (s *GeminiChat) chat(userId, msg string) string {
 	if s.maxTokens > 0 {
 		model.SetMaxOutputTokens(int32(s.maxTokens)) // 参数设置方法参考：https://github.com/google/generative-ai-go
 	}
+	model.SafetySettings, err = parseGeminiSafetySettings(config.GetGeminiSafety())
+	if err != nil {
+		return err.Error()
+	}
+	if config.IsToolsEnabled() {
+		model.Tools = toGeminiTools(GetTools())
+	}
 	// Initialize the chat
 	cs := model.StartChat()
 	var msgs = GetMsgListWithDb(config.Bot_Type_Gemini, userId, &genai.Content{
@@ -58,20 +300,62 @@ func (s *GeminiChat) chat(userId, msg string) string {
 		},
 		Role: GeminiUser,
 	}, s.toDbMsg, s.toChatMsg)
+	// gemini的历史记录不支持system角色，补充的上下文通过SystemInstruction传入
+	if msgs[0].Role == "system" {
+		model.SystemInstruction = &genai.Content{Parts: msgs[0].Parts}
+		msgs = msgs[1:]
+	}
 	if len(msgs) > 1 {
 		cs.History = msgs[:len(msgs)-1]
 	}
 
-	resp, err := cs.SendMessage(ctx, genai.Text(msg))
+	resp, err := cs.SendMessage(ctx, msgs[len(msgs)-1].Parts...)
+	// 模型要求调用工具时执行工具并把结果交回模型，直到得到最终回复
+	for round := 0; err == nil && round < Max_Tool_Rounds; round++ {
+		calls := geminiFunctionCalls(resp)
+		if len(calls) == 0 {
+			break
+		}
+		responses := make([]genai.Part, 0, len(calls))
+		for _, call := range calls {
+			responses = append(responses, genai.FunctionResponse{
+				Name:     call.Name,
+				Response: map[string]any{"result": CallTool(userId, call.Name, call.Args)},
+			})
+		}
+		if round == Max_Tool_Rounds-1 {
+			// 最后一轮不允许再调用工具，让模型根据已有的结果回答
+			model.ToolConfig = &genai.ToolConfig{FunctionCallingConfig: &genai.FunctionCallingConfig{Mode: genai.FunctionCallingNone}}
+		}
+		resp, err = cs.SendMessage(ctx, responses...)
+	}
+	content, text, err := geminiReply(resp, err)
 	if err != nil {
 		return err.Error()
 	}
-	text := resp.Candidates[0].Content.Parts[0].(genai.Text)
-	msgs = append(msgs, &genai.Content{Parts: []genai.Part{
-		text,
-	}, Role: GeminiBot})
+	msgs = append(msgs, content)
 	SaveMsgListWithDb(config.Bot_Type_Gemini, userId, msgs, s.toDbMsg)
-	return string(text)
+	return text
+}
+
+func (s *GeminiChat) Complete(userID, system, prompt string) (string, error) {
+	ctx := context.Background()
+	client, err := genai.NewClient(ctx, option.WithAPIKey(s.key))
+	if err != nil {
+		return "", err
+	}
+	defer client.Close()
+	model := client.GenerativeModel(s.getModel(userID))
+	if s.maxTokens > 0 {
+		model.SetMaxOutputTokens(int32(s.maxTokens))
+	}
+	model.SafetySettings, err = parseGeminiSafetySettings(config.GetGeminiSafety())
+	if err != nil {
+		return "", err
+	}
+	model.SystemInstruction = genai.NewUserContent(genai.Text(system))
+	_, text, err := geminiReply(model.GenerateContent(ctx, genai.Text(prompt)))
+	return text, err
 }
 
 func (g *GeminiChat) Chat(userID string, msg string) string {
@@ -2,8 +2,11 @@ package chat
 
 import (
 	"fmt"
+	"reflect"
+	"strings"
 	"testing"
 
+	"github.com/google/generative-ai-go/genai"
 	"github.com/joho/godotenv"
 	"github.com/pwh-pwh/aiwechat-vercel/config"
 	"github.com/pwh-pwh/aiwechat-vercel/db"
@@ -22,3 +25,83 @@ func TestGeminiChat(t *testing.T) {
 
 	fmt.Println(res)
 }
+
+func TestGeminiMsgRoundTrip(t *testing.T) {
+	chat := &GeminiChat{}
+	content := &genai.Content{Role: GeminiUser, Parts: []genai.Part{
+		genai.Text("这张图里有什么？"),
+		genai.ImageData("png", []byte{0x89, 0x50}),
+		genai.FileData{MIMEType: "application/pdf", URI: "https://example.com/a.pdf"},
+	}}
+
+	dbMsg := chat.toDbMsg(content)
+	if dbMsg.Msg != "这张图里有什么？" || len(dbMsg.Parts) != 3 {
+		t.Fatalf("unexpected db msg %+v", dbMsg)
+	}
+	restored := chat.toChatMsg(dbMsg)
+	if !reflect.DeepEqual(restored, content) {
+		t.Errorf("round trip mismatch: %+v", restored)
+	}
+
+	textMsg := chat.toDbMsg(&genai.Content{Role: GeminiBot, Parts: []genai.Part{genai.Text("你好"), genai.Text("世界")}})
+	if textMsg.Msg != "你好世界" || textMsg.Parts != nil {
+		t.Errorf("text only message should not keep parts: %+v", textMsg)
+	}
+	if legacy := chat.toChatMsg(db.Msg{Role: GeminiUser, Msg: "旧格式"}); legacy.Parts[0] != genai.Text("旧格式") {
+		t.Errorf("unexpected legacy message %+v", legacy)
+	}
+}
+
+func TestGeminiReply(t *testing.T) {
+	_, text, err := geminiReply(&genai.GenerateContentResponse{Candidates: []*genai.Candidate{{
+		Content:      &genai.Content{Role: GeminiBot, Parts: []genai.Part{genai.Text("第一段"), genai.Text("第二段")}},
+		FinishReason: genai.FinishReasonStop,
+	}}}, nil)
+	if err != nil || text != "第一段第二段" {
+		t.Errorf("unexpected reply %q %v", text, err)
+	}
+
+	_, _, err = geminiReply(nil, &genai.BlockedError{PromptFeedback: &genai.PromptFeedback{
+		BlockReason:   genai.BlockReasonSafety,
+		SafetyRatings: []*genai.SafetyRating{{Category: genai.HarmCategoryHarassment, Probability: genai.HarmProbabilityHigh, Blocked: true}},
+	}})
+	if err == nil || !strings.Contains(err.Error(), "骚扰(风险高)") {
+		t.Errorf("unexpected prompt blocked error %v", err)
+	}
+
+	_, _, err = geminiReply(nil, &genai.BlockedError{Candidate: &genai.Candidate{FinishReason: genai.FinishReasonSafety}})
+	if err == nil || !strings.Contains(err.Error(), "触发安全策略") {
+		t.Errorf("unexpected candidate blocked error %v", err)
+	}
+
+	_, _, err = geminiReply(&genai.GenerateContentResponse{Candidates: []*genai.Candidate{{FinishReason: genai.FinishReasonRecitation}}}, nil)
+	if err == nil || !strings.Contains(err.Error(), "版权") {
+		t.Errorf("empty candidate should return finish reason, got %v", err)
+	}
+
+	if _, _, err = geminiReply(&genai.GenerateContentResponse{}, nil); err == nil {
+		t.Error("expected error for empty candidates")
+	}
+}
+
+func TestParseGeminiSafetySettings(t *testing.T) {
+	settings, err := parseGeminiSafetySettings("block_none")
+	if err != nil || len(settings) != 4 || settings[0].Threshold != genai.HarmBlockNone {
+		t.Errorf("unexpected settings %v %v", settings, err)
+	}
+
+	settings, err = parseGeminiSafetySettings("HARM_CATEGORY_HARASSMENT=BLOCK_ONLY_HIGH, DANGEROUS_CONTENT=BLOCK_NONE")
+	if err != nil || len(settings) != 2 {
+		t.Fatalf("unexpected settings %v %v", settings, err)
+	}
+	if settings[0].Category != genai.HarmCategoryHarassment || settings[0].Threshold != genai.HarmBlockOnlyHigh {
+		t.Errorf("unexpected first setting %+v", settings[0])
+	}
+
+	if _, err = parseGeminiSafetySettings("HARASSMENT=SOMETIMES"); err == nil {
+		t.Error("expected error for unknown threshold")
+	}
+	if settings, err = parseGeminiSafetySettings(""); err != nil || settings != nil {
+		t.Errorf("empty setting should use defaults, got %v %v", settings, err)
+	}
+}
@@ -2,6 +2,7 @@ package chat
 
 import (
 	"context"
+	"errors"
 
 	"os"
 
@@ -54,16 +55,65 @@ func (s *SimpleGptChat) chat(userID, msg string) string {
 	if s.maxTokens > 0 {
 		req.MaxTokens = s.maxTokens // 参数名称参考：https://github.com/sashabaranov/go-openai
 	}
-	resp, err := client.CreateChatCompletion(context.Background(), req)
+	if config.IsToolsEnabled() {
+		req.Tools = toOpenAITools(GetTools())
+	}
+	content, err := s.complete(client, userID, req)
 	if err != nil {
 		return err.Error()
 	}
-	content := resp.Choices[0].Message.Content
 	msgs = append(msgs, openai.ChatCompletionMessage{Role: openai.ChatMessageRoleAssistant, Content: content})
 	SaveMsgListWithDb(config.Bot_Type_Gpt, userID, msgs, s.toDbMsg)
 	return content
 }
 
+// complete 请求模型，模型要求调用工具时执行工具并把结果交回模型，直到得到最终回复
+func (s *SimpleGptChat) complete(client *openai.Client, userID string, req openai.ChatCompletionRequest) (string, error) {
+	// 复制一份消息列表，工具调用过程不写入历史记录
+	req.Messages = append([]openai.ChatCompletionMessage(nil), req.Messages...)
+	for round := 0; ; round++ {
+		if round == Max_Tool_Rounds {
+			req.Tools = nil
+		}
+		resp, err := client.CreateChatCompletion(context.Background(), req)
+		if err != nil {
+			return "", err
+		}
+		if len(resp.Choices) == 0 {
+			return "", errors.New("gpt未返回任何内容")
+		}
+		message := resp.Choices[0].Message
+		if len(message.ToolCalls) == 0 || len(req.Tools) == 0 {
+			return message.Content, nil
+		}
+		req.Messages = append(req.Messages, message)
+		for _, call := range message.ToolCalls {
+			req.Messages = append(req.Messages, openai.ChatCompletionMessage{
+				Role:       openai.ChatMessageRoleTool,
+				Content:    CallToolWithJson(userID, call.Function.Name, call.Function.Arguments),
+				ToolCallID: call.ID,
+			})
+		}
+	}
+}
+
+func (s *SimpleGptChat) Complete(userID, system, prompt string) (string, error) {
+	cfg := openai.DefaultConfig(s.token)
+	cfg.BaseURL = s.url
+	client := openai.NewClientWithConfig(cfg)
+	req := openai.ChatCompletionRequest{
+		Model: s.getModel(userID),
+		Messages: []openai.ChatCompletionMessage{
+			{Role: openai.ChatMessageRoleSystem, Content: system},
+			{Role: openai.ChatMessageRoleUser, Content: prompt},
+		},
+	}
+	if s.maxTokens > 0 {
+		req.MaxTokens = s.maxTokens
+	}
+	return s.complete(client, userID, req)
+}
+
 func (s *SimpleGptChat) Chat(userID string, msg string) string {
 	r, flag := DoAction(userID, msg)
 	if flag {
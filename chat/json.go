@@ -0,0 +1,194 @@
+package chat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/bytedance/sonic"
+	"github.com/google/generative-ai-go/genai"
+	"github.com/pwh-pwh/aiwechat-vercel/config"
+	"github.com/sashabaranov/go-openai"
+	"google.golang.org/api/option"
+)
+
+// JSONRequest 要求模型按Schema输出json的单轮问答
+// Schema为json schema，只使用type、description、enum、items、properties、required
+type JSONRequest struct {
+	System string
+	Prompt string
+	Schema map[string]any
+	Model  string // 为空时使用用户当前的模型
+}
+
+// JSONCompleter 支持json输出模式的bot，gemini按Schema约束输出，gpt和通义千问使用json_object模式
+type JSONCompleter interface {
+	CompleteJSON(userID string, req JSONRequest) (string, error)
+}
+
+// CompleteJSON 使用指定的bot输出json，botType为空时使用用户当前的bot
+// bot不支持json模式时把Schema写入prompt，并去掉回复中的markdown代码块
+func CompleteJSON(userID, botType string, req JSONRequest) (string, error) {
+	if botType == "" {
+		botType = config.GetUserBotType(userID)
+	}
+	return completeJSON(GetChatBot(botType), userID, req)
+}
+
+func completeJSON(bot BaseChat, userID string, req JSONRequest) (string, error) {
+	if completer, ok := bot.(JSONCompleter); ok {
+		return completer.CompleteJSON(userID, req)
+	}
+	completer, ok := bot.(Completer)
+	if !ok {
+		return "", errors.New("当前bot不支持该功能")
+	}
+	r, err := completer.Complete(userID, jsonSystem(req), req.Prompt)
+	if err != nil {
+		return "", err
+	}
+	return trimCodeBlock(r), nil
+}
+
+// jsonSystem 把Schema附加到system中，json_object模式也要求prompt中出现json
+func jsonSystem(req JSONRequest) string {
+	schema, _ := sonic.MarshalString(req.Schema)
+	return fmt.Sprintf("%s\n只输出符合以下JSON Schema的json，不要输出其它内容：\n%s", req.System, schema)
+}
+
+func trimCodeBlock(s string) string {
+	s = strings.TrimSpace(s)
+	if !strings.HasPrefix(s, "```") {
+		return s
+	}
+	s = strings.TrimPrefix(s, "```")
+	s = strings.TrimPrefix(s, "json")
+	return strings.TrimSpace(strings.TrimSuffix(s, "```"))
+}
+
+func (c *ModeratedChat) CompleteJSON(userID string, req JSONRequest) (string, error) {
+	// json交给程序解析，不做输出审核，避免替换敏感词后json格式被破坏
+	return completeJSON(c.BaseChat, userID, req)
+}
+
+func (s *SimpleGptChat) CompleteJSON(userID string, req JSONRequest) (string, error) {
+	cfg := openai.DefaultConfig(s.token)
+	cfg.BaseURL = s.url
+	client := openai.NewClientWithConfig(cfg)
+	model := req.Model
+	if model == "" {
+		model = s.getModel(userID)
+	}
+	r := openai.ChatCompletionRequest{
+		Model: model,
+		Messages: []openai.ChatCompletionMessage{
+			{Role: openai.ChatMessageRoleSystem, Content: jsonSystem(req)},
+			{Role: openai.ChatMessageRoleUser, Content: req.Prompt},
+		},
+		ResponseFormat: &openai.ChatCompletionResponseFormat{Type: openai.ChatCompletionResponseFormatTypeJSONObject},
+	}
+	if s.maxTokens > 0 {
+		r.MaxTokens = s.maxTokens
+	}
+	return s.complete(client, userID, r)
+}
+
+func (s *GeminiChat) CompleteJSON(userID string, req JSONRequest) (string, error) {
+	ctx := context.Background()
+	client, err := genai.NewClient(ctx, option.WithAPIKey(s.key))
+	if err != nil {
+		return "", err
+	}
+	defer client.Close()
+	name := req.Model
+	if name == "" {
+		name = s.getModel(userID)
+	}
+	model := client.GenerativeModel(name)
+	if s.maxTokens > 0 {
+		model.SetMaxOutputTokens(int32(s.maxTokens))
+	}
+	model.SafetySettings, err = parseGeminiSafetySettings(config.GetGeminiSafety())
+	if err != nil {
+		return "", err
+	}
+	model.SystemInstruction = genai.NewUserContent(genai.Text(req.System))
+	model.ResponseMIMEType = "application/json"
+	model.ResponseSchema = toGeminiSchema(req.Schema)
+	_, text, err := geminiReply(model.GenerateContent(ctx, genai.Text(req.Prompt)))
+	return text, err
+}
+
+// toGeminiSchema 把json schema转换为gemini的Schema
+func toGeminiSchema(schema map[string]any) *genai.Schema {
+	if schema == nil {
+		return nil
+	}
+	typ, _ := schema["type"].(string)
+	s := &genai.Schema{Type: geminiSchemaType(typ)}
+	s.Description, _ = schema["description"].(string)
+	if enum := schemaStrings(schema["enum"]); len(enum) > 0 {
+		s.Enum, s.Format = enum, "enum"
+	}
+	if items, ok := schema["items"].(map[string]any); ok {
+		s.Items = toGeminiSchema(items)
+	}
+	if properties, ok := schema["properties"].(map[string]any); ok {
+		s.Properties = make(map[string]*genai.Schema, len(properties))
+		for name, p := range properties {
+			if p, ok := p.(map[string]any); ok {
+				s.Properties[name] = toGeminiSchema(p)
+			}
+		}
+	}
+	s.Required = schemaStrings(schema["required"])
+	return s
+}
+
+func geminiSchemaType(typ string) genai.Type {
+	switch typ {
+	case "object":
+		return genai.TypeObject
+	case "array":
+		return genai.TypeArray
+	default:
+		return geminiParamType(typ)
+	}
+}
+
+func schemaStrings(v any) []string {
+	switch v := v.(type) {
+	case []string:
+		return v
+	case []any:
+		list := make([]string, 0, len(v))
+		for _, item := range v {
+			list = append(list, fmt.Sprint(item))
+		}
+		return list
+	}
+	return nil
+}
+
+func (chat *QwenChat) CompleteJSON(userId string, req JSONRequest) (string, error) {
+	model := req.Model
+	if model == "" {
+		model = chat.getModel(userId)
+	}
+	msgs := []QwenMessage{
+		{Role: QwenChatSystem, Content: jsonSystem(req)},
+		{Role: QwenChatUser, Content: req.Prompt},
+	}
+	var wireMsgs []QwenWireMessage
+	if chat.Config.Mode == config.Qwen_Mode_OpenAI {
+		wireMsgs = toQwenOpenAIMessages(msgs, false)
+	} else {
+		wireMsgs = toQwenDashscopeMessages(msgs, false)
+	}
+	result, err := chat.send(model, wireMsgs, nil, &QwenResponseFormat{Type: Qwen_Response_Format_Json})
+	if err != nil {
+		return "", err
+	}
+	return result.Content, nil
+}
@@ -0,0 +1,80 @@
+package chat
+
+import (
+	"testing"
+
+	"github.com/google/generative-ai-go/genai"
+	"github.com/pwh-pwh/aiwechat-vercel/config"
+)
+
+var testJSONSchema = map[string]any{
+	"type": "object",
+	"properties": map[string]any{
+		"records": map[string]any{
+			"type": "array",
+			"items": map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"名称": map[string]any{"type": "string"},
+					"金额": map[string]any{"type": "number"},
+					"标签": map[string]any{"type": "string", "enum": []string{"出行", "其他"}},
+				},
+				"required": []string{"名称", "金额"},
+			},
+		},
+	},
+	"required": []string{"records"},
+}
+
+func TestQwenCompleteJSON(t *testing.T) {
+	for _, mode := range []string{config.Qwen_Mode_Dashscope, config.Qwen_Mode_OpenAI} {
+		server := newQwenStub(t, func(path string, body map[string]any) string {
+			format := body["response_format"]
+			if mode == config.Qwen_Mode_Dashscope {
+				format = body["parameters"].(map[string]any)["response_format"]
+			}
+			if format.(map[string]any)["type"] != Qwen_Response_Format_Json {
+				t.Errorf("%s response_format = %v", mode, format)
+			}
+			if body["model"] != "qwen-turbo" {
+				t.Errorf("%s model = %v", mode, body["model"])
+			}
+			if mode == config.Qwen_Mode_OpenAI {
+				return `{"choices":[{"finish_reason":"stop","message":{"role":"assistant","content":"{\"records\":[]}"}}]}`
+			}
+			return `{"output":{"choices":[{"finish_reason":"stop","message":{"role":"assistant","content":"{\"records\":[]}"}}]}}`
+		})
+		chat := &QwenChat{BaseChat: SimpleChat{}, Config: &config.QwenConfig{
+			HostUrl:      server.URL,
+			ApiKey:       "test-key",
+			ModelVersion: "qwen-max",
+			Mode:         mode,
+		}}
+		r, err := chat.CompleteJSON("qwenJsonUser", JSONRequest{System: "记账", Prompt: "打车20", Schema: testJSONSchema, Model: "qwen-turbo"})
+		if err != nil || r != `{"records":[]}` {
+			t.Errorf("%s CompleteJSON = %q, %v", mode, r, err)
+		}
+	}
+}
+
+func TestCompleteJSONFallback(t *testing.T) {
+	// Echo不支持json模式，原样返回prompt，用来检查去掉代码块
+	r, err := completeJSON(&Echo{}, "echoUser", JSONRequest{Prompt: "```json\n{\"records\":[]}\n```", Schema: testJSONSchema})
+	if err != nil || r != `{"records":[]}` {
+		t.Errorf("completeJSON = %q, %v", r, err)
+	}
+}
+
+func TestToGeminiSchema(t *testing.T) {
+	s := toGeminiSchema(testJSONSchema)
+	items := s.Properties["records"].Items
+	if s.Type != genai.TypeObject || s.Properties["records"].Type != genai.TypeArray || items.Type != genai.TypeObject {
+		t.Fatalf("schema = %+v", s)
+	}
+	if tag := items.Properties["标签"]; tag.Type != genai.TypeString || tag.Format != "enum" || len(tag.Enum) != 2 {
+		t.Errorf("标签 = %+v", tag)
+	}
+	if items.Properties["金额"].Type != genai.TypeNumber || len(items.Required) != 2 {
+		t.Errorf("items = %+v", items)
+	}
+}
@@ -0,0 +1,39 @@
+package chat
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pwh-pwh/aiwechat-vercel/config"
+	"github.com/pwh-pwh/aiwechat-vercel/rag"
+)
+
+func init() {
+	RegisterContextProvider(knowledgeContext)
+}
+
+// knowledgeContext 每次对话检索公众号知识库，把相关片段注入提示词
+func knowledgeContext(userId, msg string) string {
+	chunks, err := rag.Search(msg, config.GetKbTopK())
+	if err != nil {
+		fmt.Println("kb search error:", err)
+		return ""
+	}
+	return formatKnowledge(chunks)
+}
+
+func formatKnowledge(chunks []rag.Chunk) string {
+	if len(chunks) == 0 {
+		return ""
+	}
+	var sb strings.Builder
+	sb.WriteString("以下是公众号知识库中与用户问题相关的资料。回答涉及本公众号产品或服务的问题时请以资料为准，资料中没有提到的内容不要编造，可以建议用户联系人工客服。\n")
+	for _, chunk := range chunks {
+		source := chunk.Source
+		if chunk.Title != "" {
+			source += " " + chunk.Title
+		}
+		sb.WriteString(fmt.Sprintf("[%s] %s\n", source, chunk.Text))
+	}
+	return strings.TrimSpace(sb.String())
+}
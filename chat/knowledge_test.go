@@ -0,0 +1,23 @@
+package chat
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/pwh-pwh/aiwechat-vercel/rag"
+)
+
+func TestKnowledgeContext(t *testing.T) {
+	if _, _, err := rag.Add("faq.md", "# 退货\n收到商品七天内可以无理由退货。\n"); err != nil {
+		t.Fatal(err)
+	}
+	defer rag.Remove("faq.md")
+
+	context := knowledgeContext("u1", "可以退货吗")
+	if !strings.Contains(context, "[faq.md 退货] 收到商品七天内可以无理由退货。") {
+		t.Errorf("context = %s", context)
+	}
+	if context = knowledgeContext("u1", "hello"); context != "" {
+		t.Errorf("unrelated message should not inject knowledge: %s", context)
+	}
+}
@@ -0,0 +1,337 @@
+package chat
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/pwh-pwh/aiwechat-vercel/config"
+	"github.com/pwh-pwh/aiwechat-vercel/db"
+	"github.com/pwh-pwh/aiwechat-vercel/ledger"
+)
+
+func init() {
+	RegisterCommand(&Command{Name: config.Wx_Ledger, Aliases: []string{"记账设置"}, Args: []CommandArg{{Name: "设置", Type: Arg_Text, Optional: true}},
+		Usage: "查看或修改Notion记账的字段，可选值默认从数据库读取。/ledger 支付方式 微信,支付宝,信用卡 设置可选值；/ledger 金额=花费 设置属性名；" +
+			"/ledger 支付方式 reset 恢复从数据库读取；/ledger refresh 重新读取数据库；开头加 工资 修改工资数据库，如 /ledger 工资 标签 工资,奖金；" +
+			"/ledger 自动确认 on 记账时不预览直接写入",
+		Handler: paramCommand(LedgerSettings, "设置")})
+	RegisterCommand(&Command{Name: config.Wx_Ledger_Undo, Aliases: []string{"撤销"}, Usage: "撤销最近一次写入Notion的记账记录",
+		Handler: func(args *CommandArgs, userId string) string { return UndoLedger(userId) }})
+	RegisterCommand(&Command{Name: config.Wx_Ledger_Recent, Aliases: []string{"最近记录"}, Args: []CommandArg{{Name: "条数", Type: Arg_Int, Optional: true}},
+		Usage: "查看最近写入的记账记录，默认10条", Handler: func(args *CommandArgs, userId string) string {
+			return RecentLedger(userId, args.Int("条数"))
+		}})
+	RegisterCommand(&Command{Name: config.Wx_Ledger_Edit, Aliases: []string{"修改"}, Args: []CommandArg{{Name: "编号", Type: Arg_Int}, {Name: "字段", Type: Arg_String}, {Name: "值", Type: Arg_Text}},
+		Usage: "修改最近记录中的一条，编号见 最近记录，如 修改 3 金额 50", Handler: func(args *CommandArgs, userId string) string {
+			return EditLedger(userId, args.Int("编号"), args.String("字段"), args.String("值"))
+		}})
+	RegisterCommand(&Command{Name: config.Wx_Ledger_Report, Aliases: []string{"账单"}, Args: []CommandArg{{Name: "条件", Type: Arg_Text, Optional: true}},
+		Usage: "统计支出，按标签、开支类型、支付方式汇总，如 /lstat 上月 微信 分析，时间可以是" + strings.Join(ledger.PeriodNames, "、") +
+			"，默认本月；加 分析 时由模型点评消费变化。也可以直接发送 本月支出、上月各类别、本周微信支付",
+		Handler: paramCommand(LedgerReportCommand, "条件")})
+}
+
+// LedgerSettings 查看或修改用户的记账字段设置
+func LedgerSettings(param, userId string) string {
+	databaseType := ledger.Type_Expense
+	if rest, ok := strings.CutPrefix(param, "工资"); ok {
+		databaseType, param = ledger.Type_Income, strings.TrimSpace(rest)
+	}
+	var err error
+	switch strings.ToLower(param) {
+	case "":
+		return showLedgerSchema(userId, databaseType, false)
+	case "refresh", "刷新":
+		return showLedgerSchema(userId, databaseType, true)
+	case "reset", "重置":
+		if err = ledger.ResetSettings(userId, databaseType); err != nil {
+			return err.Error()
+		}
+		return "已删除字段设置，全部从数据库读取"
+	}
+	if value, ok := strings.CutPrefix(param, "自动确认"); ok {
+		return setLedgerAutoConfirm(userId, strings.TrimSpace(value))
+	}
+	if field, property, ok := strings.Cut(param, "="); ok {
+		field, property = strings.TrimSpace(field), strings.TrimSpace(property)
+		err = ledger.SetProperty(userId, databaseType, field, property)
+	} else {
+		field, value, _ := strings.Cut(param, " ")
+		var choices []string
+		if value = strings.TrimSpace(value); value != "" && strings.ToLower(value) != "reset" {
+			choices = strings.FieldsFunc(value, func(r rune) bool {
+				return r == ',' || r == '，' || r == '、' || isCommandSpace(r)
+			})
+		}
+		err = ledger.SetOptions(userId, databaseType, field, choices)
+	}
+	if errors.Is(err, ledger.ErrUnknownField) {
+		return fmt.Sprintf("%s，可以设置的字段：%s", err, strings.Join(ledger.GetSettings(userId, databaseType).Fields(), "、"))
+	}
+	if err != nil {
+		return err.Error()
+	}
+	return "已修改\n" + showLedgerSchema(userId, databaseType, false)
+}
+
+// showLedgerSchema 绑定了数据库时显示合并数据库选项后的字段，否则只显示默认值和用户的设置
+func showLedgerSchema(userId, databaseType string, refresh bool) string {
+	account, err := ledger.GetAccount(userId, databaseType)
+	if errors.Is(err, ledger.ErrNoAccount) {
+		account = &ledger.Account{UserId: userId, Type: databaseType}
+	} else if err != nil {
+		return err.Error()
+	}
+	if account.DatabaseId == "" {
+		return "未绑定数据库，绑定后会从数据库读取可选值\n" + ledger.LoadSchema(account).String()
+	}
+	if refresh {
+		ledger.RefreshSchema(account)
+	}
+	return ledger.LoadSchema(account).String()
+}
+
+const ledgerPreviewHint = "回复 确认 写入，回复 取消 放弃，回复 序号 字段 值 修改，如 1 金额 45"
+
+// RecordLedger 用模型识别文字中的记录，预览后等待用户确认，开启自动确认时直接写入
+func RecordLedger(userId, databaseType, text string) string {
+	account, err := ledger.GetAccount(userId, databaseType)
+	if err != nil {
+		log.Println("Error querying user config:", err)
+		return ledger.ErrNoAccount.Error()
+	}
+	schema := ledger.LoadSchema(account)
+	complete := ledgerCompleter(userId)
+	batch := &ledger.Batch{Type: databaseType}
+	if databaseType == ledger.Type_Income {
+		batch.Incomes, err = ledger.ExtractIncomes(complete, schema, text, config.Now())
+		if err != nil {
+			return "识别工资记录失败: " + err.Error()
+		}
+	} else {
+		batch.Expenses, err = ledger.ExtractExpenses(complete, schema, text, config.Now())
+		if err != nil {
+			return "识别记账记录失败: " + err.Error()
+		}
+	}
+	return StageLedger(userId, account, schema, batch, false)
+}
+
+// StageLedger 开启自动确认时直接写入，否则保存为待确认的记录并返回预览
+// merge为true时追加到同类型的待确认记录，用于模型分多次调用记账工具
+func StageLedger(userId string, account *ledger.Account, schema ledger.Schema, batch *ledger.Batch, merge bool) string {
+	if db.IsLedgerAutoConfirm(userId) {
+		return ledger.Feedback(batch.Insert(account, schema))
+	}
+	if pending := ledger.GetPending(userId); merge && pending != nil && pending.Type == batch.Type {
+		pending.Expenses = append(pending.Expenses, batch.Expenses...)
+		pending.Incomes = append(pending.Incomes, batch.Incomes...)
+		batch = pending
+	}
+	if err := ledger.SavePending(userId, batch); err != nil {
+		return err.Error()
+	}
+	return fmt.Sprintf("识别到%d条记录，%s\n%s", batch.Len(), ledgerPreviewHint, batch.Preview(schema))
+}
+
+var ledgerEditPattern = regexp.MustCompile(`^(\d+)\s+(\S+)\s+(.+)$`)
+
+// ConfirmLedger 处理对待确认记录的回复，不是 确认、取消 或 序号 字段 值 时返回false，交给其它功能处理
+func ConfirmLedger(userId, msg string) (string, bool) {
+	msg = strings.TrimSpace(msg)
+	match := ledgerEditPattern.FindStringSubmatch(msg)
+	if msg != "确认" && msg != "取消" && match == nil {
+		return "", false
+	}
+	if msg == "确认" || msg == "取消" {
+		batch := ledger.TakePending(userId)
+		if batch == nil {
+			return "", false
+		}
+		if msg == "取消" {
+			return "已取消，记录没有写入", true
+		}
+		account, err := ledger.GetAccount(userId, batch.Type)
+		if err != nil {
+			return err.Error(), true
+		}
+		return ledger.Feedback(batch.Insert(account, ledger.LoadSchema(account))), true
+	}
+	batch := ledger.GetPending(userId)
+	if batch == nil {
+		return "", false
+	}
+	account, err := ledger.GetAccount(userId, batch.Type)
+	if err != nil {
+		return err.Error(), true
+	}
+	schema := ledger.LoadSchema(account)
+	// 0 开头或不是字段名时是新的记账消息，如 1 3月工资 10000
+	index, _ := strconv.Atoi(match[1])
+	if index == 0 || !slices.Contains(schema.Fields(), match[2]) {
+		return "", false
+	}
+	if err = batch.Edit(schema, index, match[2], strings.TrimSpace(match[3])); err != nil {
+		return err.Error(), true
+	}
+	if err = ledger.SavePending(userId, batch); err != nil {
+		return err.Error(), true
+	}
+	return fmt.Sprintf("已修改，%s\n%s", ledgerPreviewHint, batch.Preview(schema)), true
+}
+
+// setLedgerAutoConfirm /ledger 自动确认 on|off
+func setLedgerAutoConfirm(userId, value string) string {
+	switch strings.ToLower(value) {
+	case "on", "开":
+		if err := db.SetLedgerAutoConfirm(userId, true); err != nil {
+			return err.Error()
+		}
+		return "已开启自动确认，记账时不再预览，直接写入"
+	case "off", "关":
+		if err := db.SetLedgerAutoConfirm(userId, false); err != nil {
+			return err.Error()
+		}
+		return "已关闭自动确认，记账前会先预览"
+	}
+	if db.IsLedgerAutoConfirm(userId) {
+		return "自动确认已开启，/ledger 自动确认 off 关闭"
+	}
+	return "自动确认已关闭，/ledger 自动确认 on 开启"
+}
+
+// ledgerCompleter 使用 ledgerBot、ledgerModel 配置的模型识别记录，未配置时使用用户当前的bot和模型
+func ledgerCompleter(userId string) ledger.Completer {
+	return func(system, prompt string, schema map[string]any) (string, error) {
+		return CompleteJSON(userId, config.GetLedgerBot(), JSONRequest{
+			System: system,
+			Prompt: prompt,
+			Schema: schema,
+			Model:  config.GetLedgerModel(),
+		})
+	}
+}
+
+// UndoLedger 归档最近一次写入的记录
+func UndoLedger(userId string) string {
+	results, err := ledger.Undo(userId)
+	if err != nil && len(results) == 0 {
+		return err.Error()
+	}
+	var lines []string
+	for _, r := range results {
+		if r.Err != nil {
+			lines = append(lines, fmt.Sprintf("撤销%s失败: %v", r.Name, r.Err))
+		} else {
+			lines = append(lines, "已撤销: "+r.Name)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// RecentLedger 最近写入的n条记录，编号用于 修改
+func RecentLedger(userId string, n int) string {
+	if n <= 0 {
+		n = 10
+	}
+	history := ledger.GetHistory(userId)
+	if len(history) == 0 {
+		return "还没有记账记录"
+	}
+	if n < len(history) {
+		history = history[:n]
+	}
+	lines := make([]string, 0, len(history))
+	for i, r := range history {
+		lines = append(lines, fmt.Sprintf("%d. %s", i+1, r))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// EditLedger 修改最近记录中的一条，同时更新Notion中的页面
+func EditLedger(userId string, index int, field, value string) string {
+	r, err := ledger.Edit(userId, index, field, strings.TrimSpace(value))
+	if errors.Is(err, ledger.ErrUnknownField) && r != nil {
+		return fmt.Sprintf("%s，可以修改的字段：%s", err, strings.Join(ledger.GetSettings(userId, r.Type).Fields(), "、"))
+	}
+	if err != nil {
+		return err.Error()
+	}
+	return fmt.Sprintf("已修改 %d. %s", index, r)
+}
+
+var ledgerReportPattern = regexp.MustCompile(`^(` + strings.Join(ledger.PeriodNames, "|") + `)(.*?)(支出|支付|花费|开销|类别|账单)(分析)?$`)
+
+// MatchLedgerReport 处理 本月支出、上月各类别、本周微信支付 这类统计消息，不是统计消息时返回false
+func MatchLedgerReport(userId, msg string) (string, bool) {
+	match := ledgerReportPattern.FindStringSubmatch(strings.TrimSpace(msg))
+	if match == nil {
+		return "", false
+	}
+	return LedgerReport(userId, match[1], strings.TrimPrefix(match[2], "各"), match[4] != ""), true
+}
+
+// LedgerReportCommand /lstat 时间 筛选 分析，都可以省略
+func LedgerReportCommand(param, userId string) string {
+	period, filter, analyze := "本月", "", false
+	for _, word := range strings.FieldsFunc(param, isCommandSpace) {
+		switch {
+		case slices.Contains(ledger.PeriodNames, word):
+			period = word
+		case word == "分析":
+			analyze = true
+		default:
+			filter = word
+		}
+	}
+	return LedgerReport(userId, period, filter, analyze)
+}
+
+const ledgerCommentSystem = "你是理财助手，根据用户两个时间段的支出统计，用三四句话点评这段时间的消费结构和与上一期相比的变化，并给出一条具体的建议。" +
+	"本期可能还没有结束，比较时注意日期范围，不要逐条重复统计中的数字。"
+
+// LedgerReport 统计用户记账数据库中一段时间的支出，filter为标签、开支类型或支付方式的选项，analyze时附加模型的点评
+func LedgerReport(userId, periodName, filter string, analyze bool) string {
+	account, err := ledger.GetAccount(userId, ledger.Type_Expense)
+	if err != nil {
+		log.Println("Error querying user config:", err)
+		return ledger.ErrNoAccount.Error()
+	}
+	schema := ledger.LoadSchema(account)
+	period, ok := ledger.ParsePeriod(periodName, config.Now())
+	if !ok {
+		return "时间只能是" + strings.Join(ledger.PeriodNames, "、")
+	}
+	filters := map[string]string{}
+	if filter != "" {
+		field, choice, ok := schema.FindChoice(filter)
+		if !ok {
+			return fmt.Sprintf("没有找到「%s」，可以按标签、开支类型、支付方式的选项筛选，/ledger 查看可选值", filter)
+		}
+		filters[field] = choice
+	}
+	expenses, err := ledger.QueryExpenses(account, schema, period, filters)
+	if err != nil {
+		return "查询支出失败: " + err.Error()
+	}
+	report := ledger.Summarize(period, filters, expenses)
+	if !analyze || report.Count == 0 {
+		return report.String()
+	}
+	previous, err := ledger.QueryExpenses(account, schema, period.Previous(), filters)
+	if err != nil {
+		return report.String() + "\n\n查询上一期支出失败: " + err.Error()
+	}
+	prompt := report.String() + "\n\n" + ledger.Summarize(period.Previous(), filters, previous).String()
+	comment, err := Complete(userId, ledgerCommentSystem, prompt)
+	if err != nil {
+		log.Println("Error commenting ledger report:", err)
+		return report.String() + "\n\n点评失败: " + err.Error()
+	}
+	return report.String() + "\n\n" + strings.TrimSpace(comment)
+}
@@ -0,0 +1,266 @@
+package chat
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/pwh-pwh/aiwechat-vercel/config"
+	"github.com/pwh-pwh/aiwechat-vercel/db"
+	"github.com/pwh-pwh/aiwechat-vercel/ledger"
+)
+
+// useEmptyNotion 配置数据库中没有任何绑定记录
+func useEmptyNotion(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"results":[],"has_more":false}`))
+	}))
+	t.Cleanup(server.Close)
+	t.Setenv(config.Notion_Url_Key, server.URL)
+	t.Setenv(config.Notion_Api_Key_Key, "secret")
+	t.Setenv(config.Notion_Config_Database_Id_Key, "config-db")
+}
+
+func TestLedgerSettings(t *testing.T) {
+	useEmptyNotion(t)
+	t.Cleanup(func() {
+		ledger.ResetSettings("ledger-u1", ledger.Type_Expense)
+		ledger.ResetSettings("ledger-u1", ledger.Type_Income)
+	})
+
+	if r, _ := RunCommand("ledger-u1", "记账设置"); !strings.HasPrefix(r, "未绑定数据库") || !strings.Contains(r, "支付宝、微信、银行卡") {
+		t.Errorf("/ledger = %q", r)
+	}
+	if r, _ := RunCommand("ledger-u1", "/ledger 支付方式 微信，信用卡"); !strings.HasPrefix(r, "已修改") || !strings.Contains(r, "支付方式\n  微信、信用卡") {
+		t.Errorf("/ledger set options = %q", r)
+	}
+	if r, _ := RunCommand("ledger-u1", "/ledger 金额 = 花费"); !strings.Contains(r, "金额：属性「花费」") {
+		t.Errorf("/ledger set property = %q", r)
+	}
+	if r, _ := RunCommand("ledger-u1", "/ledger 颜色 红"); !strings.HasPrefix(r, "没有这个字段，可以设置的字段：名称、金额") {
+		t.Errorf("/ledger unknown field = %q", r)
+	}
+	if r, _ := RunCommand("ledger-u1", "/ledger 工资 标签 工资,奖金"); !strings.Contains(r, "标签\n  工资、奖金") {
+		t.Errorf("/ledger income = %q", r)
+	}
+	// 支出数据库的设置不受影响
+	if r, _ := RunCommand("ledger-u1", "/ledger 支付方式 reset"); !strings.Contains(r, "支付宝、微信、银行卡") || !strings.Contains(r, "花费") {
+		t.Errorf("/ledger reset options = %q", r)
+	}
+	if r, _ := RunCommand("ledger-u1", "/ledger reset"); r != "已删除字段设置，全部从数据库读取" {
+		t.Errorf("/ledger reset = %q", r)
+	}
+	if s := ledger.GetSettings("ledger-u1", ledger.Type_Expense); len(s.Properties) != 0 || len(s.Options) != 0 {
+		t.Errorf("settings after reset = %+v", s)
+	}
+}
+
+// ledgerAccountResult 配置数据库中ledger-u2绑定的支出数据库
+const ledgerAccountResult = `{"results":[{"id":"account","properties":{` +
+	`"用户id":{"type":"title","title":[{"plain_text":"ledger-u2"}]},` +
+	`"NOTION_API_KEY":{"type":"rich_text","rich_text":[{"plain_text":"user-secret"}]},` +
+	`"DATABASE_ID":{"type":"rich_text","rich_text":[{"plain_text":"expense-db"}]},` +
+	`"数据库类型":{"type":"select","select":{"name":"0"}}}}],"has_more":false}`
+
+// useLedgerNotion 用户绑定了支出数据库，返回写入的记录
+func useLedgerNotion(t *testing.T) func() []map[string]any {
+	var mu sync.Mutex
+	var created []map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch {
+		case r.URL.Path == "/v1/databases/config-db/query":
+			w.Write([]byte(ledgerAccountResult))
+		case r.Method == http.MethodPost && r.URL.Path == "/v1/pages":
+			var body map[string]any
+			json.NewDecoder(r.Body).Decode(&body)
+			created = append(created, body["properties"].(map[string]any))
+			w.Write([]byte(fmt.Sprintf(`{"id":"page%d"}`, len(created))))
+		case r.Method == http.MethodPatch && strings.HasPrefix(r.URL.Path, "/v1/pages/"):
+			w.Write([]byte(`{"id":"` + strings.TrimPrefix(r.URL.Path, "/v1/pages/") + `"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+	t.Setenv(config.Notion_Url_Key, server.URL)
+	t.Setenv(config.Notion_Api_Key_Key, "secret")
+	t.Setenv(config.Notion_Config_Database_Id_Key, "config-db")
+	// echo原样返回prompt，消息内容就是模型输出的json
+	t.Setenv(config.Ledger_Bot_Key, config.Bot_Type_Echo)
+	t.Cleanup(func() { db.SetLedgerHistory("ledger-u2", "") })
+	return func() []map[string]any {
+		mu.Lock()
+		defer mu.Unlock()
+		return created
+	}
+}
+
+func TestRecordLedgerPreview(t *testing.T) {
+	created := useLedgerNotion(t)
+	t.Cleanup(func() { ledger.DeletePending("ledger-u2") })
+	records := `{"records":[{"名称":"打车","金额":20,"标签":"出行","日期":"2025-03-08","支付方式":"微信","开支类型":"日常开支"},` +
+		`{"名称":"午饭","金额":30,"标签":"生活吃喝加买菜","日期":"2025-03-08","支付方式":"支付宝","开支类型":"日常开支"}]}`
+
+	r := RecordLedger("ledger-u2", ledger.Type_Expense, records)
+	if !strings.HasPrefix(r, "识别到2条记录") || !strings.Contains(r, "2. 午饭 30元\n   标签：生活吃喝加买菜，日期：2025-03-08，支付方式：支付宝") {
+		t.Fatalf("preview = %q", r)
+	}
+	if len(created()) != 0 {
+		t.Fatal("records inserted before confirm")
+	}
+	if _, ok := ConfirmLedger("ledger-u2", "你好"); ok {
+		t.Error("normal message handled")
+	}
+	if _, ok := ConfirmLedger("ledger-u2", "1 3月工资 10000"); ok {
+		t.Error("new record handled as edit")
+	}
+	if r, _ := ConfirmLedger("ledger-u2", "2 金额 45元"); !strings.HasPrefix(r, "已修改") || !strings.Contains(r, "2. 午饭 45元") {
+		t.Errorf("edit = %q", r)
+	}
+	if r, _ := ConfirmLedger("ledger-u2", "1 支付方式 现金"); !strings.Contains(r, "不能是现金") {
+		t.Errorf("invalid edit = %q", r)
+	}
+	if r, _ := ConfirmLedger("ledger-u2", "3 金额 10"); r != "没有第3条记录" {
+		t.Errorf("edit out of range = %q", r)
+	}
+	if r, _ := ConfirmLedger("ledger-u2", "确认"); r != "Successfully added: 打车\nSuccessfully added: 午饭" {
+		t.Errorf("confirm = %q", r)
+	}
+	if pages := created(); len(pages) != 2 || pages[1]["金额"].(map[string]any)["number"] != 45.0 {
+		t.Errorf("created = %v", pages)
+	}
+	if _, ok := ConfirmLedger("ledger-u2", "确认"); ok {
+		t.Error("confirm handled without pending records")
+	}
+
+	RecordLedger("ledger-u2", ledger.Type_Expense, records)
+	if r, _ := ConfirmLedger("ledger-u2", "取消"); r != "已取消，记录没有写入" || len(created()) != 2 {
+		t.Errorf("cancel = %q", r)
+	}
+}
+
+func TestStageLedger(t *testing.T) {
+	created := useLedgerNotion(t)
+	t.Cleanup(func() { ledger.DeletePending("ledger-u2") })
+	account, err := ledger.GetAccount("ledger-u2", ledger.Type_Expense)
+	if err != nil {
+		t.Fatal(err)
+	}
+	schema := ledger.LoadSchema(account)
+	for _, e := range []ledger.Expense{
+		{Name: "打车", Amount: 20, Tag: "出行", Date: "2025-03-08", Payment: "微信", Type: "日常开支"},
+		{Name: "午饭", Amount: 30, Tag: "生活吃喝加买菜", Date: "2025-03-08", Payment: "支付宝", Type: "日常开支"},
+	} {
+		r := StageLedger("ledger-u2", account, schema, &ledger.Batch{Type: ledger.Type_Expense, Expenses: []ledger.Expense{e}}, true)
+		if !strings.Contains(r, e.Name) {
+			t.Errorf("stage %s = %q", e.Name, r)
+		}
+	}
+	if len(created()) != 0 {
+		t.Fatal("records inserted before confirm")
+	}
+	if r, _ := ConfirmLedger("ledger-u2", "确认"); r != "Successfully added: 打车\nSuccessfully added: 午饭" {
+		t.Errorf("confirm = %q", r)
+	}
+}
+
+func TestLedgerAutoConfirm(t *testing.T) {
+	created := useLedgerNotion(t)
+	t.Cleanup(func() { db.SetLedgerAutoConfirm("ledger-u2", false) })
+	if r, _ := RunCommand("ledger-u2", "/ledger 自动确认 on"); !strings.HasPrefix(r, "已开启自动确认") {
+		t.Fatalf("/ledger 自动确认 on = %q", r)
+	}
+	r := RecordLedger("ledger-u2", ledger.Type_Expense, `{"records":[{"名称":"打车","金额":20,"标签":"出行","日期":"2025-03-08","支付方式":"微信","开支类型":"日常开支"}]}`)
+	if r != "Successfully added: 打车" || len(created()) != 1 {
+		t.Errorf("auto confirm = %q", r)
+	}
+	if r, _ := RunCommand("ledger-u2", "/ledger 自动确认"); !strings.HasPrefix(r, "自动确认已开启") {
+		t.Errorf("/ledger 自动确认 = %q", r)
+	}
+}
+
+func TestLedgerHistory(t *testing.T) {
+	useLedgerNotion(t)
+	db.SetLedgerAutoConfirm("ledger-u2", true)
+	t.Cleanup(func() { db.SetLedgerAutoConfirm("ledger-u2", false) })
+	if r, _ := RunCommand("ledger-u2", "最近记录"); r != "还没有记账记录" {
+		t.Errorf("最近记录 = %q", r)
+	}
+	RecordLedger("ledger-u2", ledger.Type_Expense, `{"records":[{"名称":"打车","金额":20,"标签":"出行","日期":"2025-03-08","支付方式":"微信","开支类型":"日常开支"},`+
+		`{"名称":"午饭","金额":30,"标签":"生活吃喝加买菜","日期":"2025-03-08","支付方式":"支付宝","开支类型":"日常开支"}]}`)
+
+	if r, _ := RunCommand("ledger-u2", "最近记录"); r != "1. 2025-03-08 午饭 30元\n2. 2025-03-08 打车 20元" {
+		t.Errorf("最近记录 = %q", r)
+	}
+	if r, _ := RunCommand("ledger-u2", "修改 1 金额 35"); r != "已修改 1. 2025-03-08 午饭 35元" {
+		t.Errorf("修改 = %q", r)
+	}
+	if r, _ := RunCommand("ledger-u2", "修改 2 颜色 红"); !strings.HasPrefix(r, "没有这个字段，可以修改的字段：名称、金额") {
+		t.Errorf("修改 unknown field = %q", r)
+	}
+	if r, _ := RunCommand("ledger-u2", "撤销"); r != "已撤销: 午饭\n已撤销: 打车" {
+		t.Errorf("撤销 = %q", r)
+	}
+	if r, _ := RunCommand("ledger-u2", "撤销"); r != "没有可以撤销的记录" {
+		t.Errorf("撤销 again = %q", r)
+	}
+}
+
+func TestLedgerReport(t *testing.T) {
+	var filters []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/databases/config-db/query":
+			w.Write([]byte(ledgerAccountResult))
+		case "/v1/databases/expense-db/query":
+			var body map[string]any
+			json.NewDecoder(r.Body).Decode(&body)
+			filter, _ := json.Marshal(body["filter"])
+			filters = append(filters, string(filter))
+			w.Write([]byte(`{"results":[` +
+				`{"id":"p1","properties":{"名称":{"type":"title","title":[{"plain_text":"打车"}]},"金额":{"type":"number","number":20},` +
+				`"标签":{"type":"select","select":{"name":"出行"}},"日期":{"type":"date","date":{"start":"2025-03-08"}},` +
+				`"支付方式":{"type":"select","select":{"name":"微信"}},"开支类型":{"type":"select","select":{"name":"日常开支"}}}},` +
+				`{"id":"p2","properties":{"名称":{"type":"title","title":[{"plain_text":"午饭"}]},"金额":{"type":"number","number":30},` +
+				`"标签":{"type":"select","select":{"name":"生活吃喝加买菜"}},"日期":{"type":"date","date":{"start":"2025-03-09"}},` +
+				`"支付方式":{"type":"select","select":{"name":"微信"}},"开支类型":{"type":"select","select":{"name":"日常开支"}}}}` +
+				`],"has_more":false}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+	t.Setenv(config.Notion_Url_Key, server.URL)
+	t.Setenv(config.Notion_Api_Key_Key, "secret")
+	t.Setenv(config.Notion_Config_Database_Id_Key, "config-db")
+
+	if _, ok := MatchLedgerReport("ledger-u2", "本月天气怎么样"); ok {
+		t.Error("normal message handled")
+	}
+	r, ok := MatchLedgerReport("ledger-u2", "本周微信支付")
+	if !ok || !strings.HasPrefix(r, "本周支出（") || !strings.Contains(r, "，支付方式：微信\n共2笔，合计50元\n按标签：\n  生活吃喝加买菜 30元 60%，1笔") || strings.Contains(r, "按支付方式") {
+		t.Errorf("本周微信支付 = %q", r)
+	}
+	if len(filters) != 1 || !strings.Contains(filters[0], `"on_or_after"`) || !strings.Contains(filters[0], `"property":"支付方式","select":{"equals":"微信"}`) {
+		t.Errorf("filters = %v", filters)
+	}
+	if r, _ := MatchLedgerReport("ledger-u2", "上月各类别"); !strings.HasPrefix(r, "上月支出（") || !strings.Contains(r, "按支付方式：\n  微信 50元 100%，2笔") {
+		t.Errorf("上月各类别 = %q", r)
+	}
+	if r, _ := MatchLedgerReport("ledger-u2", "本月游戏支出"); !strings.HasPrefix(r, "没有找到「游戏」") {
+		t.Errorf("unknown filter = %q", r)
+	}
+
+	// echo原样返回prompt，点评中包含上一期的统计
+	t.Setenv(config.Bot_Type_Key, config.Bot_Type_Echo)
+	filters = nil
+	if r, _ := RunCommand("ledger-u2", "/lstat 上月 分析"); !strings.Contains(r, "\n\n上月支出（") || !strings.Contains(r, "上一期支出（") || len(filters) != 2 {
+		t.Errorf("/lstat 上月 分析 = %q", r)
+	}
+}
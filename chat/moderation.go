@@ -0,0 +1,57 @@
+package chat
+
+import (
+	"errors"
+
+	"github.com/pwh-pwh/aiwechat-vercel/moderation"
+	"github.com/silenceper/wechat/v2/officialaccount/message"
+)
+
+const (
+	Moderation_Input_Reply  = "消息包含不当内容，请修改后重试"
+	Moderation_Output_Reply = "回答包含不当内容，已被拦截"
+)
+
+// ModeratedChat 在bot前后审核用户输入和模型输出，配置了敏感词或审核接口时由GetChatBot包装
+type ModeratedChat struct {
+	BaseChat
+}
+
+func (c *ModeratedChat) Chat(userID string, msg string) string {
+	input := moderation.Moderate(msg)
+	moderation.Record(userID, moderation.STAGE_INPUT, input)
+	if input.Blocked {
+		return Moderation_Input_Reply
+	}
+	return moderateOutput(userID, c.BaseChat.Chat(userID, input.Text))
+}
+
+func (c *ModeratedChat) HandleMediaMsg(msg *message.MixMessage) string {
+	return moderateOutput(string(msg.FromUserName), c.BaseChat.HandleMediaMsg(msg))
+}
+
+func (c *ModeratedChat) Complete(userID, system, prompt string) (string, error) {
+	completer, ok := c.BaseChat.(Completer)
+	if !ok {
+		return "", errors.New("当前bot不支持该功能")
+	}
+	r, err := completer.Complete(userID, system, prompt)
+	if err != nil {
+		return "", err
+	}
+	output := moderation.Moderate(r)
+	moderation.Record(userID, moderation.STAGE_OUTPUT, output)
+	if output.Blocked {
+		return "", errors.New(Moderation_Output_Reply)
+	}
+	return output.Text, nil
+}
+
+func moderateOutput(userID, r string) string {
+	output := moderation.Moderate(r)
+	moderation.Record(userID, moderation.STAGE_OUTPUT, output)
+	if output.Blocked {
+		return Moderation_Output_Reply
+	}
+	return output.Text
+}
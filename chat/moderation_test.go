@@ -0,0 +1,29 @@
+package chat
+
+import (
+	"testing"
+
+	"github.com/pwh-pwh/aiwechat-vercel/config"
+	"github.com/pwh-pwh/aiwechat-vercel/moderation"
+)
+
+func TestModeratedChat(t *testing.T) {
+	t.Setenv("moderationActions", "政治=block")
+	moderation.MatcherBackend = moderation.NewMatcher(map[string]string{"代开发票": "广告", "敏感人物": "政治"})
+	t.Cleanup(func() { moderation.MatcherBackend = nil })
+
+	bot := &ModeratedChat{BaseChat: &Echo{}}
+	if r := bot.Chat("u1", "关于敏感人物"); r != Moderation_Input_Reply {
+		t.Errorf("blocked input = %q", r)
+	}
+	// 输入打码后再交给bot
+	if r := bot.Chat("u1", "代开发票"); r != "****" {
+		t.Errorf("masked = %q", r)
+	}
+	if _, err := bot.Complete("u1", "", "敏感人物"); err == nil || err.Error() != Moderation_Output_Reply {
+		t.Errorf("blocked output = %v", err)
+	}
+	if _, ok := GetChatBot(config.Bot_Type_Echo).(*ModeratedChat); !ok {
+		t.Error("GetChatBot should wrap bots when moderation is enabled")
+	}
+}
@@ -2,18 +2,26 @@ package chat
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"strings"
 
 	"github.com/bytedance/sonic"
 	"github.com/pwh-pwh/aiwechat-vercel/config"
 	"github.com/pwh-pwh/aiwechat-vercel/db"
+	"github.com/sashabaranov/go-openai"
+	"github.com/silenceper/wechat/v2/officialaccount/message"
 )
 
 const (
-	QwenChatUser = "user"
-	QwenChatBot  = "assistant"
+	QwenChatSystem = "system"
+	QwenChatUser   = "user"
+	QwenChatBot    = "assistant"
+	QwenChatTool   = "tool"
+
+	QwenImagePrompt = "请描述这张图片"
 )
 
 type QwenChat struct {
@@ -22,6 +30,7 @@ type QwenChat struct {
 	maxTokens int
 }
 
+// dashscope原生接口请求体
 type QwenRequest struct {
 	Model      string     `json:"model"`
 	Input      Input      `json:"input"`
@@ -29,42 +38,142 @@ type QwenRequest struct {
 }
 
 type Input struct {
-	Messages []QwenMessage `json:"messages"`
+	Messages []QwenWireMessage `json:"messages"`
 }
 
 type Parameters struct {
-	ResultFormat      string   `json:"result_format"`
-	Seed              int      `json:"seed"`
-	MaxTokens         int      `json:"max_tokens"`
-	TopP              float64  `json:"top_p"`
-	TopK              float64  `json:"top_k"`
-	RepetitionPenalty float64  `json:"repetition_penalty"`
-	Temperature       float64  `json:"temperature"`
-	Stop              string   `json:"stop"`
-	EnableSearch      bool     `json:"enable_search"`
-	IncrementalOutput bool     `json:"incremental_output"`
-	Tools             []string `json:"tools"`
+	ResultFormat      string              `json:"result_format,omitempty"`
+	Seed              int                 `json:"seed,omitempty"`
+	MaxTokens         int                 `json:"max_tokens,omitempty"`
+	TopP              float64             `json:"top_p"`
+	TopK              float64             `json:"top_k,omitempty"`
+	RepetitionPenalty float64             `json:"repetition_penalty"`
+	Temperature       float64             `json:"temperature"`
+	Stop              string              `json:"stop,omitempty"`
+	EnableSearch      bool                `json:"enable_search"`
+	IncrementalOutput bool                `json:"incremental_output"`
+	Tools             []openai.Tool       `json:"tools,omitempty"` // 与openai的工具格式一致，需要result_format为message
+	ResponseFormat    *QwenResponseFormat `json:"response_format,omitempty"`
+}
+
+// openai兼容模式请求体 参考：https://help.aliyun.com/zh/model-studio/developer-reference/compatibility-of-openai-with-dashscope
+type QwenOpenAIRequest struct {
+	Model          string              `json:"model"`
+	Messages       []QwenWireMessage   `json:"messages"`
+	MaxTokens      int                 `json:"max_tokens,omitempty"`
+	TopP           float64             `json:"top_p"`
+	Temperature    float64             `json:"temperature"`
+	EnableSearch   bool                `json:"enable_search,omitempty"`
+	Tools          []openai.Tool       `json:"tools,omitempty"`
+	ResponseFormat *QwenResponseFormat `json:"response_format,omitempty"`
+}
+
+const Qwen_Response_Format_Json = "json_object"
+
+// QwenResponseFormat 输出格式，json_object要求模型只输出json
+type QwenResponseFormat struct {
+	Type string `json:"type"`
 }
 
+// QwenMessage 为对话历史中的消息，Images仅在请求多模态模型时展开，保存历史时作为file类型的Parts
 type QwenMessage struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
+	Role    string   `json:"role"`
+	Content string   `json:"content"`
+	Images  []string `json:"-"`
+}
+
+// QwenWireMessage 为实际发送的消息，Content为字符串或多模态内容数组
+type QwenWireMessage struct {
+	Role       string            `json:"role"`
+	Content    any               `json:"content"`
+	ToolCalls  []openai.ToolCall `json:"tool_calls,omitempty"`
+	ToolCallId string            `json:"tool_call_id,omitempty"`
+	Name       string            `json:"name,omitempty"`
+}
+
+// dashscope原生多模态内容
+type QwenContent struct {
+	Image string `json:"image,omitempty"`
+	Text  string `json:"text,omitempty"`
+}
+
+// openai兼容模式多模态内容
+type QwenOpenAIContent struct {
+	Type     string        `json:"type"`
+	Text     string        `json:"text,omitempty"`
+	ImageUrl *QwenImageUrl `json:"image_url,omitempty"`
+}
+
+type QwenImageUrl struct {
+	Url string `json:"url"`
 }
 
 type QwenResponse struct {
 	Output    Output `json:"output"`
 	Usage     Usage  `json:"usage"`
 	RequestID string `json:"request_id"`
+	Code      string `json:"code"`
+	Message   string `json:"message"`
+
+	// openai兼容模式
+	Choices []QwenChoice `json:"choices"`
+	Error   *QwenError   `json:"error"`
 }
 
 type Output struct {
-	Text         string `json:"text"`
-	FinishReason string `json:"finish_reason"`
+	Text         string       `json:"text"`
+	FinishReason string       `json:"finish_reason"`
+	Choices      []QwenChoice `json:"choices"`
 }
 
+type QwenChoice struct {
+	FinishReason string          `json:"finish_reason"`
+	Message      QwenWireMessage `json:"message"`
+}
+
+type QwenError struct {
+	Code    string `json:"code"`
+	Type    string `json:"type"`
+	Message string `json:"message"`
+}
+
+// Usage 兼容原生接口(input/output_tokens)与openai兼容模式(prompt/completion_tokens)
 type Usage struct {
-	OutputTokens int `json:"output_tokens"`
-	InputTokens  int `json:"input_tokens"`
+	OutputTokens     int `json:"output_tokens"`
+	InputTokens      int `json:"input_tokens"`
+	ImageTokens      int `json:"image_tokens"`
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
+func (u Usage) Input() int {
+	if u.InputTokens > 0 {
+		return u.InputTokens
+	}
+	return u.PromptTokens
+}
+
+func (u Usage) Output() int {
+	if u.OutputTokens > 0 {
+		return u.OutputTokens
+	}
+	return u.CompletionTokens
+}
+
+func (u Usage) Total() int {
+	if u.TotalTokens > 0 {
+		return u.TotalTokens
+	}
+	return u.Input() + u.Output()
+}
+
+// QwenResult 为解析后的模型回复
+type QwenResult struct {
+	Content      string
+	FinishReason string
+	ToolCalls    []openai.ToolCall
+	Usage        Usage
 }
 
 func (chat *QwenChat) Chat(userId, message string) (res string) {
@@ -75,6 +184,17 @@ func (chat *QwenChat) Chat(userId, message string) (res string) {
 	return WithTimeChat(userId, message, chat.chat)
 }
 
+// HandleMediaMsg 多模态模型(qwen-vl)支持直接识别图片，其余模型保持默认处理
+func (chat *QwenChat) HandleMediaMsg(msg *message.MixMessage) string {
+	userId := string(msg.FromUserName)
+	if msg.MsgType == message.MsgTypeImage && isQwenVLModel(chat.getModel(userId)) {
+		return WithTimeChat(userId, msg.PicURL, func(userId, picUrl string) string {
+			return chat.chatWithImages(userId, QwenImagePrompt, []string{picUrl})
+		})
+	}
+	return chat.BaseChat.HandleMediaMsg(msg)
+}
+
 func (chat *QwenChat) getModel(userID string) string {
 	if model, err := db.GetModel(userID, config.Bot_Type_Qwen); err == nil && model != "" {
 		return model
@@ -82,81 +202,258 @@ func (chat *QwenChat) getModel(userID string) string {
 	return chat.Config.ModelVersion
 }
 
+func isQwenVLModel(model string) bool {
+	return strings.Contains(strings.ToLower(model), "-vl")
+}
+
 func (chat *QwenChat) chat(userId string, message string) (res string) {
+	return chat.chatWithImages(userId, message, nil)
+}
+
+func (chat *QwenChat) chatWithImages(userId, message string, images []string) (res string) {
 	var msgs = GetMsgListWithDb(config.Bot_Type_Qwen, userId, QwenMessage{
 		Role:    QwenChatUser,
 		Content: message,
+		Images:  images,
 	}, chat.toDbMsg, chat.toChatMsg)
 
-	qwenReq := QwenRequest{
-		Model: chat.getModel(userId),
-		Input: Input{Messages: msgs},
+	var tools []openai.Tool
+	if config.IsToolsEnabled() {
+		tools = toOpenAITools(GetTools())
 	}
-	// 如果设置了环境变量且合法，则增加maxTokens参数，否则不设置
-	if chat.maxTokens > 0 {
-		qwenReq.Parameters.MaxTokens = chat.maxTokens // 参数名称参考：https://help.aliyun.com/zh/dashscope/developer-reference/api-details
+	result, err := chat.complete(userId, chat.getModel(userId), msgs, tools)
+	if err != nil {
+		return err.Error()
 	}
-	qwenReq.Parameters.TopP = 0.8; // 通义千问要求top_p ∈ (0,1)
-	qwenReq.Parameters.RepetitionPenalty = 1.1; // 用于控制模型生成时的重复度，需要大于0。提高repetition_penalty时可以降低模型生成的重复度。1.0表示不做惩罚。默认为1.1。
-	qwenReq.Parameters.Temperature = 0.85; // 取值范围：[0, 2)，系统默认值0.85。不建议取值为0，无意义。
+	res = result.Content
 
-	body, _ := sonic.Marshal(qwenReq)
-	req, err := http.NewRequest("POST", chat.Config.HostUrl, bytes.NewReader(body))
+	msgs = append(msgs, QwenMessage{
+		Role:    QwenChatBot,
+		Content: res,
+	})
+	SaveMsgListWithDb(config.Bot_Type_Qwen, userId, msgs, chat.toDbMsg)
+	return
+}
+
+func (chat *QwenChat) Complete(userId, system, prompt string) (string, error) {
+	result, err := chat.complete(userId, chat.getModel(userId), []QwenMessage{
+		{Role: QwenChatSystem, Content: system},
+		{Role: QwenChatUser, Content: prompt},
+	}, nil)
 	if err != nil {
-		res = fmt.Sprintf("NewRequest failed,err:%v", err.Error())
-		return
+		return "", err
+	}
+	return result.Content, nil
+}
+
+// complete 请求模型，模型要求调用工具时执行工具并把结果交回模型，直到得到最终回复
+func (chat *QwenChat) complete(userId, model string, msgs []QwenMessage, tools []openai.Tool) (*QwenResult, error) {
+	var wireMsgs []QwenWireMessage
+	if chat.Config.Mode == config.Qwen_Mode_OpenAI {
+		wireMsgs = toQwenOpenAIMessages(msgs, isQwenVLModel(model))
+	} else {
+		wireMsgs = toQwenDashscopeMessages(msgs, isQwenVLModel(model))
+	}
+	for round := 0; ; round++ {
+		if round == Max_Tool_Rounds {
+			tools = nil
+		}
+		result, err := chat.send(model, wireMsgs, tools, nil)
+		if err != nil || len(result.ToolCalls) == 0 || len(tools) == 0 {
+			return result, err
+		}
+		wireMsgs = append(wireMsgs, QwenWireMessage{Role: QwenChatBot, Content: result.Content, ToolCalls: result.ToolCalls})
+		for _, call := range result.ToolCalls {
+			wireMsgs = append(wireMsgs, QwenWireMessage{
+				Role:       QwenChatTool,
+				Content:    CallToolWithJson(userId, call.Function.Name, call.Function.Arguments),
+				ToolCallId: call.ID,
+				Name:       call.Function.Name,
+			})
+		}
+	}
+}
+
+// send 按配置的接口模式发送请求并解析回复
+func (chat *QwenChat) send(model string, msgs []QwenWireMessage, tools []openai.Tool, format *QwenResponseFormat) (*QwenResult, error) {
+	multimodal := isQwenVLModel(model)
+	url := chat.Config.HostUrl
+	var body []byte
+	if chat.Config.Mode == config.Qwen_Mode_OpenAI {
+		qwenReq := QwenOpenAIRequest{
+			Model:          model,
+			Messages:       msgs,
+			TopP:           0.8,
+			Temperature:    0.85,
+			EnableSearch:   chat.Config.EnableSearch,
+			Tools:          tools,
+			ResponseFormat: format,
+		}
+		if chat.maxTokens > 0 {
+			qwenReq.MaxTokens = chat.maxTokens
+		}
+		body, _ = sonic.Marshal(qwenReq)
+	} else {
+		qwenReq := QwenRequest{
+			Model: model,
+			Input: Input{Messages: msgs},
+		}
+		// 如果设置了环境变量且合法，则增加maxTokens参数，否则不设置
+		if chat.maxTokens > 0 {
+			qwenReq.Parameters.MaxTokens = chat.maxTokens // 参数名称参考：https://help.aliyun.com/zh/dashscope/developer-reference/api-details
+		}
+		qwenReq.Parameters.TopP = 0.8              // 通义千问要求top_p ∈ (0,1)
+		qwenReq.Parameters.RepetitionPenalty = 1.1 // 用于控制模型生成时的重复度，需要大于0。提高repetition_penalty时可以降低模型生成的重复度。1.0表示不做惩罚。默认为1.1。
+		qwenReq.Parameters.Temperature = 0.85      // 取值范围：[0, 2)，系统默认值0.85。不建议取值为0，无意义。
+		qwenReq.Parameters.EnableSearch = chat.Config.EnableSearch
+		qwenReq.Parameters.ResultFormat = chat.Config.ResultFormat
+		if len(tools) > 0 {
+			qwenReq.Parameters.Tools = tools
+			qwenReq.Parameters.ResultFormat = config.Qwen_Result_Format_Message
+		}
+		if format != nil {
+			qwenReq.Parameters.ResponseFormat = format
+			qwenReq.Parameters.ResultFormat = config.Qwen_Result_Format_Message
+		}
+		if multimodal {
+			// 多模态模型只支持message格式，且使用单独的接口
+			qwenReq.Parameters.ResultFormat = config.Qwen_Result_Format_Message
+			url = strings.Replace(url, "text-generation", "multimodal-generation", 1)
+		}
+		body, _ = sonic.Marshal(qwenReq)
+	}
+
+	req, err := http.NewRequest("POST", url, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("NewRequest failed,err:%v", err.Error())
 	}
 	// 设置请求头
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Authorization", "Bearer "+chat.Config.ApiKey)
 	client := http.Client{}
 	// 发送请求
-	var resp *http.Response
-	resp, err = client.Do(req)
+	resp, err := client.Do(req)
 	if err != nil {
-		res = fmt.Sprintf("client.Do failed,err:%v", err.Error())
+		return nil, fmt.Errorf("client.Do failed,err:%v", err.Error())
 	}
 	defer resp.Body.Close()
 
-	var rpnBody []byte
-	rpnBody, err = io.ReadAll(resp.Body)
+	rpnBody, err := io.ReadAll(resp.Body)
 	if err != nil {
-		res = fmt.Sprintf("read http response failed,error=%v", err.Error())
-		return
+		return nil, fmt.Errorf("read http response failed,error=%v", err.Error())
 	}
 	if resp.StatusCode != http.StatusOK {
-		res = string(rpnBody)
-		return
+		return nil, errors.New(string(rpnBody))
 	}
 
 	// 读取响应
 	var qwenRpn QwenResponse
 	err = sonic.Unmarshal(rpnBody, &qwenRpn)
 	if err != nil {
-		res = fmt.Sprintf("Unmarshal response body failed,err:%v", err.Error())
-		return
+		return nil, fmt.Errorf("Unmarshal response body failed,err:%v", err.Error())
+	}
+	return qwenRpn.result()
+}
+
+func (rpn *QwenResponse) result() (*QwenResult, error) {
+	if rpn.Error != nil {
+		return nil, fmt.Errorf("%s: %s", rpn.Error.Code, rpn.Error.Message)
+	}
+	if rpn.Code != "" {
+		return nil, fmt.Errorf("%s: %s", rpn.Code, rpn.Message)
+	}
+	result := &QwenResult{Usage: rpn.Usage}
+	choices := rpn.Choices
+	if len(choices) == 0 {
+		choices = rpn.Output.Choices
 	}
+	if len(choices) > 0 {
+		result.Content = qwenContentText(choices[0].Message.Content)
+		result.FinishReason = choices[0].FinishReason
+		result.ToolCalls = choices[0].Message.ToolCalls
+	} else {
+		result.Content = rpn.Output.Text
+		result.FinishReason = rpn.Output.FinishReason
+	}
+	return result, nil
+}
 
-	res = qwenRpn.Output.Text
+// qwenContentText 回复内容可能为字符串，也可能是多模态模型返回的[{"text": "..."}]
+func qwenContentText(content any) string {
+	switch c := content.(type) {
+	case string:
+		return c
+	case []any:
+		var sb strings.Builder
+		for _, item := range c {
+			if part, ok := item.(map[string]any); ok {
+				if text, ok := part["text"].(string); ok {
+					sb.WriteString(text)
+				}
+			}
+		}
+		return sb.String()
+	}
+	return ""
+}
 
-	msgs = append(msgs, QwenMessage{
-		Role:    QwenChatBot,
-		Content: res,
-	})
-	SaveMsgListWithDb(config.Bot_Type_Qwen, userId, msgs, chat.toDbMsg)
-	return
+func toQwenDashscopeMessages(msgs []QwenMessage, multimodal bool) []QwenWireMessage {
+	list := make([]QwenWireMessage, 0, len(msgs))
+	for _, msg := range msgs {
+		if !multimodal {
+			list = append(list, QwenWireMessage{Role: msg.Role, Content: msg.Content})
+			continue
+		}
+		contents := make([]QwenContent, 0, len(msg.Images)+1)
+		for _, image := range msg.Images {
+			contents = append(contents, QwenContent{Image: image})
+		}
+		contents = append(contents, QwenContent{Text: msg.Content})
+		list = append(list, QwenWireMessage{Role: msg.Role, Content: contents})
+	}
+	return list
+}
+
+func toQwenOpenAIMessages(msgs []QwenMessage, multimodal bool) []QwenWireMessage {
+	list := make([]QwenWireMessage, 0, len(msgs))
+	for _, msg := range msgs {
+		if !multimodal || len(msg.Images) == 0 {
+			list = append(list, QwenWireMessage{Role: msg.Role, Content: msg.Content})
+			continue
+		}
+		contents := make([]QwenOpenAIContent, 0, len(msg.Images)+1)
+		for _, image := range msg.Images {
+			contents = append(contents, QwenOpenAIContent{Type: "image_url", ImageUrl: &QwenImageUrl{Url: image}})
+		}
+		contents = append(contents, QwenOpenAIContent{Type: "text", Text: msg.Content})
+		list = append(list, QwenWireMessage{Role: msg.Role, Content: contents})
+	}
+	return list
 }
 
 func (s *QwenChat) toDbMsg(msg QwenMessage) db.Msg {
-	return db.Msg{
+	dbMsg := db.Msg{
 		Role: msg.Role,
 		Msg:  msg.Content,
 	}
+	if len(msg.Images) > 0 {
+		for _, image := range msg.Images {
+			dbMsg.Parts = append(dbMsg.Parts, db.MsgPart{Type: db.MSG_PART_FILE, Uri: image})
+		}
+		dbMsg.Parts = append(dbMsg.Parts, db.MsgPart{Type: db.MSG_PART_TEXT, Text: msg.Content})
+	}
+	return dbMsg
 }
 
 func (s *QwenChat) toChatMsg(msg db.Msg) QwenMessage {
-	return QwenMessage{
+	qwenMsg := QwenMessage{
 		Role:    msg.Role,
 		Content: msg.Msg,
 	}
+	for _, part := range msg.Parts {
+		if part.Type == db.MSG_PART_FILE {
+			qwenMsg.Images = append(qwenMsg.Images, part.Uri)
+		}
+	}
+	return qwenMsg
 }
@@ -1,7 +1,12 @@
 package chat
 
 import (
+	"encoding/json"
 	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/joho/godotenv"
@@ -23,3 +28,216 @@ func TestQwenChat(t *testing.T) {
 
 	fmt.Println(res)
 }
+
+func newQwenStub(t *testing.T, handler func(path string, body map[string]any) string) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer test-key" {
+			t.Errorf("unexpected Authorization header: %q", r.Header.Get("Authorization"))
+		}
+		var body map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Fatalf("decode request body: %v", err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, handler(r.URL.Path, body))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestQwenChatDashscopeText(t *testing.T) {
+	server := newQwenStub(t, func(path string, body map[string]any) string {
+		if path != "/api/v1/services/aigc/text-generation/generation" {
+			t.Errorf("unexpected path %s", path)
+		}
+		msgs := body["input"].(map[string]any)["messages"].([]any)
+		if content := msgs[len(msgs)-1].(map[string]any)["content"]; content != "你好" {
+			t.Errorf("unexpected content %v", content)
+		}
+		params := body["parameters"].(map[string]any)
+		if params["result_format"] != "text" || params["enable_search"] != true {
+			t.Errorf("unexpected parameters %v", params)
+		}
+		return `{"output":{"text":"你好呀","finish_reason":"stop"},"usage":{"input_tokens":3,"output_tokens":2,"total_tokens":5},"request_id":"1"}`
+	})
+	chat := &QwenChat{BaseChat: SimpleChat{}, Config: &config.QwenConfig{
+		HostUrl:      server.URL + "/api/v1/services/aigc/text-generation/generation",
+		ApiKey:       "test-key",
+		ModelVersion: "qwen-max",
+		Mode:         config.Qwen_Mode_Dashscope,
+		ResultFormat: config.Qwen_Result_Format_Text,
+		EnableSearch: true,
+	}}
+
+	result, err := chat.complete("qwenTestUser", "qwen-max", []QwenMessage{{Role: QwenChatUser, Content: "你好"}}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Content != "你好呀" || result.FinishReason != "stop" {
+		t.Errorf("unexpected result %+v", result)
+	}
+	if result.Usage.Input() != 3 || result.Usage.Output() != 2 || result.Usage.Total() != 5 {
+		t.Errorf("unexpected usage %+v", result.Usage)
+	}
+}
+
+func TestQwenChatDashscopeMessage(t *testing.T) {
+	server := newQwenStub(t, func(path string, body map[string]any) string {
+		if format := body["parameters"].(map[string]any)["result_format"]; format != "message" {
+			t.Errorf("unexpected result_format %v", format)
+		}
+		return `{"output":{"choices":[{"finish_reason":"stop","message":{"role":"assistant","content":"message格式"}}]},"usage":{"input_tokens":4,"output_tokens":3}}`
+	})
+	chat := &QwenChat{BaseChat: SimpleChat{}, Config: &config.QwenConfig{
+		HostUrl:      server.URL,
+		ApiKey:       "test-key",
+		Mode:         config.Qwen_Mode_Dashscope,
+		ResultFormat: config.Qwen_Result_Format_Message,
+	}}
+
+	result, err := chat.complete("qwenTestUser", "qwen-max", []QwenMessage{{Role: QwenChatUser, Content: "hi"}}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Content != "message格式" {
+		t.Errorf("unexpected content %q", result.Content)
+	}
+	if result.Usage.Total() != 7 {
+		t.Errorf("unexpected total tokens %d", result.Usage.Total())
+	}
+}
+
+func TestQwenChatOpenAIMode(t *testing.T) {
+	server := newQwenStub(t, func(path string, body map[string]any) string {
+		if path != "/compatible-mode/v1/chat/completions" {
+			t.Errorf("unexpected path %s", path)
+		}
+		if _, ok := body["input"]; ok {
+			t.Error("openai mode should not send input")
+		}
+		if body["enable_search"] != true {
+			t.Errorf("enable_search not set: %v", body)
+		}
+		if msgs := body["messages"].([]any); msgs[0].(map[string]any)["content"] != "hi" {
+			t.Errorf("unexpected messages %v", msgs)
+		}
+		return `{"choices":[{"finish_reason":"stop","message":{"role":"assistant","content":"兼容模式"}}],"usage":{"prompt_tokens":8,"completion_tokens":2,"total_tokens":10}}`
+	})
+	chat := &QwenChat{BaseChat: SimpleChat{}, Config: &config.QwenConfig{
+		HostUrl:      server.URL + "/compatible-mode/v1/chat/completions",
+		ApiKey:       "test-key",
+		Mode:         config.Qwen_Mode_OpenAI,
+		EnableSearch: true,
+	}}
+
+	result, err := chat.complete("qwenTestUser", "qwen-plus", []QwenMessage{{Role: QwenChatUser, Content: "hi"}}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Content != "兼容模式" {
+		t.Errorf("unexpected content %q", result.Content)
+	}
+	if result.Usage.Input() != 8 || result.Usage.Output() != 2 || result.Usage.Total() != 10 {
+		t.Errorf("unexpected usage %+v", result.Usage)
+	}
+}
+
+func TestQwenChatVLImage(t *testing.T) {
+	server := newQwenStub(t, func(path string, body map[string]any) string {
+		if path != "/api/v1/services/aigc/multimodal-generation/generation" {
+			t.Errorf("unexpected path %s", path)
+		}
+		msgs := body["input"].(map[string]any)["messages"].([]any)
+		content := msgs[len(msgs)-1].(map[string]any)["content"].([]any)
+		if content[0].(map[string]any)["image"] != "https://example.com/cat.png" || content[1].(map[string]any)["text"] != QwenImagePrompt {
+			t.Errorf("unexpected multimodal content %v", content)
+		}
+		return `{"output":{"choices":[{"finish_reason":"stop","message":{"role":"assistant","content":[{"text":"一只猫"}]}}]},"usage":{"input_tokens":1200,"output_tokens":3,"image_tokens":1100}}`
+	})
+	chat := &QwenChat{BaseChat: SimpleChat{}, Config: &config.QwenConfig{
+		HostUrl:      server.URL + "/api/v1/services/aigc/text-generation/generation",
+		ApiKey:       "test-key",
+		ModelVersion: "qwen-vl-plus",
+		Mode:         config.Qwen_Mode_Dashscope,
+		ResultFormat: config.Qwen_Result_Format_Text,
+	}}
+
+	res := chat.chatWithImages("qwenVLTestUser", QwenImagePrompt, []string{"https://example.com/cat.png"})
+	if res != "一只猫" {
+		t.Errorf("unexpected reply %q", res)
+	}
+}
+
+func TestQwenChatOpenAIModeVLImage(t *testing.T) {
+	server := newQwenStub(t, func(path string, body map[string]any) string {
+		msgs := body["messages"].([]any)
+		content := msgs[len(msgs)-1].(map[string]any)["content"].([]any)
+		image := content[0].(map[string]any)
+		if image["type"] != "image_url" || image["image_url"].(map[string]any)["url"] != "https://example.com/cat.png" {
+			t.Errorf("unexpected image content %v", image)
+		}
+		return `{"choices":[{"finish_reason":"stop","message":{"role":"assistant","content":"一只猫"}}]}`
+	})
+	chat := &QwenChat{BaseChat: SimpleChat{}, Config: &config.QwenConfig{
+		HostUrl: server.URL,
+		ApiKey:  "test-key",
+		Mode:    config.Qwen_Mode_OpenAI,
+	}}
+
+	result, err := chat.complete("qwenTestUser", "qwen-vl-max", []QwenMessage{{Role: QwenChatUser, Content: QwenImagePrompt, Images: []string{"https://example.com/cat.png"}}}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Content != "一只猫" {
+		t.Errorf("unexpected content %q", result.Content)
+	}
+}
+
+func TestQwenChatError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		io.WriteString(w, `{"code":"InvalidParameter","message":"bad model"}`)
+	}))
+	defer server.Close()
+	chat := &QwenChat{BaseChat: SimpleChat{}, Config: &config.QwenConfig{HostUrl: server.URL, ApiKey: "test-key"}}
+
+	if _, err := chat.complete("qwenTestUser", "qwen-max", []QwenMessage{{Role: QwenChatUser, Content: "hi"}}, nil); err == nil || !strings.Contains(err.Error(), "bad model") {
+		t.Errorf("expected error with body, got %v", err)
+	}
+}
+
+func TestQwenChatToolLoop(t *testing.T) {
+	registerWeatherTool(t)
+	round := 0
+	server := newQwenStub(t, func(path string, body map[string]any) string {
+		round++
+		params := body["parameters"].(map[string]any)
+		if params["result_format"] != "message" || len(params["tools"].([]any)) == 0 {
+			t.Errorf("tools need message result format, got %v", params)
+		}
+		if round == 1 {
+			return `{"output":{"choices":[{"finish_reason":"tool_calls","message":{"role":"assistant","content":"","tool_calls":[{"id":"","type":"function","function":{"name":"test_weather","arguments":"{\"city\":\"上海\"}"}}]}}]}}`
+		}
+		msgs := body["input"].(map[string]any)["messages"].([]any)
+		last := msgs[len(msgs)-1].(map[string]any)
+		if last["role"] != QwenChatTool || last["content"] != "qwenToolUser:上海晴" || last["name"] != "test_weather" {
+			t.Errorf("unexpected tool message %v", last)
+		}
+		return `{"output":{"choices":[{"finish_reason":"stop","message":{"role":"assistant","content":"上海晴天"}}]}}`
+	})
+	chat := &QwenChat{BaseChat: SimpleChat{}, Config: &config.QwenConfig{
+		HostUrl:      server.URL,
+		ApiKey:       "test-key",
+		Mode:         config.Qwen_Mode_Dashscope,
+		ResultFormat: config.Qwen_Result_Format_Text,
+	}}
+
+	result, err := chat.complete("qwenToolUser", "qwen-max", []QwenMessage{{Role: QwenChatUser, Content: "上海天气"}}, toOpenAITools(GetTools()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Content != "上海晴天" || round != 2 {
+		t.Errorf("unexpected result %+v after %d rounds", result, round)
+	}
+}
@@ -0,0 +1,98 @@
+package chat
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pwh-pwh/aiwechat-vercel/config"
+	"github.com/pwh-pwh/aiwechat-vercel/remind"
+)
+
+const (
+	// 待办和提醒发送失败后的重试间隔和最长重试时间
+	Remind_Retry_Delay  = time.Hour
+	Remind_Retry_Window = 48 * time.Hour
+)
+
+func init() {
+	commands := []*Command{
+		{Name: config.Wx_Remind, Aliases: []string{"提醒"}, Args: []CommandArg{{Name: "时间 内容", Type: Arg_Text}},
+			Usage: "定时提醒，如 /remind 明天9点 交房租、/remind 30分钟后 关火，支持重复提醒：每天8点、工作日9点、每周一三五、每月1号",
+			Handler: func(args *CommandArgs, userId string) string {
+				return AddReminder(args.String("时间 内容"), userId)
+			}},
+		{Name: config.Wx_Remind_List, Aliases: []string{"提醒列表"}, Usage: "查看提醒列表",
+			Handler: func(args *CommandArgs, userId string) string { return GetReminderList(userId) }},
+		{Name: config.Wx_Remind_Del, Args: []CommandArg{{Name: "编号", Type: Arg_Int}}, Usage: "删除提醒",
+			Handler: func(args *CommandArgs, userId string) string {
+				return DelReminder(args.Int("编号"), userId)
+			}},
+	}
+	for _, cmd := range commands {
+		RegisterCommand(cmd)
+	}
+}
+
+func AddReminder(text, userId string) string {
+	r, err := remind.Add(userId, text)
+	if err != nil {
+		return err.Error()
+	}
+	return "已设置提醒 " + r.String()
+}
+
+func GetReminderList(userId string) string {
+	reminders, err := remind.List(userId)
+	if err != nil {
+		return err.Error()
+	}
+	if len(reminders) == 0 {
+		return "没有提醒"
+	}
+	lines := make([]string, 0, len(reminders))
+	for _, r := range reminders {
+		lines = append(lines, r.String())
+	}
+	return strings.Join(lines, "\n")
+}
+
+func DelReminder(id int, userId string) string {
+	r, err := remind.Delete(userId, id)
+	if err != nil {
+		return err.Error()
+	}
+	return fmt.Sprintf("已删除 #%d %s", r.Id, r.Content)
+}
+
+// SendReminders 发送到时间的提醒，返回发送成功的数量，由定时任务调用
+func SendReminders() (int, error) {
+	now := remind.Now()
+	reminders, err := remind.Due(now)
+	if err != nil {
+		return 0, err
+	}
+	sent := 0
+	for _, r := range reminders {
+		if err := SendText(r.UserId, "⏰ 提醒："+r.Content); err != nil {
+			fmt.Printf("reminder to %s error: %v\n", r.UserId, err)
+			if shouldRetryRemind(r.Time, now) {
+				if err := remind.Retry(r, now.Add(Remind_Retry_Delay)); err != nil {
+					fmt.Println("retry reminder error:", err)
+				}
+				continue
+			}
+		} else {
+			sent++
+		}
+		if err := remind.Fired(r); err != nil {
+			fmt.Println("update reminder error:", err)
+		}
+	}
+	return sent, nil
+}
+
+// shouldRetryRemind 超过48小时没有互动的用户无法接收客服消息，发送失败时在48小时内定时重试，用户再次互动后可以收到
+func shouldRetryRemind(due int64, now time.Time) bool {
+	return now.Sub(time.Unix(due, 0)) < Remind_Retry_Window
+}
@@ -0,0 +1,46 @@
+package chat
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pwh-pwh/aiwechat-vercel/config"
+	"github.com/pwh-pwh/aiwechat-vercel/remind"
+)
+
+func TestRemindCommands(t *testing.T) {
+	messenger := useFakeMessenger(t)
+	now := time.Date(2024, 5, 15, 10, 0, 0, 0, config.GetLocation())
+	remind.Now = func() time.Time { return now }
+	t.Cleanup(func() {
+		remind.Now = config.Now
+		for _, id := range []int{1, 2} {
+			remind.Delete("remind-chat", id)
+		}
+	})
+
+	if r, _ := RunCommand("remind-chat", "/remind 明天9点 交房租"); r != "已设置提醒 #1 交房租 (05-16 09:00)" {
+		t.Errorf("/remind = %q", r)
+	}
+	if r, _ := RunCommand("remind-chat", "提醒 每月1号 还信用卡"); !strings.Contains(r, "每月1号 09:00，下次 06-01 09:00") {
+		t.Errorf("/remind = %q", r)
+	}
+	if r, _ := RunCommand("remind-chat", "/rl"); !strings.HasPrefix(r, "#1 交房租") || !strings.Contains(r, "#2 还信用卡") {
+		t.Errorf("/rl = %q", r)
+	}
+
+	now = now.Add(24 * time.Hour)
+	if sent, err := SendReminders(); sent != 1 || err != nil {
+		t.Errorf("SendReminders = %d, %v", sent, err)
+	}
+	if r := messenger.sent["remind-chat"]; r != "⏰ 提醒：交房租" {
+		t.Errorf("reminder = %q", r)
+	}
+	if r, _ := RunCommand("remind-chat", "/rl"); strings.Contains(r, "交房租") {
+		t.Errorf("one-off reminder should be removed after sent: %q", r)
+	}
+	if r, _ := RunCommand("remind-chat", "/rd 2"); r != "已删除 #2 还信用卡" {
+		t.Errorf("/rd = %q", r)
+	}
+}
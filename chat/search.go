@@ -0,0 +1,127 @@
+package chat
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/pwh-pwh/aiwechat-vercel/client"
+	"github.com/pwh-pwh/aiwechat-vercel/config"
+	"github.com/pwh-pwh/aiwechat-vercel/db"
+	"github.com/pwh-pwh/aiwechat-vercel/reply"
+	"github.com/silenceper/wechat/v2/officialaccount/message"
+)
+
+const searchSystemPrompt = "你是一个联网搜索助手。请只根据给出的搜索结果回答用户的问题，" +
+	"引用某条结果时在句末用[序号]标注来源，例如[1]、[2]。搜索结果不足以回答时请直接说明，不要编造。"
+
+// SearchBackend 不为空时替代配置中的搜索后端，测试时可以替换为本地实现
+var SearchBackend client.Searcher
+
+func getSearcher() (client.Searcher, error) {
+	if SearchBackend != nil {
+		return SearchBackend, nil
+	}
+	return client.NewSearcher(config.GetSearchProvider(), config.GetSearchUrl(), config.GetSearchApiKey())
+}
+
+// Search /search 搜索后交给当前bot回答
+func Search(param, userId string) string {
+	if param == "" {
+		return "请输入搜索内容，例如：/search 今天的新闻"
+	}
+	return WithTimeChat(userId, config.Wx_Command_Search+" "+param, func(userId, msg string) string {
+		return searchReply(userId, param)
+	})
+}
+
+// AutoSearch /autosearch on|off 开启后普通消息都会先联网搜索再回答
+func AutoSearch(param, userId string) string {
+	switch strings.ToLower(param) {
+	case "on", "开启":
+		if _, err := getSearcher(); err != nil {
+			return err.Error()
+		}
+		if err := db.SetAutoSearch(userId, true); err != nil {
+			return "开启自动搜索失败"
+		}
+		return "已开启自动搜索，发送的消息会先联网搜索再回答"
+	case "off", "关闭":
+		db.SetAutoSearch(userId, false)
+		return "已关闭自动搜索"
+	case "":
+		if db.IsAutoSearch(userId) {
+			return "自动搜索已开启，发送 /autosearch off 关闭"
+		}
+		return "自动搜索未开启，发送 /autosearch on 开启"
+	default:
+		return "参数错误，请使用 /autosearch on 或 /autosearch off"
+	}
+}
+
+// WebCard /web 只搜索不调用模型，把第一条结果作为图文卡片回复，点击打开原网页
+func WebCard(args *CommandArgs, userId string) *message.Reply {
+	searcher, err := getSearcher()
+	if err != nil {
+		return reply.Text(err.Error())
+	}
+	results, err := searcher.Search(args.String("内容"), reply.MaxNewsArticles)
+	if err != nil {
+		return reply.Text("搜索失败: " + err.Error())
+	}
+	if len(results) == 0 {
+		return reply.Text("没有搜索到相关结果")
+	}
+	rpn, err := reply.News().Article(results[0].Title, results[0].Snippet, "", results[0].Url).Build()
+	if err != nil {
+		return reply.Text(err.Error())
+	}
+	return rpn
+}
+
+func searchReply(userId, query string) string {
+	r, err := SearchAnswer(userId, query, Complete)
+	if err != nil {
+		return err.Error()
+	}
+	return r
+}
+
+// SearchAnswer 搜索query，把结果注入提示词后调用complete回答，并在回答末尾附上带序号的来源列表
+func SearchAnswer(userId, query string, complete func(userID, system, prompt string) (string, error)) (string, error) {
+	searcher, err := getSearcher()
+	if err != nil {
+		return "", err
+	}
+	results, err := searcher.Search(query, config.GetSearchCount())
+	if err != nil {
+		return "", fmt.Errorf("搜索失败: %w", err)
+	}
+	if len(results) == 0 {
+		return "", errors.New("没有搜索到相关结果")
+	}
+	answer, err := complete(userId, searchSystemPrompt, buildSearchPrompt(query, results))
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(answer) + "\n\n参考来源：\n" + formatSearchSources(results), nil
+}
+
+func buildSearchPrompt(query string, results []client.SearchResult) string {
+	var sb strings.Builder
+	sb.WriteString("搜索结果：\n")
+	for i, result := range results {
+		sb.WriteString(fmt.Sprintf("[%d] %s\n来源：%s\n%s\n\n", i+1, result.Title, result.Url, result.Snippet))
+	}
+	sb.WriteString("问题：")
+	sb.WriteString(query)
+	return sb.String()
+}
+
+func formatSearchSources(results []client.SearchResult) string {
+	list := make([]string, 0, len(results))
+	for i, result := range results {
+		list = append(list, fmt.Sprintf("[%d] %s %s", i+1, result.Title, result.Url))
+	}
+	return strings.Join(list, "\n")
+}
@@ -0,0 +1,118 @@
+package chat
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/pwh-pwh/aiwechat-vercel/client"
+	"github.com/silenceper/wechat/v2/officialaccount/message"
+)
+
+type fakeSearcher struct {
+	results []client.SearchResult
+	err     error
+	query   string
+	count   int
+}
+
+func (f *fakeSearcher) Search(query string, count int) ([]client.SearchResult, error) {
+	f.query, f.count = query, count
+	return f.results, f.err
+}
+
+func useFakeSearcher(t *testing.T, searcher *fakeSearcher) {
+	t.Helper()
+	SearchBackend = searcher
+	t.Cleanup(func() { SearchBackend = nil })
+}
+
+func TestSearchAnswer(t *testing.T) {
+	searcher := &fakeSearcher{results: []client.SearchResult{
+		{Title: "Go 1.22 发布", Url: "https://go.dev/blog/go1.22", Snippet: "Go 1.22 带来了循环变量语义变化"},
+		{Title: "Go 发布历史", Url: "https://go.dev/doc/devel/release", Snippet: "各版本发布时间"},
+	}}
+	useFakeSearcher(t, searcher)
+
+	var system, prompt string
+	answer, err := SearchAnswer("u1", "go 1.22 有什么变化", func(userID, s, p string) (string, error) {
+		system, prompt = s, p
+		return "循环变量每次迭代都会重新创建[1]。\n", nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if searcher.query != "go 1.22 有什么变化" || searcher.count != 5 {
+		t.Errorf("search called with %q %d", searcher.query, searcher.count)
+	}
+	if !strings.Contains(system, "[序号]") {
+		t.Errorf("system prompt should ask for citations: %s", system)
+	}
+	for _, want := range []string{"[1] Go 1.22 发布\n来源：https://go.dev/blog/go1.22", "[2] Go 发布历史", "问题：go 1.22 有什么变化"} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("prompt missing %q:\n%s", want, prompt)
+		}
+	}
+	want := "循环变量每次迭代都会重新创建[1]。\n\n参考来源：\n[1] Go 1.22 发布 https://go.dev/blog/go1.22\n[2] Go 发布历史 https://go.dev/doc/devel/release"
+	if answer != want {
+		t.Errorf("answer = %q, want %q", answer, want)
+	}
+}
+
+func TestSearchAnswerError(t *testing.T) {
+	complete := func(userID, system, prompt string) (string, error) {
+		t.Fatal("complete should not be called")
+		return "", nil
+	}
+	useFakeSearcher(t, &fakeSearcher{err: errors.New("timeout")})
+	if _, err := SearchAnswer("u1", "q", complete); err == nil || !strings.Contains(err.Error(), "timeout") {
+		t.Errorf("unexpected error %v", err)
+	}
+	useFakeSearcher(t, &fakeSearcher{})
+	if _, err := SearchAnswer("u1", "q", complete); err == nil {
+		t.Error("expected error for empty results")
+	}
+}
+
+func TestAutoSearchToggle(t *testing.T) {
+	useFakeSearcher(t, &fakeSearcher{})
+	if r := AutoSearch("on", "u-auto"); !strings.Contains(r, "已开启") {
+		t.Errorf("on: %s", r)
+	}
+	if r := AutoSearch("", "u-auto"); !strings.Contains(r, "已开启") {
+		t.Errorf("status: %s", r)
+	}
+	if r := AutoSearch("off", "u-auto"); r != "已关闭自动搜索" {
+		t.Errorf("off: %s", r)
+	}
+	if r := AutoSearch("", "u-auto"); !strings.Contains(r, "未开启") {
+		t.Errorf("status after off: %s", r)
+	}
+	if r, _ := RunCommand("u-auto", "/autosearch 开启"); !strings.Contains(r, "已开启") {
+		t.Errorf("command on: %s", r)
+	}
+	if r, _ := RunCommand("u-auto", "/autosearch 关闭"); r != "已关闭自动搜索" {
+		t.Errorf("command off: %s", r)
+	}
+}
+
+func TestWebCard(t *testing.T) {
+	searcher := &fakeSearcher{results: []client.SearchResult{{Title: "Go 1.22 发布", Url: "https://go.dev/blog/go1.22", Snippet: "循环变量语义变化"}}}
+	useFakeSearcher(t, searcher)
+	r, ok := DoReplyAction("u1", "网页 go 1.22")
+	if !ok || r.MsgType != message.MsgTypeNews {
+		t.Fatalf("reply = %+v, %v", r, ok)
+	}
+	news := r.MsgData.(*message.News)
+	if news.ArticleCount != 1 || news.Articles[0].Title != "Go 1.22 发布" || news.Articles[0].URL != "https://go.dev/blog/go1.22" {
+		t.Errorf("news = %+v", news.Articles[0])
+	}
+	if searcher.query != "go 1.22" || searcher.count != 1 {
+		t.Errorf("search %q %d", searcher.query, searcher.count)
+	}
+
+	useFakeSearcher(t, &fakeSearcher{})
+	if r, _ := DoReplyAction("u1", "/web go"); r.MsgType != message.MsgTypeText {
+		t.Errorf("empty results reply = %+v", r)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"crypto/hmac"
 	"crypto/sha256"
 	"encoding/base64"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -58,18 +59,6 @@ func (chat *SparkChat) Chat(userId, message string) (res string) {
 }
 
 func (chat *SparkChat) chat(userId string, message string) (res string) {
-	dialer := websocket.Dialer{
-		HandshakeTimeout: 5 * time.Second,
-	}
-	//握手并建立websocket 连接
-	conn, resp, err := dialer.Dial(assembleAuthUrl1(chat.Config.HostUrl, chat.Config.ApiKey, chat.Config.ApiSecret), nil)
-	if err != nil {
-		res = readResp(resp) + err.Error()
-		return
-	} else if resp.StatusCode != 101 {
-		res = readResp(resp)
-		return
-	}
 	/*var msgs = []SparkMessage{
 		{
 			Role:    "user",
@@ -89,6 +78,38 @@ func (chat *SparkChat) chat(userId string, message string) (res string) {
 		Content: message,
 	}, chat.toDbMsg, chat.toChatMsg)
 
+	res, err := chat.send(msgs)
+	if err != nil {
+		return err.Error()
+	}
+	msgs = append(msgs, SparkMessage{
+		Role:    "assistant",
+		Content: res,
+	})
+	SaveMsgListWithDb(config.Bot_Type_Spark, userId, msgs, chat.toDbMsg)
+	return
+}
+
+func (chat *SparkChat) Complete(userId, system, prompt string) (string, error) {
+	return chat.send([]SparkMessage{
+		{Role: "system", Content: system},
+		{Role: "user", Content: prompt},
+	})
+}
+
+// send 建立websocket连接发送消息，拼接流式返回的全部内容
+func (chat *SparkChat) send(msgs []SparkMessage) (res string, err error) {
+	dialer := websocket.Dialer{
+		HandshakeTimeout: 5 * time.Second,
+	}
+	//握手并建立websocket 连接
+	conn, resp, err := dialer.Dial(assembleAuthUrl1(chat.Config.HostUrl, chat.Config.ApiKey, chat.Config.ApiSecret), nil)
+	if err != nil {
+		return "", errors.New(readResp(resp) + err.Error())
+	} else if resp.StatusCode != 101 {
+		return "", errors.New(readResp(resp))
+	}
+
 	go func() {
 		data := generateRequestBody(chat.Config.AppId, chat.Config.SparkDomainVersion, msgs, chat.maxTokens)
 		conn.WriteJSON(data)
@@ -108,11 +129,10 @@ func (chat *SparkChat) chat(userId string, message string) (res string) {
 		err = sonic.Unmarshal(msg, &rpn)
 		if err != nil {
 			fmt.Println("Error parsing JSON:", err)
-			return
+			return res, nil
 		}
 		if rpn.Header.IsFailed() {
-			res = rpn.Header.ToString()
-			return
+			return "", errors.New(rpn.Header.ToString())
 		}
 		//解析数据
 		choices := rpn.Payload["choices"].(map[string]interface{})
@@ -134,12 +154,7 @@ func (chat *SparkChat) chat(userId string, message string) (res string) {
 		}
 
 	}
-	msgs = append(msgs, SparkMessage{
-		Role:    "assistant",
-		Content: res,
-	})
-	SaveMsgListWithDb(config.Bot_Type_Spark, userId, msgs, chat.toDbMsg)
-	return
+	return res, nil
 }
 
 func (s *SparkChat) toDbMsg(msg SparkMessage) db.Msg {
@@ -0,0 +1,77 @@
+package chat
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/bytedance/sonic"
+	"github.com/pwh-pwh/aiwechat-vercel/client"
+	"github.com/pwh-pwh/aiwechat-vercel/db"
+)
+
+const summarySystemPrompt = "你是一个阅读助手，请根据给出的网页内容用中文回答。" +
+	"没有额外要求时，先用一句话概括主旨，再分条列出3到5个要点，总字数控制在300字以内。"
+
+var urlRegex = regexp.MustCompile(`https?://[^\s<>"'，。！？、；）》]+`)
+
+// ExtractUrl 返回文本中的第一个链接以及去掉链接后的其余文字
+func ExtractUrl(text string) (pageUrl string, rest string) {
+	loc := urlRegex.FindStringIndex(text)
+	if loc == nil {
+		return "", text
+	}
+	return text[loc[0]:loc[1]], strings.TrimSpace(strings.TrimSpace(text[:loc[0]]) + " " + strings.TrimSpace(text[loc[1]:]))
+}
+
+// SummarizeUrl 抓取链接内容并由当前bot总结，question不为空时按question回答
+func SummarizeUrl(userId, pageUrl, question string) string {
+	return WithTimeChat(userId, pageUrl+question, func(userId, msg string) string {
+		r, err := SummarizeWebPage(userId, pageUrl, question, Complete)
+		if err != nil {
+			return err.Error()
+		}
+		return r
+	})
+}
+
+func SummarizeWebPage(userId, pageUrl, question string, complete func(userID, system, prompt string) (string, error)) (string, error) {
+	page, err := getWebPage(pageUrl)
+	if err != nil {
+		return "", fmt.Errorf("读取链接失败: %w", err)
+	}
+	if question == "" {
+		question = "请总结这篇文章"
+	}
+	prompt := fmt.Sprintf("标题：%s\n", page.Title)
+	if page.Author != "" {
+		prompt += fmt.Sprintf("作者：%s\n", page.Author)
+	}
+	prompt += fmt.Sprintf("正文：\n%s\n\n%s", page.Content, question)
+	summary, err := complete(userId, summarySystemPrompt, prompt)
+	if err != nil {
+		return "", err
+	}
+	if page.Title == "" {
+		return strings.TrimSpace(summary), nil
+	}
+	return fmt.Sprintf("《%s》\n%s", page.Title, strings.TrimSpace(summary)), nil
+}
+
+// getWebPage 优先读取缓存，没有缓存时抓取网页并写入缓存
+func getWebPage(pageUrl string) (*client.WebPage, error) {
+	if cached, err := db.GetWebPage(pageUrl); err == nil && cached != "" {
+		var page client.WebPage
+		if err = sonic.UnmarshalString(cached, &page); err == nil {
+			return &page, nil
+		}
+	}
+	page, err := client.FetchWebPage(pageUrl)
+	if err != nil {
+		return nil, err
+	}
+	if data, err := sonic.MarshalString(page); err == nil {
+		db.SetWebPage(pageUrl, data)
+	}
+	return page, nil
+}
@@ -0,0 +1,74 @@
+package chat
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/pwh-pwh/aiwechat-vercel/client"
+)
+
+func TestExtractUrl(t *testing.T) {
+	cases := []struct {
+		text, url, rest string
+	}{
+		{"https://mp.weixin.qq.com/s/abc", "https://mp.weixin.qq.com/s/abc", ""},
+		{"帮我看看 https://example.com/a?b=1，讲了什么", "https://example.com/a?b=1", "帮我看看 ，讲了什么"},
+		{"没有链接", "", "没有链接"},
+	}
+	for _, c := range cases {
+		u, rest := ExtractUrl(c.text)
+		if u != c.url || rest != c.rest {
+			t.Errorf("ExtractUrl(%q) = %q, %q", c.text, u, rest)
+		}
+	}
+}
+
+// allowLocalFetch 测试服务器监听在本机，测试期间不检查地址
+func allowLocalFetch(t *testing.T) {
+	t.Helper()
+	guarded := client.FetchHttpClient
+	client.FetchHttpClient = &http.Client{}
+	t.Cleanup(func() { client.FetchHttpClient = guarded })
+}
+
+func TestSummarizeWebPage(t *testing.T) {
+	allowLocalFetch(t)
+	hits := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(`<html><body><h1 id="activity-name">周报</h1><a id="js_name">某公众号</a><div id="js_content"><p>本周上线了新功能。</p></div></body></html>`))
+	}))
+	defer server.Close()
+
+	var prompt string
+	complete := func(userID, system, p string) (string, error) {
+		prompt = p
+		return "上线了新功能。\n", nil
+	}
+	r, err := SummarizeWebPage("u1", server.URL+"/s/1", "", complete)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r != "《周报》\n上线了新功能。" {
+		t.Errorf("reply = %q", r)
+	}
+	for _, want := range []string{"标题：周报", "作者：某公众号", "本周上线了新功能。", "请总结这篇文章"} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("prompt missing %q:\n%s", want, prompt)
+		}
+	}
+
+	// 第二次读取缓存，不再请求网页
+	if _, err = SummarizeWebPage("u1", server.URL+"/s/1", "有哪些新功能", complete); err != nil {
+		t.Fatal(err)
+	}
+	if hits != 1 {
+		t.Errorf("page fetched %d times", hits)
+	}
+	if !strings.HasSuffix(prompt, "有哪些新功能") {
+		t.Errorf("question not passed: %s", prompt)
+	}
+}
@@ -0,0 +1,122 @@
+package chat
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pwh-pwh/aiwechat-vercel/config"
+	"github.com/pwh-pwh/aiwechat-vercel/todo"
+)
+
+func init() {
+	commands := []*Command{
+		{Name: config.Wx_Todo_Add, Args: []CommandArg{{Name: "待办事项", Type: Arg_Text}},
+			Usage: "添加待办，可以带截止时间和优先级，如 /ta !! 明天下午3点 交报告，!重要 !!紧急，到期时会提醒",
+			Handler: func(args *CommandArgs, userId string) string {
+				return AddTodo(args.String("待办事项"), userId)
+			}},
+		{Name: config.Wx_Todo_List, Aliases: []string{"待办"}, Args: []CommandArg{{Name: "all", Type: Arg_String, Optional: true, Choices: []string{"all"}}},
+			Usage: "获取待办列表，all包括已完成的", Handler: func(args *CommandArgs, userId string) string {
+				return GetTodoList(userId, args.Has("all"))
+			}},
+		{Name: config.Wx_Todo_Done, Args: []CommandArg{{Name: "编号", Type: Arg_Int}}, Usage: "完成待办",
+			Handler: func(args *CommandArgs, userId string) string {
+				return DoneTodo(args.Int("编号"), userId)
+			}},
+		{Name: config.Wx_Todo_Edit, Args: []CommandArg{{Name: "编号", Type: Arg_Int}, {Name: "新内容", Type: Arg_Text}},
+			Usage: "修改待办，新内容中的时间和优先级会一并修改", Handler: func(args *CommandArgs, userId string) string {
+				item, err := todo.Edit(userId, args.Int("编号"), args.String("新内容"))
+				if err != nil {
+					return err.Error()
+				}
+				return "已修改 " + item.String()
+			}},
+		{Name: config.Wx_Todo_Del, Args: []CommandArg{{Name: "编号", Type: Arg_Int}}, Usage: "删除待办",
+			Handler: func(args *CommandArgs, userId string) string {
+				return DelTodo(args.Int("编号"), userId)
+			}},
+	}
+	for _, cmd := range commands {
+		RegisterCommand(cmd)
+	}
+}
+
+func AddTodo(text, userId string) string {
+	item, err := todo.Add(userId, text)
+	if err != nil {
+		return err.Error()
+	}
+	return "已添加 " + item.String()
+}
+
+// GetTodoList 未完成的待办，all为true时包括已完成的
+func GetTodoList(userId string, all bool) string {
+	items, err := todo.List(userId)
+	if err != nil {
+		return err.Error()
+	}
+	var lines []string
+	done := 0
+	for _, item := range items {
+		if item.Done {
+			done++
+			if !all {
+				continue
+			}
+		}
+		lines = append(lines, item.String())
+	}
+	if len(lines) == 0 {
+		if done > 0 {
+			return fmt.Sprintf("待办都已完成，共%d项，发送 /tl all 查看", done)
+		}
+		return "todolist为空"
+	}
+	if !all && done > 0 {
+		lines = append(lines, fmt.Sprintf("另有%d项已完成，发送 /tl all 查看", done))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func DoneTodo(id int, userId string) string {
+	item, err := todo.Done(userId, id)
+	if err != nil {
+		return err.Error()
+	}
+	return "已完成 " + item.String()
+}
+
+func DelTodo(id int, userId string) string {
+	item, err := todo.Delete(userId, id)
+	if err != nil {
+		return err.Error()
+	}
+	return fmt.Sprintf("已删除 #%d %s", item.Id, item.Content)
+}
+
+// SendTodoReminders 给到期的待办发送提醒，返回发送成功的数量，由定时任务调用
+func SendTodoReminders() (int, error) {
+	now := todo.Now()
+	reminders, err := todo.DueReminders(now)
+	if err != nil {
+		return 0, err
+	}
+	sent := 0
+	for _, r := range reminders {
+		if err := SendText(r.UserId, "⏰ 待办提醒："+r.Item.String()+"\n完成后发送 /tdone "+fmt.Sprint(r.Item.Id)); err != nil {
+			fmt.Printf("todo reminder to %s error: %v\n", r.UserId, err)
+			if shouldRetryRemind(r.Item.Due, now) {
+				if err := todo.Retry(r.UserId, r.Item, now.Add(Remind_Retry_Delay)); err != nil {
+					fmt.Println("retry todo reminder error:", err)
+				}
+				continue
+			}
+		} else {
+			sent++
+		}
+		if err := todo.MarkReminded(r.UserId, r.Item); err != nil {
+			fmt.Println("mark todo reminded error:", err)
+		}
+	}
+	return sent, nil
+}
@@ -0,0 +1,58 @@
+package chat
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pwh-pwh/aiwechat-vercel/config"
+	"github.com/pwh-pwh/aiwechat-vercel/todo"
+)
+
+func TestTodoCommands(t *testing.T) {
+	messenger := useFakeMessenger(t)
+	now := time.Date(2024, 5, 15, 10, 0, 0, 0, config.GetLocation())
+	todo.Now = func() time.Time { return now }
+	t.Cleanup(func() {
+		todo.Now = config.Now
+		for _, id := range []int{1, 2} {
+			todo.Delete("todo-chat", id)
+		}
+		todo.Delete("fail", 1)
+	})
+
+	if r, _ := RunCommand("todo-chat", "/ta 明天9点 交房租"); !strings.Contains(r, "#1 交房租 (截止 05-16 09:00)") {
+		t.Errorf("/ta = %q", r)
+	}
+	RunCommand("todo-chat", "/ta 买牛奶")
+	if r, _ := RunCommand("todo-chat", "/tdone 2"); !strings.HasPrefix(r, "已完成") {
+		t.Errorf("/tdone = %q", r)
+	}
+	if r, _ := RunCommand("todo-chat", "待办"); !strings.Contains(r, "交房租") || strings.Contains(r, "买牛奶") || !strings.Contains(r, "另有1项已完成") {
+		t.Errorf("/tl = %q", r)
+	}
+
+	now = now.Add(24 * time.Hour)
+	if sent, err := SendTodoReminders(); sent != 1 || err != nil {
+		t.Errorf("SendTodoReminders = %d, %v", sent, err)
+	}
+	if r := messenger.sent["todo-chat"]; !strings.Contains(r, "交房租") || !strings.Contains(r, "/tdone 1") {
+		t.Errorf("reminder = %q", r)
+	}
+	if sent, _ := SendTodoReminders(); sent != 0 {
+		t.Error("todo should only be reminded once")
+	}
+
+	// 发送失败时不标记已提醒，一小时后重试
+	item, _ := todo.Add("fail", "1分钟后 交报告")
+	now = now.Add(time.Minute)
+	if sent, _ := SendTodoReminders(); sent != 0 {
+		t.Errorf("failed reminder counted as sent")
+	}
+	if item, _ = todo.Get("fail", item.Id); item.Reminded {
+		t.Error("failed reminder should not be marked reminded")
+	}
+	if reminders, _ := todo.DueReminders(now.Add(time.Hour)); len(reminders) != 1 {
+		t.Errorf("failed reminder should be retried: %v", reminders)
+	}
+}
@@ -0,0 +1,278 @@
+package chat
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+
+	"github.com/bytedance/sonic"
+	"github.com/google/generative-ai-go/genai"
+	"github.com/sashabaranov/go-openai"
+)
+
+const (
+	Tool_Param_String  = "string"
+	Tool_Param_Number  = "number"
+	Tool_Param_Integer = "integer"
+	Tool_Param_Boolean = "boolean"
+
+	// 单次对话最多执行几轮工具调用，避免模型反复调用工具
+	Max_Tool_Rounds = 5
+)
+
+// ToolParam 工具参数，只支持简单类型
+type ToolParam struct {
+	Name        string
+	Type        string
+	Description string
+	Enum        []string
+	Required    bool
+}
+
+// Tool 可以被模型调用的工具，Handler返回的结果会作为工具输出交给模型继续回答
+type Tool struct {
+	Name        string
+	Description string
+	Params      []ToolParam
+	Handler     func(userId string, args map[string]any) (string, error)
+}
+
+var (
+	toolLock  sync.RWMutex
+	toolList  []*Tool
+	toolIndex = map[string]*Tool{}
+)
+
+func init() {
+	RegisterTool(&Tool{
+		Name:        "get_coin_price",
+		Description: "查询加密货币的实时价格和24小时涨跌幅、最高最低价、成交量",
+		Params: []ToolParam{
+			{Name: "symbol", Type: Tool_Param_String, Description: "币种或币安交易对，例如btc、ETHUSDT，多个用空格分隔", Required: true},
+		},
+		Handler: func(userId string, args map[string]any) (string, error) {
+			return GetCoin(ToolArgString(args, "symbol"), userId), nil
+		},
+	})
+	RegisterTool(&Tool{
+		Name:        "get_weather",
+		Description: "查询中国主要城市的当前天气和未来三天预报",
+		Params: []ToolParam{
+			{Name: "city", Type: Tool_Param_String, Description: "城市名，例如北京、深圳，为空时使用用户上次查询的城市"},
+		},
+		Handler: func(userId string, args map[string]any) (string, error) {
+			return GetWeather(ToolArgString(args, "city"), userId), nil
+		},
+	})
+	RegisterTool(&Tool{
+		Name:        "add_todo",
+		Description: "为用户添加一条待办事项，截止时间写在内容开头会在到期时提醒用户",
+		Params: []ToolParam{
+			{Name: "content", Type: Tool_Param_String, Description: "待办事项内容，例如“明天下午3点 交报告”，以!开头表示重要、!!表示紧急", Required: true},
+		},
+		Handler: func(userId string, args map[string]any) (string, error) {
+			return AddTodo(ToolArgString(args, "content"), userId), nil
+		},
+	})
+	RegisterTool(&Tool{
+		Name:        "list_todo",
+		Description: "获取用户的待办事项列表，每项以#编号开头",
+		Handler: func(userId string, args map[string]any) (string, error) {
+			return GetTodoList(userId, false), nil
+		},
+	})
+	RegisterTool(&Tool{
+		Name:        "delete_todo",
+		Description: "按编号删除用户的一条待办事项，编号从list_todo的结果中获取",
+		Params: []ToolParam{
+			{Name: "id", Type: Tool_Param_Integer, Description: "待办事项编号", Required: true},
+		},
+		Handler: func(userId string, args map[string]any) (string, error) {
+			return DelTodo(int(ToolArgFloat(args, "id")), userId), nil
+		},
+	})
+	RegisterTool(&Tool{
+		Name:        "complete_todo",
+		Description: "按编号把用户的一条待办事项标记为已完成",
+		Params: []ToolParam{
+			{Name: "id", Type: Tool_Param_Integer, Description: "待办事项编号", Required: true},
+		},
+		Handler: func(userId string, args map[string]any) (string, error) {
+			return DoneTodo(int(ToolArgFloat(args, "id")), userId), nil
+		},
+	})
+	RegisterTool(&Tool{
+		Name:        "add_reminder",
+		Description: "在指定时间通过公众号消息提醒用户，支持重复提醒",
+		Params: []ToolParam{
+			{Name: "content", Type: Tool_Param_String, Description: "时间写在开头的提醒内容，例如“明天9点 交房租”“30分钟后 关火”“每个工作日9点 打卡”“每月1号 交房租”", Required: true},
+		},
+		Handler: func(userId string, args map[string]any) (string, error) {
+			return AddReminder(ToolArgString(args, "content"), userId), nil
+		},
+	})
+}
+
+// RegisterTool 注册工具，同名工具会被覆盖
+func RegisterTool(tool *Tool) {
+	toolLock.Lock()
+	defer toolLock.Unlock()
+	if _, ok := toolIndex[tool.Name]; ok {
+		for i, t := range toolList {
+			if t.Name == tool.Name {
+				toolList[i] = tool
+			}
+		}
+	} else {
+		toolList = append(toolList, tool)
+	}
+	toolIndex[tool.Name] = tool
+}
+
+func GetTools() []*Tool {
+	toolLock.RLock()
+	defer toolLock.RUnlock()
+	return append([]*Tool(nil), toolList...)
+}
+
+// CallTool 执行工具，出错时把错误信息作为结果返回给模型
+func CallTool(userId, name string, args map[string]any) string {
+	toolLock.RLock()
+	tool, ok := toolIndex[name]
+	toolLock.RUnlock()
+	if !ok {
+		return fmt.Sprintf("工具%s不存在", name)
+	}
+	if args == nil {
+		args = map[string]any{}
+	}
+	for _, param := range tool.Params {
+		if _, ok := args[param.Name]; param.Required && !ok {
+			return fmt.Sprintf("调用%s失败: 缺少参数%s", name, param.Name)
+		}
+	}
+	result, err := tool.Handler(userId, args)
+	if err != nil {
+		return fmt.Sprintf("调用%s失败: %v", name, err)
+	}
+	return result
+}
+
+// CallToolWithJson 执行参数为json字符串的工具调用(openai格式)
+func CallToolWithJson(userId, name, arguments string) string {
+	args := map[string]any{}
+	if arguments != "" {
+		if err := sonic.UnmarshalString(arguments, &args); err != nil {
+			return fmt.Sprintf("调用%s失败: 参数格式错误 %v", name, err)
+		}
+	}
+	return CallTool(userId, name, args)
+}
+
+func ToolArgString(args map[string]any, name string) string {
+	switch v := args[name].(type) {
+	case string:
+		return v
+	case nil:
+		return ""
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func ToolArgFloat(args map[string]any, name string) float64 {
+	switch v := args[name].(type) {
+	case float64:
+		return v
+	case int:
+		return float64(v)
+	case string:
+		f, _ := strconv.ParseFloat(v, 64)
+		return f
+	}
+	return 0
+}
+
+// JsonSchema 参数的json schema，openai和通义千问共用
+func (t *Tool) JsonSchema() map[string]any {
+	properties := map[string]any{}
+	required := []string{}
+	for _, param := range t.Params {
+		property := map[string]any{
+			"type":        param.Type,
+			"description": param.Description,
+		}
+		if len(param.Enum) > 0 {
+			property["enum"] = param.Enum
+		}
+		properties[param.Name] = property
+		if param.Required {
+			required = append(required, param.Name)
+		}
+	}
+	return map[string]any{
+		"type":       "object",
+		"properties": properties,
+		"required":   required,
+	}
+}
+
+func toOpenAITools(tools []*Tool) []openai.Tool {
+	list := make([]openai.Tool, 0, len(tools))
+	for _, tool := range tools {
+		list = append(list, openai.Tool{
+			Type: openai.ToolTypeFunction,
+			Function: &openai.FunctionDefinition{
+				Name:        tool.Name,
+				Description: tool.Description,
+				Parameters:  tool.JsonSchema(),
+			},
+		})
+	}
+	return list
+}
+
+func toGeminiTools(tools []*Tool) []*genai.Tool {
+	if len(tools) == 0 {
+		return nil
+	}
+	declarations := make([]*genai.FunctionDeclaration, 0, len(tools))
+	for _, tool := range tools {
+		declaration := &genai.FunctionDeclaration{
+			Name:        tool.Name,
+			Description: tool.Description,
+		}
+		if len(tool.Params) > 0 {
+			schema := &genai.Schema{Type: genai.TypeObject, Properties: map[string]*genai.Schema{}}
+			for _, param := range tool.Params {
+				schema.Properties[param.Name] = &genai.Schema{
+					Type:        geminiParamType(param.Type),
+					Description: param.Description,
+					Enum:        param.Enum,
+				}
+				if len(param.Enum) > 0 {
+					schema.Properties[param.Name].Format = "enum"
+				}
+				if param.Required {
+					schema.Required = append(schema.Required, param.Name)
+				}
+			}
+			declaration.Parameters = schema
+		}
+		declarations = append(declarations, declaration)
+	}
+	return []*genai.Tool{{FunctionDeclarations: declarations}}
+}
+
+func geminiParamType(paramType string) genai.Type {
+	switch paramType {
+	case Tool_Param_Number:
+		return genai.TypeNumber
+	case Tool_Param_Integer:
+		return genai.TypeInteger
+	case Tool_Param_Boolean:
+		return genai.TypeBoolean
+	default:
+		return genai.TypeString
+	}
+}
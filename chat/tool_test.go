@@ -0,0 +1,139 @@
+package chat
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/generative-ai-go/genai"
+	"github.com/sashabaranov/go-openai"
+)
+
+// registerWeatherTool 注册测试用的工具，测试结束后恢复原来的工具列表
+func registerWeatherTool(t *testing.T) {
+	t.Helper()
+	toolLock.RLock()
+	list, index := toolList, maps.Clone(toolIndex)
+	toolLock.RUnlock()
+	t.Cleanup(func() {
+		toolLock.Lock()
+		toolList, toolIndex = list, index
+		toolLock.Unlock()
+	})
+	RegisterTool(&Tool{
+		Name:        "test_weather",
+		Description: "查询天气",
+		Params: []ToolParam{
+			{Name: "city", Type: Tool_Param_String, Description: "城市", Required: true},
+			{Name: "unit", Type: Tool_Param_String, Enum: []string{"c", "f"}},
+		},
+		Handler: func(userId string, args map[string]any) (string, error) {
+			if ToolArgString(args, "city") == "火星" {
+				return "", errors.New("不支持的城市")
+			}
+			return userId + ":" + ToolArgString(args, "city") + "晴", nil
+		},
+	})
+}
+
+func findTool(t *testing.T, name string) *Tool {
+	t.Helper()
+	for _, tool := range GetTools() {
+		if tool.Name == name {
+			return tool
+		}
+	}
+	t.Fatalf("tool %s not registered", name)
+	return nil
+}
+
+func TestToolSchema(t *testing.T) {
+	registerWeatherTool(t)
+	tool := findTool(t, "test_weather")
+	schema := tool.JsonSchema()
+	if required := schema["required"].([]string); len(required) != 1 || required[0] != "city" {
+		t.Errorf("unexpected required %v", required)
+	}
+	unit := schema["properties"].(map[string]any)["unit"].(map[string]any)
+	if enum := unit["enum"].([]string); len(enum) != 2 {
+		t.Errorf("unexpected enum %v", enum)
+	}
+
+	geminiTools := toGeminiTools([]*Tool{tool})
+	declaration := geminiTools[0].FunctionDeclarations[0]
+	if declaration.Parameters.Type != genai.TypeObject || declaration.Parameters.Properties["unit"].Format != "enum" {
+		t.Errorf("unexpected gemini schema %+v", declaration.Parameters)
+	}
+	if openaiTools := toOpenAITools([]*Tool{tool}); openaiTools[0].Function.Name != "test_weather" {
+		t.Errorf("unexpected openai tools %+v", openaiTools)
+	}
+}
+
+func TestCallTool(t *testing.T) {
+	registerWeatherTool(t)
+	if r := CallToolWithJson("u1", "test_weather", `{"city":"北京"}`); r != "u1:北京晴" {
+		t.Errorf("unexpected result %q", r)
+	}
+	if r := CallTool("u1", "test_weather", nil); !strings.Contains(r, "缺少参数city") {
+		t.Errorf("missing param should be reported, got %q", r)
+	}
+	if r := CallTool("u1", "test_weather", map[string]any{"city": "火星"}); !strings.Contains(r, "不支持的城市") {
+		t.Errorf("handler error should be reported, got %q", r)
+	}
+	if r := CallToolWithJson("u1", "test_weather", `{bad json`); !strings.Contains(r, "参数格式错误") {
+		t.Errorf("bad arguments should be reported, got %q", r)
+	}
+	if r := CallTool("u1", "not_exist", nil); !strings.Contains(r, "不存在") {
+		t.Errorf("unknown tool should be reported, got %q", r)
+	}
+}
+
+func TestGptToolLoop(t *testing.T) {
+	registerWeatherTool(t)
+	round := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req openai.ChatCompletionRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatal(err)
+		}
+		round++
+		w.Header().Set("Content-Type", "application/json")
+		if round == 1 {
+			if len(req.Tools) == 0 {
+				t.Error("tools should be sent")
+			}
+			io.WriteString(w, `{"choices":[{"index":0,"finish_reason":"tool_calls","message":{"role":"assistant","content":"","tool_calls":[{"id":"call_1","type":"function","function":{"name":"test_weather","arguments":"{\"city\":\"杭州\"}"}}]}}]}`)
+			return
+		}
+		last := req.Messages[len(req.Messages)-1]
+		if last.Role != openai.ChatMessageRoleTool || last.ToolCallID != "call_1" || last.Content != "gptToolUser:杭州晴" {
+			t.Errorf("unexpected tool message %+v", last)
+		}
+		io.WriteString(w, `{"choices":[{"index":0,"finish_reason":"stop","message":{"role":"assistant","content":"杭州今天晴"}}]}`)
+	}))
+	defer server.Close()
+
+	cfg := openai.DefaultConfig("test")
+	cfg.BaseURL = server.URL + "/v1"
+	chat := &SimpleGptChat{}
+	req := openai.ChatCompletionRequest{
+		Model:    "gpt-test",
+		Messages: []openai.ChatCompletionMessage{{Role: openai.ChatMessageRoleUser, Content: "杭州天气"}},
+		Tools:    toOpenAITools([]*Tool{findTool(t, "test_weather")}),
+	}
+	content, err := chat.complete(openai.NewClientWithConfig(cfg), "gptToolUser", req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if content != "杭州今天晴" || round != 2 {
+		t.Errorf("unexpected content %q after %d rounds", content, round)
+	}
+	if len(req.Messages) != 1 {
+		t.Error("tool messages should not modify the original message list")
+	}
+}
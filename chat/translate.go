@@ -0,0 +1,106 @@
+package chat
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pwh-pwh/aiwechat-vercel/client"
+	"github.com/pwh-pwh/aiwechat-vercel/config"
+	"github.com/pwh-pwh/aiwechat-vercel/translate"
+)
+
+func init() {
+	commands := []*Command{
+		{Name: config.Wx_Translate, Aliases: []string{"翻译"}, Args: []CommandArg{{Name: "文本", Type: Arg_Text}},
+			Usage: "翻译，可以在开头写目标语言，如 /fy 日语 你好，不写时中文翻译为英语、其它语言翻译为中文", Handler: paramCommand(Translate, "文本")},
+		{Name: config.Wx_Translate_Mode, Args: []CommandArg{{Name: "on|off|目标语言", Type: Arg_String, Optional: true}},
+			Usage: "开启后发送的消息都会翻译，直到关闭", Handler: paramCommand(TranslateMode, "on|off|目标语言")},
+		{Name: config.Wx_Translate_Bilingual, Args: []CommandArg{{Name: "开关", Type: Arg_String, Optional: true, Choices: []string{"on", "off"}}},
+			Usage: "翻译结果同时显示原文", Handler: paramCommand(TranslateBilingual, "开关")},
+	}
+	for _, cmd := range commands {
+		RegisterCommand(cmd)
+	}
+}
+
+func getTranslator(userId string) translate.Translator {
+	if config.GetTranslateProvider() == config.Translate_Provider_Deepl {
+		return &translate.DeepLTranslator{Client: client.NewDeepL(config.GetDeeplUrl(), config.GetDeeplKey())}
+	}
+	return &translate.BotTranslator{UserId: userId, Complete: Complete}
+}
+
+// Translate 翻译文本，开头写了目标语言时使用该语言，否则使用翻译模式设置的语言或自动选择
+func Translate(param, userId string) string {
+	settings := translate.GetSettings(userId)
+	target, text, ok := translate.ParseTarget(param)
+	if !ok {
+		target = settings.TargetLang(text)
+	}
+	return WithTimeChat(userId, config.Wx_Translate+target.Code+text, func(userId, msg string) string {
+		r, err := getTranslator(userId).Translate(text, target)
+		if err != nil {
+			return err.Error()
+		}
+		return translate.Format(text, r, settings.Bilingual)
+	})
+}
+
+// TranslateMode 开启或关闭翻译模式，param为目标语言时开启并使用该语言
+func TranslateMode(param, userId string) string {
+	settings := translate.GetSettings(userId)
+	switch strings.ToLower(param) {
+	case "":
+		if !settings.Mode {
+			return "翻译模式未开启，发送 /fymode on 开启"
+		}
+		return "翻译模式已开启，目标语言：" + targetName(settings) + "，发送 /fymode off 关闭"
+	case "on":
+		settings.Mode = true
+	case "off":
+		settings.Mode = false
+	default:
+		lang, ok := translate.FindLang(param)
+		if !ok {
+			return fmt.Sprintf("不支持的语言：%s", param)
+		}
+		settings.Mode, settings.Target = true, lang.Code
+	}
+	if err := translate.SaveSettings(userId, settings); err != nil {
+		return err.Error()
+	}
+	if !settings.Mode {
+		return "翻译模式已关闭"
+	}
+	return "翻译模式已开启，目标语言：" + targetName(settings) + "，之后发送的消息都会翻译，发送 /fymode off 关闭"
+}
+
+func targetName(settings translate.Settings) string {
+	if lang, ok := translate.FindLang(settings.Target); ok {
+		return lang.Name
+	}
+	return "自动(中文翻译为英语，其它语言翻译为中文)"
+}
+
+func TranslateBilingual(param, userId string) string {
+	settings := translate.GetSettings(userId)
+	if param == "" {
+		if settings.Bilingual {
+			return "双语显示已开启"
+		}
+		return "双语显示未开启"
+	}
+	settings.Bilingual = param == "on"
+	if err := translate.SaveSettings(userId, settings); err != nil {
+		return err.Error()
+	}
+	if settings.Bilingual {
+		return "双语显示已开启，翻译结果会同时显示原文"
+	}
+	return "双语显示已关闭"
+}
+
+// IsTranslateMode 用户是否开启了翻译模式
+func IsTranslateMode(userId string) bool {
+	return translate.GetSettings(userId).Mode
+}
@@ -0,0 +1,50 @@
+package chat
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pwh-pwh/aiwechat-vercel/translate"
+)
+
+func TestTranslate(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Text       []string `json:"text"`
+			TargetLang string   `json:"target_lang"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		json.NewEncoder(w).Encode(map[string]any{"translations": []map[string]string{{"text": body.TargetLang + ":" + body.Text[0]}}})
+	}))
+	defer server.Close()
+	t.Setenv("translateProvider", "deepl")
+	t.Setenv("deeplUrl", server.URL)
+	t.Cleanup(func() { translate.SaveSettings("trans-chat", translate.Settings{}) })
+
+	// 按顺序执行，/fymode 会修改之后 /fy 的默认目标语言
+	for _, c := range [][2]string{
+		{"/fy 你好", "EN-US:你好"},
+		{"翻译 good night", "ZH:good night"},
+		{"/fy 日语 你好", "JA:你好"},
+		{"/fymode 法语", "翻译模式已开启，目标语言：法语，之后发送的消息都会翻译，发送 /fymode off 关闭"},
+		{"/fymode xx", "不支持的语言：xx"},
+	} {
+		if r, _ := RunCommand("trans-chat", c[0]); r != c[1] {
+			t.Errorf("%s = %q, want %q", c[0], r, c[1])
+		}
+	}
+
+	RunCommand("trans-chat", "/fybi on")
+	if r, ok := DoAction("trans-chat", "早上好"); !ok || r != "早上好\n——————\nFR:早上好" {
+		t.Errorf("translate mode = %q, %v", r, ok)
+	}
+	// 翻译模式下指令仍然生效
+	if r, _ := DoAction("trans-chat", "/fymode off"); r != "翻译模式已关闭" {
+		t.Errorf("/fymode off = %q", r)
+	}
+	if _, ok := DoAction("trans-chat", "早上好"); ok {
+		t.Error("messages should not be translated after mode is off")
+	}
+}
@@ -0,0 +1,52 @@
+package chat
+
+import (
+	"fmt"
+
+	"github.com/pwh-pwh/aiwechat-vercel/client"
+	"github.com/pwh-pwh/aiwechat-vercel/config"
+	"github.com/pwh-pwh/aiwechat-vercel/weather"
+)
+
+// WeatherBackend 不为空时替代配置中的天气后端，测试时可以替换为本地实现
+var WeatherBackend client.WeatherProvider
+
+func getWeatherProvider() (client.WeatherProvider, error) {
+	if WeatherBackend != nil {
+		return WeatherBackend, nil
+	}
+	return client.NewWeatherProvider(config.GetWeatherProvider(), config.GetWeatherUrl(), config.GetWeatherKey())
+}
+
+func init() {
+	RegisterCommand(&Command{Name: config.Wx_Weather, Aliases: []string{"天气"}, Args: []CommandArg{{Name: "城市", Type: Arg_String, Optional: true}},
+		Usage: "查看当前天气和三天预报，不填城市时使用上次查询的城市", Handler: paramCommand(GetWeather, "城市")})
+}
+
+// GetWeather 查询天气，查询成功后记住城市，下次可以不填
+func GetWeather(param, userId string) string {
+	name := param
+	if name == "" {
+		if name = weather.GetDefaultCity(userId); name == "" {
+			return "请输入城市，例如：/wec 北京，之后可以直接发送 /wec"
+		}
+	}
+	city, ok := weather.FindCity(name)
+	if !ok {
+		return fmt.Sprintf("没有找到城市%s，目前支持直辖市、省会和主要城市", name)
+	}
+	provider, err := getWeatherProvider()
+	if err != nil {
+		return err.Error()
+	}
+	w, err := provider.Weather(city.Location())
+	if err != nil {
+		return err.Error()
+	}
+	if param != "" {
+		if err = weather.SetDefaultCity(userId, city.Name); err != nil {
+			fmt.Println("set default city error:", err)
+		}
+	}
+	return weather.Format(city, w)
+}
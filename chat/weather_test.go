@@ -0,0 +1,44 @@
+package chat
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/pwh-pwh/aiwechat-vercel/client"
+	"github.com/pwh-pwh/aiwechat-vercel/db"
+)
+
+type fakeWeather struct {
+	locations []client.WeatherLocation
+}
+
+func (f *fakeWeather) Weather(loc client.WeatherLocation) (*client.Weather, error) {
+	f.locations = append(f.locations, loc)
+	return &client.Weather{Now: client.WeatherNow{Text: "晴", Temp: 20, Wind: "微风"}}, nil
+}
+
+func TestGetWeather(t *testing.T) {
+	provider := &fakeWeather{}
+	WeatherBackend = provider
+	t.Cleanup(func() {
+		WeatherBackend = nil
+		db.DeleteHashValue(db.WEATHER_KEY, "weather-u1")
+	})
+
+	if r, _ := RunCommand("weather-u1", "/wec"); !strings.HasPrefix(r, "请输入城市") {
+		t.Errorf("/wec without city = %q", r)
+	}
+	if r, _ := RunCommand("weather-u1", "天气 火星"); !strings.HasPrefix(r, "没有找到城市火星") {
+		t.Errorf("/wec unknown = %q", r)
+	}
+	if r, _ := RunCommand("weather-u1", "/wec 上海市"); !strings.HasPrefix(r, "上海 现在晴 20℃") {
+		t.Errorf("/wec 上海 = %q", r)
+	}
+	// 记住上次查询的城市
+	if r, _ := RunCommand("weather-u1", "/wec"); !strings.HasPrefix(r, "上海") {
+		t.Errorf("/wec default = %q", r)
+	}
+	if len(provider.locations) != 2 || provider.locations[0].Id != "101020100" || provider.locations[0].Lat != 31.23 {
+		t.Errorf("locations = %+v", provider.locations)
+	}
+}
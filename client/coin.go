@@ -5,31 +5,110 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"strings"
+	"time"
 )
 
-const BINANCE_API_URL = "https://api.binance.com/api/v3/ticker/price?symbol=%s"
+// BINANCE_API_URL 币安行情接口，部分地区的服务器无法访问时可以改用 https://data-api.binance.vision
+const BINANCE_API_URL = "https://api.binance.com"
 
 type CoinPrice struct {
 	Symbol string `json:"symbol"`
 	Price  string `json:"price"`
 }
 
-func GetCoinPrice(symbol string) (*CoinPrice, error) {
-	symbolUpper := strings.ToUpper(symbol)
-	resp, err := http.Get(fmt.Sprintf(BINANCE_API_URL, symbolUpper))
+// Ticker 24小时行情
+type Ticker struct {
+	Symbol             string `json:"symbol"`
+	LastPrice          string `json:"lastPrice"`
+	PriceChange        string `json:"priceChange"`
+	PriceChangePercent string `json:"priceChangePercent"`
+	HighPrice          string `json:"highPrice"`
+	LowPrice           string `json:"lowPrice"`
+	Volume             string `json:"volume"`
+	QuoteVolume        string `json:"quoteVolume"`
+}
+
+// BinanceError 币安接口返回的错误，如 {"code":-1121,"msg":"Invalid symbol."}
+type BinanceError struct {
+	Code int    `json:"code"`
+	Msg  string `json:"msg"`
+}
+
+// 交易对不存在
+const Binance_Invalid_Symbol = -1121
+
+func (e *BinanceError) Error() string {
+	if e.Code == Binance_Invalid_Symbol {
+		return "交易对不存在"
+	}
+	return fmt.Sprintf("币安接口错误(%d): %s", e.Code, e.Msg)
+}
+
+// Binance 币安行情接口，BaseUrl为空时使用BINANCE_API_URL
+type Binance struct {
+	BaseUrl string
+}
+
+func NewBinance(baseUrl string) *Binance {
+	if baseUrl == "" {
+		baseUrl = BINANCE_API_URL
+	}
+	return &Binance{BaseUrl: strings.TrimSuffix(baseUrl, "/")}
+}
+
+var binanceHttpClient = &http.Client{Timeout: 4 * time.Second}
+
+func (b *Binance) get(path string, query url.Values, v any) error {
+	resp, err := binanceHttpClient.Get(b.BaseUrl + path + "?" + query.Encode())
 	if err != nil {
-		return nil, err
+		return err
 	}
 	defer resp.Body.Close()
-	bytes, err := io.ReadAll(resp.Body)
+	data, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, err
+		return err
 	}
-	coinPrice := new(CoinPrice)
-	err = json.Unmarshal(bytes, coinPrice)
-	if err != nil {
-		return nil, err
+	if resp.StatusCode != http.StatusOK {
+		binanceErr := new(BinanceError)
+		if json.Unmarshal(data, binanceErr) == nil && binanceErr.Msg != "" {
+			return binanceErr
+		}
+		return fmt.Errorf("币安接口请求失败，状态码%d", resp.StatusCode)
 	}
-	return coinPrice, nil
+	return json.Unmarshal(data, v)
+}
+
+// symbolsQuery 多个交易对的参数格式为 ["BTCUSDT","ETHUSDT"]
+func symbolsQuery(symbols []string) url.Values {
+	data, _ := json.Marshal(symbols)
+	return url.Values{"symbols": {string(data)}}
+}
+
+func (b *Binance) Price(symbol string) (*CoinPrice, error) {
+	price := new(CoinPrice)
+	return price, b.get("/api/v3/ticker/price", url.Values{"symbol": {strings.ToUpper(symbol)}}, price)
+}
+
+// Prices 批量查询价格，有一个交易对不存在时整体返回错误
+func (b *Binance) Prices(symbols []string) ([]CoinPrice, error) {
+	var prices []CoinPrice
+	return prices, b.get("/api/v3/ticker/price", symbolsQuery(symbols), &prices)
+}
+
+func (b *Binance) Ticker(symbol string) (*Ticker, error) {
+	ticker := new(Ticker)
+	return ticker, b.get("/api/v3/ticker/24hr", url.Values{"symbol": {strings.ToUpper(symbol)}}, ticker)
+}
+
+// Tickers 批量查询24小时行情，有一个交易对不存在时整体返回错误
+func (b *Binance) Tickers(symbols []string) ([]Ticker, error) {
+	var tickers []Ticker
+	return tickers, b.get("/api/v3/ticker/24hr", symbolsQuery(symbols), &tickers)
+}
+
+// GetCoinPrice 使用默认地址查询价格
+func GetCoinPrice(symbol string) (*CoinPrice, error) {
+	return NewBinance("").Price(symbol)
 }
@@ -1,13 +1,45 @@
 package client
 
 import (
+	"net/http"
+	"net/http/httptest"
 	"testing"
 )
 
-func TestGetCoinPrice(t *testing.T) {
-	price, err := GetCoinPrice("solusdt")
-	if err != nil {
-		t.Error(err)
+func TestBinance(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		switch {
+		case q.Get("symbol") == "NOPEUSDT":
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"code":-1121,"msg":"Invalid symbol."}`))
+		case r.URL.Path == "/api/v3/ticker/price" && q.Get("symbol") == "BTCUSDT":
+			w.Write([]byte(`{"symbol":"BTCUSDT","price":"67000.10000000"}`))
+		case r.URL.Path == "/api/v3/ticker/price" && q.Get("symbols") == `["BTCUSDT","ETHUSDT"]`:
+			w.Write([]byte(`[{"symbol":"BTCUSDT","price":"67000.1"},{"symbol":"ETHUSDT","price":"3500"}]`))
+		case r.URL.Path == "/api/v3/ticker/24hr" && q.Get("symbol") == "BTCUSDT":
+			w.Write([]byte(`{"symbol":"BTCUSDT","lastPrice":"67000.1","priceChangePercent":"-1.25","highPrice":"68000","lowPrice":"66000","volume":"12345.6","quoteVolume":"827000000"}`))
+		default:
+			w.WriteHeader(http.StatusTeapot)
+		}
+	}))
+	defer server.Close()
+
+	b := NewBinance(server.URL + "/")
+	if price, err := b.Price("btcusdt"); err != nil || price.Price != "67000.10000000" {
+		t.Errorf("Price = %v, %v", price, err)
+	}
+	if prices, err := b.Prices([]string{"BTCUSDT", "ETHUSDT"}); err != nil || len(prices) != 2 || prices[1].Price != "3500" {
+		t.Errorf("Prices = %v, %v", prices, err)
+	}
+	if ticker, err := b.Ticker("BTCUSDT"); err != nil || ticker.PriceChangePercent != "-1.25" || ticker.HighPrice != "68000" {
+		t.Errorf("Ticker = %v, %v", ticker, err)
+	}
+	_, err := b.Price("NOPEUSDT")
+	if e, ok := err.(*BinanceError); !ok || e.Code != Binance_Invalid_Symbol || err.Error() != "交易对不存在" {
+		t.Errorf("invalid symbol error = %v", err)
+	}
+	if _, err = b.Ticker("ETHUSDT"); err == nil || err.Error() != "币安接口请求失败，状态码418" {
+		t.Errorf("status error = %v", err)
 	}
-	t.Log(price)
 }
@@ -0,0 +1,78 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	DEEPL_API_URL      = "https://api.deepl.com"
+	DEEPL_FREE_API_URL = "https://api-free.deepl.com"
+)
+
+// DeepL 翻译接口，BaseUrl为空时按key判断，免费版的key以:fx结尾
+type DeepL struct {
+	BaseUrl string
+	Key     string
+}
+
+func NewDeepL(baseUrl, key string) *DeepL {
+	if baseUrl == "" {
+		baseUrl = DEEPL_API_URL
+		if strings.HasSuffix(key, ":fx") {
+			baseUrl = DEEPL_FREE_API_URL
+		}
+	}
+	return &DeepL{BaseUrl: strings.TrimSuffix(baseUrl, "/"), Key: key}
+}
+
+var deeplHttpClient = &http.Client{Timeout: 4 * time.Second}
+
+// Translate 翻译为targetLang(如 ZH、EN-US)，返回译文和识别出的原文语言
+func (d *DeepL) Translate(text, targetLang string) (string, string, error) {
+	body, err := json.Marshal(map[string]any{"text": []string{text}, "target_lang": targetLang})
+	if err != nil {
+		return "", "", err
+	}
+	req, err := http.NewRequest(http.MethodPost, d.BaseUrl+"/v2/translate", bytes.NewReader(body))
+	if err != nil {
+		return "", "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "DeepL-Auth-Key "+d.Key)
+	resp, err := deeplHttpClient.Do(req)
+	if err != nil {
+		return "", "", err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		var e struct {
+			Message string `json:"message"`
+		}
+		json.Unmarshal(data, &e)
+		return "", "", fmt.Errorf("deepl request failed with status code %d: %s", resp.StatusCode, e.Message)
+	}
+	var result struct {
+		Translations []struct {
+			DetectedSourceLanguage string `json:"detected_source_language"`
+			Text                   string `json:"text"`
+		} `json:"translations"`
+	}
+	if err = json.Unmarshal(data, &result); err != nil {
+		return "", "", err
+	}
+	if len(result.Translations) == 0 {
+		return "", "", errors.New("deepl returned no translation")
+	}
+	return result.Translations[0].Text, result.Translations[0].DetectedSourceLanguage, nil
+}
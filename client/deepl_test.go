@@ -0,0 +1,42 @@
+package client
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestDeepLTranslate(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Text       []string `json:"text"`
+			TargetLang string   `json:"target_lang"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		if r.URL.Path != "/v2/translate" || r.Header.Get("Authorization") != "DeepL-Auth-Key k1:fx" {
+			t.Errorf("request = %s %v", r.URL.Path, r.Header)
+		}
+		if body.TargetLang == "XX" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"message":"Value for 'target_lang' not supported."}`))
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{"translations": []map[string]string{
+			{"detected_source_language": "ZH", "text": body.TargetLang + ":" + body.Text[0]},
+		}})
+	}))
+	defer server.Close()
+
+	if d := NewDeepL("", "k1:fx"); d.BaseUrl != DEEPL_FREE_API_URL {
+		t.Errorf("free key BaseUrl = %s", d.BaseUrl)
+	}
+	d := NewDeepL(server.URL, "k1:fx")
+	text, source, err := d.Translate("你好", "EN-US")
+	if err != nil || text != "EN-US:你好" || source != "ZH" {
+		t.Errorf("Translate = %q %q %v", text, source, err)
+	}
+	if _, _, err = d.Translate("你好", "XX"); err == nil || err.Error() != "deepl request failed with status code 400: Value for 'target_lang' not supported." {
+		t.Errorf("error = %v", err)
+	}
+}
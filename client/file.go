@@ -0,0 +1,56 @@
+package client
+
+import (
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+)
+
+type RemoteFile struct {
+	Name        string
+	ContentType string
+	Data        []byte
+}
+
+// FetchFile 下载文件，超过maxSize字节时返回错误；文件名优先取Content-Disposition，只能访问公网地址
+func FetchFile(fileUrl string, maxSize int64) (*RemoteFile, error) {
+	u, err := url.Parse(fileUrl)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return nil, fmt.Errorf("不支持的链接: %s", fileUrl)
+	}
+	req, err := http.NewRequest(http.MethodGet, fileUrl, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", pageUserAgent)
+	resp, err := FetchHttpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("下载文件失败，状态码: %d", resp.StatusCode)
+	}
+	if resp.ContentLength > maxSize {
+		return nil, fmt.Errorf("文件过大，最大支持%dMB", maxSize>>20)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > maxSize {
+		return nil, fmt.Errorf("文件过大，最大支持%dMB", maxSize>>20)
+	}
+	file := &RemoteFile{
+		Name:        path.Base(u.Path),
+		ContentType: resp.Header.Get("Content-Type"),
+		Data:        data,
+	}
+	if _, params, err := mime.ParseMediaType(resp.Header.Get("Content-Disposition")); err == nil && params["filename"] != "" {
+		file.Name = params["filename"]
+	}
+	return file, nil
+}
@@ -0,0 +1,75 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+)
+
+var ErrPrivateAddr = errors.New("不支持访问内网地址")
+
+// FetchHttpClient 抓取用户发送的链接使用的client，只允许访问公网地址
+var FetchHttpClient = NewGuardedHttpClient(10 * time.Second)
+
+// 不属于私有地址但同样不能访问的网段，100.64.0.0/10中包含阿里云的元数据地址100.100.100.200
+var blockedNets = []*net.IPNet{
+	mustParseCIDR("0.0.0.0/8"),
+	mustParseCIDR("100.64.0.0/10"),
+}
+
+func mustParseCIDR(s string) *net.IPNet {
+	_, n, err := net.ParseCIDR(s)
+	if err != nil {
+		panic(err)
+	}
+	return n
+}
+
+// NewGuardedHttpClient 只允许访问公网http/https地址的client，避免用户通过链接访问内网或云服务器元数据接口
+// 在建立连接时检查解析后的ip，重定向和dns重新解析后同样生效
+func NewGuardedHttpClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{Timeout: 5 * time.Second, Control: guardAddress}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// 通过代理访问时只能检查代理的地址
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &http.Client{Timeout: timeout, Transport: transport, CheckRedirect: checkRedirect}
+}
+
+func guardAddress(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if ip := net.ParseIP(host); ip == nil || !IsPublicIP(ip) {
+		return fmt.Errorf("%w: %s", ErrPrivateAddr, host)
+	}
+	return nil
+}
+
+func checkRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= 10 {
+		return errors.New("重定向次数过多")
+	}
+	if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+		return fmt.Errorf("不支持的链接: %s", req.URL)
+	}
+	return nil
+}
+
+// IsPublicIP 排除回环、私有、链路本地、未指定和组播地址
+func IsPublicIP(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified() {
+		return false
+	}
+	for _, n := range blockedNets {
+		if n.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
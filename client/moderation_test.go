@@ -0,0 +1,34 @@
+package client
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestModerationApiClassify(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]string
+		json.NewDecoder(r.Body).Decode(&body)
+		if r.URL.Path != "/v1/moderations" || r.Header.Get("Authorization") != "Bearer tk" || body["model"] != "m1" {
+			t.Errorf("request = %s %v %v", r.URL.Path, r.Header, body)
+		}
+		flagged := strings.Contains(body["input"], "kill")
+		json.NewEncoder(w).Encode(map[string]any{"results": []map[string]any{{
+			"flagged":    flagged,
+			"categories": map[string]bool{"violence": flagged, "hate": false, "harassment": flagged},
+		}}})
+	}))
+	defer server.Close()
+
+	api := &ModerationApi{BaseUrl: server.URL + "/v1/", Token: "tk", Model: "m1"}
+	categories, err := api.Classify("i will kill it")
+	if err != nil || strings.Join(categories, ",") != "harassment,violence" {
+		t.Errorf("Classify = %v, %v", categories, err)
+	}
+	if categories, err = api.Classify("hello"); err != nil || len(categories) != 0 {
+		t.Errorf("clean Classify = %v, %v", categories, err)
+	}
+}
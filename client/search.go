@@ -0,0 +1,143 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+const (
+	SEARCH_PROVIDER_BING    = "bing"
+	SEARCH_PROVIDER_SEARXNG = "searxng"
+
+	BING_API_URL = "https://api.bing.microsoft.com/v7.0/search"
+)
+
+type SearchResult struct {
+	Title   string
+	Url     string
+	Snippet string
+}
+
+// Searcher 联网搜索后端，返回按相关度排序的结果
+type Searcher interface {
+	Search(query string, count int) ([]SearchResult, error)
+}
+
+// NewSearcher 按provider创建搜索后端，baseUrl为空时使用默认地址(searxng必须填写)
+func NewSearcher(provider, baseUrl, apiKey string) (Searcher, error) {
+	switch provider {
+	case SEARCH_PROVIDER_BING:
+		if apiKey == "" {
+			return nil, errors.New("请配置searchApiKey")
+		}
+		if baseUrl == "" {
+			baseUrl = BING_API_URL
+		}
+		return &BingSearcher{BaseUrl: baseUrl, ApiKey: apiKey}, nil
+	case SEARCH_PROVIDER_SEARXNG:
+		if baseUrl == "" {
+			return nil, errors.New("请配置searchUrl")
+		}
+		return &SearxngSearcher{BaseUrl: baseUrl}, nil
+	case "":
+		return nil, errors.New("请配置searchProvider")
+	default:
+		return nil, fmt.Errorf("不支持的searchProvider: %s", provider)
+	}
+}
+
+var searchHttpClient = &http.Client{Timeout: 5 * time.Second}
+
+func getJson(req *http.Request, v any) error {
+	resp, err := searchHttpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("search request failed with status code %d: %s", resp.StatusCode, string(body))
+	}
+	return json.Unmarshal(body, v)
+}
+
+// BingSearcher 使用Bing Web Search API
+type BingSearcher struct {
+	BaseUrl string
+	ApiKey  string
+}
+
+func (b *BingSearcher) Search(query string, count int) ([]SearchResult, error) {
+	params := url.Values{}
+	params.Set("q", query)
+	params.Set("count", strconv.Itoa(count))
+	params.Set("mkt", "zh-CN")
+	req, err := http.NewRequest(http.MethodGet, b.BaseUrl+"?"+params.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Ocp-Apim-Subscription-Key", b.ApiKey)
+	var rpn struct {
+		WebPages struct {
+			Value []struct {
+				Name    string `json:"name"`
+				Url     string `json:"url"`
+				Snippet string `json:"snippet"`
+			} `json:"value"`
+		} `json:"webPages"`
+	}
+	if err = getJson(req, &rpn); err != nil {
+		return nil, err
+	}
+	results := make([]SearchResult, 0, len(rpn.WebPages.Value))
+	for _, item := range rpn.WebPages.Value {
+		results = append(results, SearchResult{Title: item.Name, Url: item.Url, Snippet: item.Snippet})
+	}
+	return limitResults(results, count), nil
+}
+
+// SearxngSearcher 使用自建的SearXNG实例，需要在settings.yml中开启json格式
+type SearxngSearcher struct {
+	BaseUrl string
+}
+
+func (s *SearxngSearcher) Search(query string, count int) ([]SearchResult, error) {
+	params := url.Values{}
+	params.Set("q", query)
+	params.Set("format", "json")
+	req, err := http.NewRequest(http.MethodGet, s.BaseUrl+"/search?"+params.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	var rpn struct {
+		Results []struct {
+			Title   string `json:"title"`
+			Url     string `json:"url"`
+			Content string `json:"content"`
+		} `json:"results"`
+	}
+	if err = getJson(req, &rpn); err != nil {
+		return nil, err
+	}
+	results := make([]SearchResult, 0, len(rpn.Results))
+	for _, item := range rpn.Results {
+		results = append(results, SearchResult{Title: item.Title, Url: item.Url, Snippet: item.Content})
+	}
+	return limitResults(results, count), nil
+}
+
+func limitResults(results []SearchResult, count int) []SearchResult {
+	if count > 0 && len(results) > count {
+		return results[:count]
+	}
+	return results
+}
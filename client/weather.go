@@ -0,0 +1,227 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+const (
+	WEATHER_PROVIDER_OPENMETEO = "openmeteo"
+	WEATHER_PROVIDER_QWEATHER  = "qweather"
+
+	OPENMETEO_API_URL = "https://api.open-meteo.com"
+	QWEATHER_API_URL  = "https://devapi.qweather.com"
+)
+
+// WeatherLocation 查询天气的位置，和风天气使用Id，open-meteo使用经纬度
+type WeatherLocation struct {
+	Id  string
+	Lat float64
+	Lon float64
+}
+
+type WeatherNow struct {
+	Text      string
+	Temp      float64
+	FeelsLike float64
+	Humidity  int
+	Wind      string
+}
+
+type WeatherDay struct {
+	Date      string
+	TextDay   string
+	TextNight string
+	TempMax   float64
+	TempMin   float64
+}
+
+// Weather 当前天气和未来几天的预报，Days第一天为今天
+type Weather struct {
+	Now  WeatherNow
+	Days []WeatherDay
+}
+
+// WeatherProvider 天气后端
+type WeatherProvider interface {
+	Weather(loc WeatherLocation) (*Weather, error)
+}
+
+// NewWeatherProvider 按provider创建天气后端，默认使用不需要key的open-meteo
+func NewWeatherProvider(provider, baseUrl, key string) (WeatherProvider, error) {
+	switch provider {
+	case WEATHER_PROVIDER_OPENMETEO, "":
+		if baseUrl == "" {
+			baseUrl = OPENMETEO_API_URL
+		}
+		return &OpenMeteo{BaseUrl: baseUrl}, nil
+	case WEATHER_PROVIDER_QWEATHER:
+		if key == "" {
+			return nil, errors.New("请配置weatherKey")
+		}
+		if baseUrl == "" {
+			baseUrl = QWEATHER_API_URL
+		}
+		return &QWeather{BaseUrl: baseUrl, Key: key}, nil
+	default:
+		return nil, fmt.Errorf("不支持的weatherProvider: %s", provider)
+	}
+}
+
+var weatherHttpClient = &http.Client{Timeout: 4 * time.Second}
+
+func getWeatherJson(rawUrl string, v any) error {
+	resp, err := weatherHttpClient.Get(rawUrl)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("weather request failed with status code %d: %s", resp.StatusCode, string(data))
+	}
+	return json.Unmarshal(data, v)
+}
+
+// OpenMeteo https://open-meteo.com 免费接口，不需要key
+type OpenMeteo struct {
+	BaseUrl string
+}
+
+// WMO天气代码
+var wmoCodes = map[int]string{
+	0: "晴", 1: "晴间多云", 2: "多云", 3: "阴", 45: "雾", 48: "冻雾",
+	51: "小毛毛雨", 53: "毛毛雨", 55: "大毛毛雨", 56: "冻毛毛雨", 57: "冻毛毛雨",
+	61: "小雨", 63: "中雨", 65: "大雨", 66: "冻雨", 67: "冻雨",
+	71: "小雪", 73: "中雪", 75: "大雪", 77: "雪粒",
+	80: "阵雨", 81: "阵雨", 82: "强阵雨", 85: "阵雪", 86: "强阵雪",
+	95: "雷阵雨", 96: "雷阵雨伴有冰雹", 99: "雷阵雨伴有冰雹",
+}
+
+func wmoText(code int) string {
+	if text, ok := wmoCodes[code]; ok {
+		return text
+	}
+	return "未知"
+}
+
+func (o *OpenMeteo) Weather(loc WeatherLocation) (*Weather, error) {
+	query := url.Values{
+		"latitude":      {strconv.FormatFloat(loc.Lat, 'f', -1, 64)},
+		"longitude":     {strconv.FormatFloat(loc.Lon, 'f', -1, 64)},
+		"current":       {"temperature_2m,relative_humidity_2m,apparent_temperature,weather_code,wind_speed_10m"},
+		"daily":         {"weather_code,temperature_2m_max,temperature_2m_min"},
+		"timezone":      {"auto"},
+		"forecast_days": {"3"},
+	}
+	var result struct {
+		Current struct {
+			Temperature float64 `json:"temperature_2m"`
+			Humidity    int     `json:"relative_humidity_2m"`
+			Apparent    float64 `json:"apparent_temperature"`
+			WeatherCode int     `json:"weather_code"`
+			WindSpeed   float64 `json:"wind_speed_10m"`
+		} `json:"current"`
+		Daily struct {
+			Time        []string  `json:"time"`
+			WeatherCode []int     `json:"weather_code"`
+			TempMax     []float64 `json:"temperature_2m_max"`
+			TempMin     []float64 `json:"temperature_2m_min"`
+		} `json:"daily"`
+	}
+	if err := getWeatherJson(o.BaseUrl+"/v1/forecast?"+query.Encode(), &result); err != nil {
+		return nil, err
+	}
+	c := result.Current
+	weather := &Weather{Now: WeatherNow{
+		Text: wmoText(c.WeatherCode), Temp: c.Temperature, FeelsLike: c.Apparent, Humidity: c.Humidity,
+		Wind: fmt.Sprintf("风速%.0fkm/h", c.WindSpeed),
+	}}
+	d := result.Daily
+	for i := range d.Time {
+		if i >= len(d.WeatherCode) || i >= len(d.TempMax) || i >= len(d.TempMin) {
+			break
+		}
+		text := wmoText(d.WeatherCode[i])
+		weather.Days = append(weather.Days, WeatherDay{Date: d.Time[i], TextDay: text, TextNight: text, TempMax: d.TempMax[i], TempMin: d.TempMin[i]})
+	}
+	return weather, nil
+}
+
+// QWeather 和风天气，免费订阅使用 https://devapi.qweather.com
+type QWeather struct {
+	BaseUrl string
+	Key     string
+}
+
+func (q *QWeather) get(path string, loc WeatherLocation, v any) error {
+	query := url.Values{"location": {loc.Id}, "key": {q.Key}}
+	var code struct {
+		Code string `json:"code"`
+	}
+	var raw json.RawMessage
+	if err := getWeatherJson(q.BaseUrl+path+"?"+query.Encode(), &raw); err != nil {
+		return err
+	}
+	if err := json.Unmarshal(raw, &code); err != nil {
+		return err
+	}
+	if code.Code != "200" {
+		return fmt.Errorf("和风天气接口错误: %s", code.Code)
+	}
+	return json.Unmarshal(raw, v)
+}
+
+func (q *QWeather) Weather(loc WeatherLocation) (*Weather, error) {
+	if loc.Id == "" {
+		return nil, errors.New("和风天气需要城市代码")
+	}
+	var now struct {
+		Now struct {
+			Text      string `json:"text"`
+			Temp      string `json:"temp"`
+			FeelsLike string `json:"feelsLike"`
+			Humidity  string `json:"humidity"`
+			WindDir   string `json:"windDir"`
+			WindScale string `json:"windScale"`
+		} `json:"now"`
+	}
+	if err := q.get("/v7/weather/now", loc, &now); err != nil {
+		return nil, err
+	}
+	var daily struct {
+		Daily []struct {
+			FxDate    string `json:"fxDate"`
+			TextDay   string `json:"textDay"`
+			TextNight string `json:"textNight"`
+			TempMax   string `json:"tempMax"`
+			TempMin   string `json:"tempMin"`
+		} `json:"daily"`
+	}
+	if err := q.get("/v7/weather/3d", loc, &daily); err != nil {
+		return nil, err
+	}
+	n := now.Now
+	weather := &Weather{Now: WeatherNow{
+		Text: n.Text, Temp: parseFloat(n.Temp), FeelsLike: parseFloat(n.FeelsLike), Humidity: int(parseFloat(n.Humidity)),
+		Wind: fmt.Sprintf("%s%s级", n.WindDir, n.WindScale),
+	}}
+	for _, d := range daily.Daily {
+		weather.Days = append(weather.Days, WeatherDay{Date: d.FxDate, TextDay: d.TextDay, TextNight: d.TextNight, TempMax: parseFloat(d.TempMax), TempMin: parseFloat(d.TempMin)})
+	}
+	return weather, nil
+}
+
+func parseFloat(s string) float64 {
+	f, _ := strconv.ParseFloat(s, 64)
+	return f
+}
@@ -0,0 +1,48 @@
+package client
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestWeatherProviders(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		switch r.URL.Path {
+		case "/v1/forecast":
+			if q.Get("latitude") != "39.9" || q.Get("forecast_days") != "3" {
+				t.Errorf("open-meteo query = %v", q)
+			}
+			w.Write([]byte(`{"current":{"temperature_2m":24.4,"relative_humidity_2m":60,"apparent_temperature":25.6,"weather_code":2,"wind_speed_10m":10.2},
+				"daily":{"time":["2024-05-15","2024-05-16"],"weather_code":[2,61],"temperature_2m_max":[28,22.5],"temperature_2m_min":[18,16]}}`))
+		case "/v7/weather/now":
+			if q.Get("key") != "k1" || q.Get("location") != "101010100" {
+				w.Write([]byte(`{"code":"401"}`))
+				return
+			}
+			w.Write([]byte(`{"code":"200","now":{"text":"晴","temp":"25","feelsLike":"26","humidity":"40","windDir":"北风","windScale":"3"}}`))
+		case "/v7/weather/3d":
+			w.Write([]byte(`{"code":"200","daily":[{"fxDate":"2024-05-15","textDay":"晴","textNight":"多云","tempMax":"30","tempMin":"19"}]}`))
+		}
+	}))
+	defer server.Close()
+
+	provider, _ := NewWeatherProvider("", server.URL, "")
+	w, err := provider.Weather(WeatherLocation{Lat: 39.9, Lon: 116.41})
+	if err != nil || w.Now.Text != "多云" || w.Now.Temp != 24.4 || len(w.Days) != 2 || w.Days[1].TextDay != "小雨" {
+		t.Errorf("open-meteo = %+v, %v", w, err)
+	}
+
+	if _, err = NewWeatherProvider(WEATHER_PROVIDER_QWEATHER, "", ""); err == nil {
+		t.Error("qweather without key should fail")
+	}
+	provider, _ = NewWeatherProvider(WEATHER_PROVIDER_QWEATHER, server.URL, "k1")
+	w, err = provider.Weather(WeatherLocation{Id: "101010100"})
+	if err != nil || w.Now.Wind != "北风3级" || w.Now.Humidity != 40 || w.Days[0].TextNight != "多云" || w.Days[0].TempMax != 30 {
+		t.Errorf("qweather = %+v, %v", w, err)
+	}
+	if _, err = provider.Weather(WeatherLocation{Id: "1"}); err == nil || err.Error() != "和风天气接口错误: 401" {
+		t.Errorf("qweather error = %v", err)
+	}
+}
@@ -0,0 +1,193 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/charset"
+)
+
+// 正文最多保留的字符数，避免超出模型上下文
+const MAX_PAGE_CONTENT = 8000
+
+const pageUserAgent = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0 Safari/537.36"
+
+type WebPage struct {
+	Url     string
+	Title   string
+	Author  string
+	Content string
+}
+
+// FetchWebPage 抓取网页并提取标题和正文，只能访问公网地址
+func FetchWebPage(pageUrl string) (*WebPage, error) {
+	u, err := url.Parse(pageUrl)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return nil, fmt.Errorf("不支持的链接: %s", pageUrl)
+	}
+	req, err := http.NewRequest(http.MethodGet, pageUrl, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", pageUserAgent)
+	resp, err := FetchHttpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("打开链接失败，状态码: %d", resp.StatusCode)
+	}
+	if contentType := resp.Header.Get("Content-Type"); contentType != "" && !strings.Contains(contentType, "html") && !strings.HasPrefix(contentType, "text/") {
+		return nil, fmt.Errorf("不支持的网页类型: %s", contentType)
+	}
+	body, err := charset.NewReader(resp.Body, resp.Header.Get("Content-Type"))
+	if err != nil {
+		return nil, err
+	}
+	return ParseWebPage(pageUrl, body)
+}
+
+// ParseWebPage 从html中提取标题和正文，微信公众号文章使用其固定的页面结构
+func ParseWebPage(pageUrl string, r io.Reader) (*WebPage, error) {
+	doc, err := html.Parse(r)
+	if err != nil {
+		return nil, err
+	}
+	page := &WebPage{Url: pageUrl}
+	if article := findById(doc, "js_content"); article != nil {
+		// 公众号文章: 标题 #activity-name，公众号名称 #js_name，正文 #js_content
+		page.Title = nodeText(findById(doc, "activity-name"))
+		page.Author = nodeText(findById(doc, "js_name"))
+		page.Content = nodeText(article)
+	} else {
+		page.Title = nodeText(findByTag(doc, "title"))
+		page.Content = nodeText(mainNode(doc))
+	}
+	if page.Title == "" {
+		page.Title = metaContent(doc, "og:title")
+	}
+	if page.Content == "" {
+		page.Content = metaContent(doc, "og:description")
+	}
+	if page.Content == "" {
+		page.Content = metaContent(doc, "description")
+	}
+	if page.Content == "" {
+		return nil, errors.New("没有在网页中找到正文内容")
+	}
+	if content := []rune(page.Content); len(content) > MAX_PAGE_CONTENT {
+		page.Content = string(content[:MAX_PAGE_CONTENT])
+	}
+	return page, nil
+}
+
+// mainNode 优先使用article、main标签，没有时使用body
+func mainNode(doc *html.Node) *html.Node {
+	for _, tag := range []string{"article", "main", "body"} {
+		if n := findByTag(doc, tag); n != nil {
+			return n
+		}
+	}
+	return doc
+}
+
+func findNode(n *html.Node, match func(n *html.Node) bool) *html.Node {
+	if n == nil {
+		return nil
+	}
+	if match(n) {
+		return n
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if r := findNode(c, match); r != nil {
+			return r
+		}
+	}
+	return nil
+}
+
+func findById(n *html.Node, id string) *html.Node {
+	return findNode(n, func(n *html.Node) bool {
+		return n.Type == html.ElementNode && attr(n, "id") == id
+	})
+}
+
+func findByTag(n *html.Node, tag string) *html.Node {
+	return findNode(n, func(n *html.Node) bool {
+		return n.Type == html.ElementNode && n.Data == tag
+	})
+}
+
+func metaContent(doc *html.Node, name string) string {
+	n := findNode(doc, func(n *html.Node) bool {
+		return n.Type == html.ElementNode && n.Data == "meta" && (attr(n, "property") == name || attr(n, "name") == name)
+	})
+	if n == nil {
+		return ""
+	}
+	return strings.TrimSpace(attr(n, "content"))
+}
+
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+// 不属于正文的标签
+var skipTags = map[string]bool{
+	"script": true, "style": true, "noscript": true, "iframe": true, "svg": true,
+	"nav": true, "header": true, "footer": true, "aside": true, "form": true, "button": true,
+}
+
+// 块级标签前后换行，保留段落结构
+var blockTags = map[string]bool{
+	"p": true, "div": true, "section": true, "br": true, "li": true, "tr": true,
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+	"blockquote": true, "pre": true, "article": true, "table": true,
+}
+
+var (
+	spaceRegex   = regexp.MustCompile(`[ \t\r\f\v\x{00a0}\x{3000}]+`)
+	newlineRegex = regexp.MustCompile(`\s*\n\s*`)
+)
+
+func nodeText(n *html.Node) string {
+	if n == nil {
+		return ""
+	}
+	var sb strings.Builder
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		switch n.Type {
+		case html.TextNode:
+			sb.WriteString(n.Data)
+			return
+		case html.ElementNode:
+			if skipTags[n.Data] {
+				return
+			}
+			if blockTags[n.Data] {
+				sb.WriteString("\n")
+				defer sb.WriteString("\n")
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(n)
+	text := spaceRegex.ReplaceAllString(sb.String(), " ")
+	text = newlineRegex.ReplaceAllString(text, "\n")
+	return strings.TrimSpace(text)
+}
@@ -0,0 +1,119 @@
+package client
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"golang.org/x/text/encoding/simplifiedchinese"
+)
+
+const wechatArticle = `<html><head><title></title>
+<meta property="og:title" content="og标题">
+<script>var msg_title = "x";</script></head>
+<body><div id="img-content">
+<h1 class="rich_media_title" id="activity-name">
+    Go 并发入门
+</h1>
+<div class="rich_media_meta_list"><a id="js_name">  Gopher日报 </a></div>
+<div class="rich_media_content" id="js_content" style="visibility: hidden;">
+<section><p>goroutine 是轻量级线程。</p><p>channel&nbsp;用于通信。</p></section>
+<script>alert(1)</script>
+</div></div></body></html>`
+
+func TestParseWebPageWechat(t *testing.T) {
+	page, err := ParseWebPage("https://mp.weixin.qq.com/s/abc", strings.NewReader(wechatArticle))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.Title != "Go 并发入门" || page.Author != "Gopher日报" {
+		t.Errorf("title=%q author=%q", page.Title, page.Author)
+	}
+	if page.Content != "goroutine 是轻量级线程。\nchannel 用于通信。" {
+		t.Errorf("content=%q", page.Content)
+	}
+}
+
+func TestParseWebPageGeneric(t *testing.T) {
+	doc := `<html><head><title>博客</title><style>p{}</style></head><body>
+<nav>首页 | 关于</nav><article><h2>标题</h2><p>第一段</p><p>第二段</p></article><footer>版权</footer></body></html>`
+	page, err := ParseWebPage("https://example.com", strings.NewReader(doc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.Title != "博客" || page.Content != "标题\n第一段\n第二段" {
+		t.Errorf("title=%q content=%q", page.Title, page.Content)
+	}
+
+	if _, err = ParseWebPage("https://example.com", strings.NewReader("<html><body><script>1</script></body></html>")); err == nil {
+		t.Error("expected error for empty page")
+	}
+}
+
+// allowLocalFetch 测试服务器监听在本机，测试期间不检查地址
+func allowLocalFetch(t *testing.T) {
+	t.Helper()
+	guarded := FetchHttpClient
+	FetchHttpClient = &http.Client{}
+	t.Cleanup(func() { FetchHttpClient = guarded })
+}
+
+func TestGuardedHttpClient(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("<html><title>内网</title></html>"))
+	}))
+	defer server.Close()
+	if _, err := FetchWebPage(server.URL); !errors.Is(err, ErrPrivateAddr) {
+		t.Errorf("loopback: %v", err)
+	}
+	for _, ip := range []string{"127.0.0.1", "10.0.0.1", "172.16.0.1", "192.168.1.1", "169.254.169.254", "100.100.100.200", "0.0.0.0", "::1", "fe80::1", "fd00::1", "::ffff:127.0.0.1"} {
+		if IsPublicIP(net.ParseIP(ip)) {
+			t.Errorf("%s should not be public", ip)
+		}
+	}
+	for _, ip := range []string{"1.1.1.1", "2400:3200::1"} {
+		if !IsPublicIP(net.ParseIP(ip)) {
+			t.Errorf("%s should be public", ip)
+		}
+	}
+	redirect, _ := http.NewRequest(http.MethodGet, "file:///etc/passwd", nil)
+	if err := checkRedirect(redirect, nil); err == nil {
+		t.Error("redirect to file scheme should fail")
+	}
+}
+
+func TestFetchWebPage(t *testing.T) {
+	allowLocalFetch(t)
+	gbk, _ := simplifiedchinese.GBK.NewEncoder().String("<html><head><title>中文标题</title></head><body><p>正文内容</p></body></html>")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/gbk":
+			w.Header().Set("Content-Type", "text/html; charset=gbk")
+			w.Write([]byte(gbk))
+		case "/pdf":
+			w.Header().Set("Content-Type", "application/pdf")
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	page, err := FetchWebPage(server.URL + "/gbk")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.Title != "中文标题" || page.Content != "正文内容" {
+		t.Errorf("title=%q content=%q", page.Title, page.Content)
+	}
+	for _, path := range []string{"/pdf", "/404"} {
+		if _, err = FetchWebPage(server.URL + path); err == nil {
+			t.Errorf("%s: expected error", path)
+		}
+	}
+	if _, err = FetchWebPage("ftp://example.com"); err == nil {
+		t.Error("expected error for unsupported scheme")
+	}
+}
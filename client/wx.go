@@ -0,0 +1,36 @@
+package client
+
+import (
+	"errors"
+
+	"github.com/silenceper/wechat/v2"
+	"github.com/silenceper/wechat/v2/cache"
+	oaConfig "github.com/silenceper/wechat/v2/officialaccount/config"
+	"github.com/silenceper/wechat/v2/officialaccount/message"
+)
+
+// Messenger 主动给用户发送消息
+type Messenger interface {
+	SendText(openId, content string) error
+}
+
+// WxMessenger 使用公众号客服消息接口，只能发给48小时内与公众号互动过的用户
+type WxMessenger struct {
+	manager *message.Manager
+}
+
+func NewWxMessenger(appId, appSecret string) (*WxMessenger, error) {
+	if appId == "" || appSecret == "" {
+		return nil, errors.New("主动发送消息需要配置WX_APP_ID和WX_APP_SECRET")
+	}
+	oa := wechat.NewWechat().GetOfficialAccount(&oaConfig.Config{
+		AppID:     appId,
+		AppSecret: appSecret,
+		Cache:     cache.NewMemory(),
+	})
+	return &WxMessenger{manager: oa.GetCustomerMessageManager()}, nil
+}
+
+func (m *WxMessenger) SendText(openId, content string) error {
+	return m.manager.Send(message.NewCustomerTextMessage(openId, content))
+}
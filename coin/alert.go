@@ -0,0 +1,173 @@
+package coin
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/bytedance/sonic"
+	"github.com/pwh-pwh/aiwechat-vercel/db"
+)
+
+const (
+	OP_ABOVE = ">"
+	OP_BELOW = "<"
+
+	// 每个用户最多的价格提醒数量
+	Max_Alerts = 10
+)
+
+// Alert 价格提醒，价格高于或低于Price时提醒一次后删除
+type Alert struct {
+	Id      int
+	UserId  string
+	Symbol  string
+	Op      string
+	Price   float64
+	Created int64
+}
+
+var alertRegex = regexp.MustCompile(`^\s*(\S+?)\s*(>=|<=|>|<|＞|＜|高于|低于|涨到|跌到)\s*([\d.]+)\s*$`)
+
+// ParseAlert 解析 BTC > 70000、eth<3000、比特币 涨到 80000 这样的条件
+func ParseAlert(text string) (symbol, op string, price float64, err error) {
+	m := alertRegex.FindStringSubmatch(text)
+	if m == nil {
+		return "", "", 0, errors.New("格式不正确，例如：BTC > 70000、ETH < 3000")
+	}
+	switch m[2] {
+	case ">", ">=", "＞", "高于", "涨到":
+		op = OP_ABOVE
+	default:
+		op = OP_BELOW
+	}
+	price, err = strconv.ParseFloat(m[3], 64)
+	if err != nil || price <= 0 {
+		return "", "", 0, errors.New("价格必须是大于0的数字")
+	}
+	return NormalizeSymbol(m[1]), op, price, nil
+}
+
+// AddAlert 添加价格提醒
+func AddAlert(userId, symbol, op string, price float64) (*Alert, error) {
+	alerts, err := Alerts(userId)
+	if err != nil {
+		return nil, err
+	}
+	if len(alerts) >= Max_Alerts {
+		return nil, fmt.Errorf("最多只能设置%d个价格提醒", Max_Alerts)
+	}
+	id := 0
+	for _, a := range alerts {
+		id = max(id, a.Id)
+	}
+	alert := &Alert{Id: id + 1, UserId: userId, Symbol: symbol, Op: op, Price: price, Created: time.Now().Unix()}
+	data, err := sonic.MarshalString(alert)
+	if err != nil {
+		return nil, err
+	}
+	return alert, db.SetPriceAlert(alert.field(), data)
+}
+
+// AllAlerts 所有用户的价格提醒，按用户和id排序
+func AllAlerts() ([]*Alert, error) {
+	values, err := db.GetPriceAlerts()
+	if err != nil {
+		return nil, err
+	}
+	alerts := make([]*Alert, 0, len(values))
+	for field, value := range values {
+		var a Alert
+		if err := sonic.UnmarshalString(value, &a); err != nil {
+			fmt.Printf("price alert %s unmarshal error: %v\n", field, err)
+			continue
+		}
+		alerts = append(alerts, &a)
+	}
+	sort.Slice(alerts, func(i, j int) bool {
+		if alerts[i].UserId != alerts[j].UserId {
+			return alerts[i].UserId < alerts[j].UserId
+		}
+		return alerts[i].Id < alerts[j].Id
+	})
+	return alerts, nil
+}
+
+// Alerts 用户的价格提醒
+func Alerts(userId string) ([]*Alert, error) {
+	all, err := AllAlerts()
+	if err != nil {
+		return nil, err
+	}
+	var alerts []*Alert
+	for _, a := range all {
+		if a.UserId == userId {
+			alerts = append(alerts, a)
+		}
+	}
+	return alerts, nil
+}
+
+func DeleteAlert(userId string, id int) (*Alert, error) {
+	alerts, err := Alerts(userId)
+	if err != nil {
+		return nil, err
+	}
+	for _, a := range alerts {
+		if a.Id == id {
+			return a, db.DeletePriceAlert(a.field())
+		}
+	}
+	return nil, fmt.Errorf("价格提醒#%d不存在", id)
+}
+
+// Symbols 提醒中的交易对，去重后排序
+func Symbols(alerts []*Alert) []string {
+	var symbols []string
+	seen := map[string]bool{}
+	for _, a := range alerts {
+		if !seen[a.Symbol] {
+			seen[a.Symbol] = true
+			symbols = append(symbols, a.Symbol)
+		}
+	}
+	sort.Strings(symbols)
+	return symbols
+}
+
+// Triggered 返回价格满足条件的提醒
+func Triggered(alerts []*Alert, prices map[string]float64) []*Alert {
+	var triggered []*Alert
+	for _, a := range alerts {
+		price, ok := prices[a.Symbol]
+		if ok && ((a.Op == OP_ABOVE && price >= a.Price) || (a.Op == OP_BELOW && price <= a.Price)) {
+			triggered = append(triggered, a)
+		}
+	}
+	return triggered
+}
+
+// Fired 提醒发送后删除
+func Fired(a *Alert) error {
+	return db.DeletePriceAlert(a.field())
+}
+
+func (a *Alert) field() string {
+	return fmt.Sprintf("%s:%d", a.UserId, a.Id)
+}
+
+// String 列表中显示的一行，如 #1 BTCUSDT > 70000
+func (a *Alert) String() string {
+	return fmt.Sprintf("#%d %s %s %s", a.Id, a.Symbol, a.Op, strconv.FormatFloat(a.Price, 'f', -1, 64))
+}
+
+// Condition 提醒条件的中文描述
+func (a *Alert) Condition() string {
+	if a.Op == OP_ABOVE {
+		return "高于"
+	}
+	return "低于"
+}
@@ -0,0 +1,74 @@
+package coin
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNormalizeSymbol(t *testing.T) {
+	for in, want := range map[string]string{
+		"btc": "BTCUSDT", "比特币": "BTCUSDT", "BTCUSDT": "BTCUSDT", "eth/btc": "ETHBTC",
+		"sol-usdc": "SOLUSDC", "bnb": "BNBUSDT", "wbtc": "WBTCUSDT", " doge ": "DOGEUSDT",
+	} {
+		if got := NormalizeSymbol(in); got != want {
+			t.Errorf("NormalizeSymbol(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestFormat(t *testing.T) {
+	if s := FormatNumber("67000.10000000"); s != "67000.1" {
+		t.Errorf("FormatNumber = %q", s)
+	}
+	if s := FormatAmount("827123456.5"); s != "8.27亿" {
+		t.Errorf("FormatAmount = %q", s)
+	}
+	if s := FormatPercent("1.5"); s != "+1.50%" {
+		t.Errorf("FormatPercent = %q", s)
+	}
+}
+
+func TestAlert(t *testing.T) {
+	t.Cleanup(func() {
+		for _, id := range []int{1, 2} {
+			DeleteAlert("coin-u1", id)
+		}
+	})
+	tests := map[string]string{"BTC > 70000": "BTCUSDT > 70000", "eth<3000.5": "ETHUSDT < 3000.5", "比特币 跌到 60000": "BTCUSDT < 60000"}
+	for in, want := range tests {
+		symbol, op, price, err := ParseAlert(in)
+		if a := (&Alert{Symbol: symbol, Op: op, Price: price}); err != nil || strings.TrimPrefix(a.String(), "#0 ") != want {
+			t.Errorf("ParseAlert(%q) = %s, %v", in, a, err)
+		}
+	}
+	if _, _, _, err := ParseAlert("BTC 70000"); err == nil {
+		t.Error("missing operator should fail")
+	}
+
+	AddAlert("coin-u1", "BTCUSDT", OP_ABOVE, 70000)
+	AddAlert("coin-u1", "ETHUSDT", OP_BELOW, 3000)
+	alerts, err := AllAlerts()
+	if err != nil || len(alerts) != 2 || strings.Join(Symbols(alerts), ",") != "BTCUSDT,ETHUSDT" {
+		t.Fatalf("AllAlerts = %v, %v", alerts, err)
+	}
+	triggered := Triggered(alerts, map[string]float64{"BTCUSDT": 70000, "ETHUSDT": 3100})
+	if len(triggered) != 1 || triggered[0].Symbol != "BTCUSDT" {
+		t.Errorf("Triggered = %v", triggered)
+	}
+	Fired(triggered[0])
+	if alerts, _ = Alerts("coin-u1"); len(alerts) != 1 || alerts[0].Id != 2 {
+		t.Errorf("after fired = %v", alerts)
+	}
+}
+
+func TestWatch(t *testing.T) {
+	t.Cleanup(func() { Unwatch("coin-u2", "BTCUSDT", "ETHUSDT", "SOLUSDT") })
+	Watch("coin-u2", "BTCUSDT", "ETHUSDT")
+	list, err := Watch("coin-u2", "ETHUSDT", "SOLUSDT")
+	if err != nil || strings.Join(list, ",") != "BTCUSDT,ETHUSDT,SOLUSDT" {
+		t.Errorf("Watch = %v, %v", list, err)
+	}
+	if list, _ = Unwatch("coin-u2", "ETHUSDT"); strings.Join(list, ",") != "BTCUSDT,SOLUSDT" {
+		t.Errorf("Unwatch = %v", list)
+	}
+}
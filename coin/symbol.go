@@ -0,0 +1,72 @@
+package coin
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// 常用币种的别名
+var aliases = map[string]string{
+	"比特币": "BTC", "大饼": "BTC", "以太坊": "ETH", "以太": "ETH", "姨太": "ETH",
+	"币安币": "BNB", "狗狗币": "DOGE", "狗狗": "DOGE", "瑞波币": "XRP", "莱特币": "LTC",
+	"索拉纳": "SOL", "波场": "TRX", "艾达币": "ADA", "柚子": "EOS",
+}
+
+// 计价币种，交易对以这些结尾时不再补全
+var quotes = []string{"USDT", "FDUSD", "USDC", "BUSD", "TUSD", "BTC", "ETH", "BNB", "EUR", "TRY", "BRL"}
+
+// Default_Quote 只写币种时的计价币种
+const Default_Quote = "USDT"
+
+// NormalizeSymbol 把 btc、比特币、btc/usdt、eth-btc 等写法转换为币安交易对，如 BTCUSDT、ETHBTC
+func NormalizeSymbol(s string) string {
+	s = strings.TrimSpace(s)
+	if alias, ok := aliases[s]; ok {
+		return alias + Default_Quote
+	}
+	s = strings.ToUpper(strings.NewReplacer("/", "", "-", "", "_", "", " ", "").Replace(s))
+	if s == "" {
+		return ""
+	}
+	for _, quote := range quotes {
+		// 基础币种至少两个字母，避免把WBTC当成W/BTC
+		if base, ok := strings.CutSuffix(s, quote); ok && len(base) >= 2 {
+			return s
+		}
+	}
+	return s + Default_Quote
+}
+
+// FormatNumber 去掉币安返回的数字末尾多余的0，如 67000.10000000 显示为 67000.1
+func FormatNumber(s string) string {
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return s
+	}
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+// FormatAmount 大额数字用万、亿显示
+func FormatAmount(s string) string {
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return s
+	}
+	switch {
+	case f >= 1e8:
+		return fmt.Sprintf("%.2f亿", f/1e8)
+	case f >= 1e4:
+		return fmt.Sprintf("%.2f万", f/1e4)
+	}
+	return strconv.FormatFloat(f, 'f', 2, 64)
+}
+
+// FormatPercent 涨跌幅，上涨时带+号
+func FormatPercent(s string) string {
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return s + "%"
+	}
+	return fmt.Sprintf("%+.2f%%", f)
+}
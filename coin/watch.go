@@ -0,0 +1,48 @@
+package coin
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/pwh-pwh/aiwechat-vercel/db"
+)
+
+// 每个用户最多的自选交易对数量
+const Max_Watch = 20
+
+// WatchList 用户的自选交易对，按添加顺序
+func WatchList(userId string) ([]string, error) {
+	val, err := db.GetWatchList(userId)
+	if err != nil || val == "" {
+		return nil, err
+	}
+	return strings.Split(val, ","), nil
+}
+
+// Watch 添加自选，已存在的交易对忽略，返回添加后的列表
+func Watch(userId string, symbols ...string) ([]string, error) {
+	list, err := WatchList(userId)
+	if err != nil {
+		return nil, err
+	}
+	for _, symbol := range symbols {
+		if !slices.Contains(list, symbol) {
+			list = append(list, symbol)
+		}
+	}
+	if len(list) > Max_Watch {
+		return nil, fmt.Errorf("最多只能添加%d个自选", Max_Watch)
+	}
+	return list, db.SetWatchList(userId, strings.Join(list, ","))
+}
+
+// Unwatch 删除自选，返回删除后的列表
+func Unwatch(userId string, symbols ...string) ([]string, error) {
+	list, err := WatchList(userId)
+	if err != nil {
+		return nil, err
+	}
+	list = slices.DeleteFunc(list, func(s string) bool { return slices.Contains(symbols, s) })
+	return list, db.SetWatchList(userId, strings.Join(list, ","))
+}
@@ -0,0 +1,12 @@
+package config
+
+import "os"
+
+const (
+	Binance_Url_Key = "binanceUrl"
+)
+
+// GetBinanceUrl 币安行情接口地址，为空时使用默认地址
+func GetBinanceUrl() string {
+	return os.Getenv(Binance_Url_Key)
+}
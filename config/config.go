@@ -21,6 +21,8 @@ const (
 	Bot_Type_Spark  = "spark"
 	Bot_Type_Qwen   = "qwen"
 	Bot_Type_Gemini = "gemini"
+
+	Tools_Enable_Key = "enableTools"
 )
 
 var (
@@ -129,4 +131,10 @@ func GetMaxTokens() int {
 		return 0
 	}
 	return maxTokens
-}
\ No newline at end of file
+}
+
+// 是否允许模型调用工具(查币价、待办、记账等)，需要模型本身支持function calling
+func IsToolsEnabled() bool {
+	enable, _ := strconv.ParseBool(os.Getenv(Tools_Enable_Key))
+	return enable
+}
@@ -0,0 +1,12 @@
+package config
+
+import "os"
+
+const (
+	Cron_Secret_Key = "CRON_SECRET"
+)
+
+// 定时任务接口的密钥，vercel cron会在请求头中带上 Authorization: Bearer CRON_SECRET
+func GetCronSecret() string {
+	return os.Getenv(Cron_Secret_Key)
+}
@@ -5,6 +5,7 @@ import "os"
 const (
 	Gemini_Welcome_Reply_Key = "geminiWelcomeReply"
 	Gemini_Key               = "geminiKey"
+	Gemini_Safety_Key        = "geminiSafety"
 )
 
 func GetGeminiWelcomeReply() (r string) {
@@ -18,3 +19,8 @@ func GetGeminiWelcomeReply() (r string) {
 func GetGeminiKey() string {
 	return os.Getenv(Gemini_Key)
 }
+
+// 安全设置，例如 BLOCK_NONE 或 HARASSMENT=BLOCK_ONLY_HIGH,DANGEROUS_CONTENT=BLOCK_NONE，不设置时使用gemini默认值
+func GetGeminiSafety() string {
+	return os.Getenv(Gemini_Safety_Key)
+}
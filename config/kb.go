@@ -0,0 +1,48 @@
+package config
+
+import (
+	"os"
+	"strconv"
+)
+
+const (
+	Kb_Embedding_Url_Key   = "kbEmbeddingUrl"
+	Kb_Embedding_Token_Key = "kbEmbeddingToken"
+	Kb_Embedding_Model_Key = "kbEmbeddingModel"
+	Kb_Top_K_Key           = "kbTopK"
+)
+
+// 向量接口地址，未配置时使用GPT_URL
+func GetKbEmbeddingUrl() string {
+	if url := os.Getenv(Kb_Embedding_Url_Key); url != "" {
+		return url
+	}
+	if url := os.Getenv("GPT_URL"); url != "" {
+		return url
+	}
+	return "https://api.openai.com/v1/"
+}
+
+// 向量接口token，未配置时使用GPT_TOKEN，都为空时知识库只使用BM25关键词检索
+func GetKbEmbeddingToken() string {
+	if token := os.Getenv(Kb_Embedding_Token_Key); token != "" {
+		return token
+	}
+	return GetGptToken()
+}
+
+func GetKbEmbeddingModel() string {
+	if model := os.Getenv(Kb_Embedding_Model_Key); model != "" {
+		return model
+	}
+	return "text-embedding-3-small"
+}
+
+// 每次对话从知识库中检索的片段数，默认3条
+func GetKbTopK() int {
+	k, err := strconv.Atoi(os.Getenv(Kb_Top_K_Key))
+	if err != nil || k <= 0 {
+		return 3
+	}
+	return k
+}
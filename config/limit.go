@@ -0,0 +1,48 @@
+package config
+
+import (
+	"os"
+	"strings"
+)
+
+const (
+	Rate_Limit_User_Key   = "rateLimitUser"
+	Rate_Limit_Admin_Key  = "rateLimitAdmin"
+	Rate_Limit_Global_Key = "rateLimitGlobal"
+	Rate_Limit_Bot_Key    = "rateLimitBot"
+)
+
+// GetRateLimit 角色对应的每用户限流规则，格式为 次数/时间窗口，多条用英文逗号分隔，如 10/1m,200/1d
+// 未配置或配置为off时不限流
+func GetRateLimit(role string) string {
+	switch role {
+	case "admin":
+		return rateLimitValue(os.Getenv(Rate_Limit_Admin_Key))
+	case "user":
+		return rateLimitValue(os.Getenv(Rate_Limit_User_Key))
+	}
+	return ""
+}
+
+// GetGlobalRateLimit 所有用户合计的限流规则，未配置时不限流
+func GetGlobalRateLimit() string {
+	return rateLimitValue(os.Getenv(Rate_Limit_Global_Key))
+}
+
+// GetBotRateLimit 每个用户使用某个bot的限流规则，格式为 bot=规则;bot=规则，如 gpt=5/1m;gemini=20/1m,300/1d
+func GetBotRateLimit(botType string) string {
+	for _, item := range strings.Split(os.Getenv(Rate_Limit_Bot_Key), ";") {
+		bot, rules, ok := strings.Cut(item, "=")
+		if ok && strings.TrimSpace(bot) == botType {
+			return rateLimitValue(strings.TrimSpace(rules))
+		}
+	}
+	return ""
+}
+
+func rateLimitValue(v string) string {
+	if strings.EqualFold(v, "off") {
+		return ""
+	}
+	return v
+}
@@ -0,0 +1,56 @@
+package config
+
+import (
+	"os"
+	"strings"
+)
+
+const (
+	Moderation_Words_Key     = "moderationWords"
+	Moderation_Words_Url_Key = "moderationWordsUrl"
+	Moderation_Actions_Key   = "moderationActions"
+	Moderation_Api_Url_Key   = "moderationApiUrl"
+	Moderation_Api_Token_Key = "moderationApiToken"
+	Moderation_Api_Model_Key = "moderationApiModel"
+)
+
+// 敏感词，格式为 类别:词,词;类别:词，不写类别时为默认类别
+func GetModerationWords() string {
+	return os.Getenv(Moderation_Words_Key)
+}
+
+// 敏感词文件链接，每行一个词，可以在词后用空格或tab分隔写类别
+func GetModerationWordsUrl() string {
+	return os.Getenv(Moderation_Words_Url_Key)
+}
+
+// GetModerationAction 类别对应的处理方式，格式为 类别=block|mask|warn;类别=...，未配置时返回空
+func GetModerationAction(category string) string {
+	for _, item := range strings.Split(os.Getenv(Moderation_Actions_Key), ";") {
+		name, action, ok := strings.Cut(item, "=")
+		if ok && strings.TrimSpace(name) == category {
+			return strings.TrimSpace(action)
+		}
+	}
+	return ""
+}
+
+// 审核接口地址，openai兼容的/moderations接口，未配置时不调用
+func GetModerationApiUrl() string {
+	return os.Getenv(Moderation_Api_Url_Key)
+}
+
+// 审核接口token，未配置时使用GPT_TOKEN
+func GetModerationApiToken() string {
+	if token := os.Getenv(Moderation_Api_Token_Key); token != "" {
+		return token
+	}
+	return os.Getenv("GPT_TOKEN")
+}
+
+func GetModerationApiModel() string {
+	if model := os.Getenv(Moderation_Api_Model_Key); model != "" {
+		return model
+	}
+	return "omni-moderation-latest"
+}
@@ -0,0 +1,36 @@
+package config
+
+import "os"
+
+const (
+	Notion_Api_Key_Key            = "NOTION_API_KEY"
+	Notion_Config_Database_Id_Key = "NOTION_CONFIG_DATABASE_ID"
+	Notion_Url_Key                = "notionUrl"
+	Ledger_Bot_Key                = "ledgerBot"
+	Ledger_Model_Key              = "ledgerModel"
+)
+
+// GetNotionApiKey 读写记账配置数据库使用的key，用户的记账数据库使用各自绑定的key
+func GetNotionApiKey() string {
+	return os.Getenv(Notion_Api_Key_Key)
+}
+
+// GetNotionConfigDatabaseId 保存用户绑定的记账数据库的配置数据库
+func GetNotionConfigDatabaseId() string {
+	return os.Getenv(Notion_Config_Database_Id_Key)
+}
+
+// GetNotionUrl Notion接口地址，为空时使用默认地址
+func GetNotionUrl() string {
+	return os.Getenv(Notion_Url_Key)
+}
+
+// GetLedgerBot 识别记账内容使用的bot，为空时使用用户当前的bot
+func GetLedgerBot() string {
+	return os.Getenv(Ledger_Bot_Key)
+}
+
+// GetLedgerModel 识别记账内容使用的模型，为空时使用bot当前的模型
+func GetLedgerModel() string {
+	return os.Getenv(Ledger_Model_Key)
+}
@@ -3,6 +3,7 @@ package config
 import (
 	"errors"
 	"os"
+	"strconv"
 )
 
 const (
@@ -10,12 +11,29 @@ const (
 	Qwen_ApiKey_Key        = "qwenApiKey"
 	Qwen_Model_Version_Key = "qwenModelVersion"
 	Qwen_Welcome_Reply_Key = "qwenWelcomeReply"
+	Qwen_Mode_Key          = "qwenMode"
+	Qwen_Result_Format_Key = "qwenResultFormat"
+	Qwen_Enable_Search_Key = "qwenEnableSearch"
+
+	// dashscope原生接口 https://dashscope.aliyuncs.com/api/v1/services/aigc/text-generation/generation
+	Qwen_Mode_Dashscope = "dashscope"
+	// dashscope的openai兼容接口 https://dashscope.aliyuncs.com/compatible-mode/v1/chat/completions
+	Qwen_Mode_OpenAI = "openai"
+
+	Qwen_Result_Format_Text    = "text"
+	Qwen_Result_Format_Message = "message"
+
+	Qwen_Default_Dashscope_Url = "https://dashscope.aliyuncs.com/api/v1/services/aigc/text-generation/generation"
+	Qwen_Default_OpenAI_Url    = "https://dashscope.aliyuncs.com/compatible-mode/v1/chat/completions"
 )
 
 type QwenConfig struct {
 	HostUrl      string
 	ApiKey       string
 	ModelVersion string
+	Mode         string
+	ResultFormat string
+	EnableSearch bool
 }
 
 func GetQwenConfig() (cfg *QwenConfig, err error) {
@@ -23,12 +41,18 @@ func GetQwenConfig() (cfg *QwenConfig, err error) {
 		HostUrl:      GetQwenHostUrl(),
 		ApiKey:       GetQwenApiKey(),
 		ModelVersion: GetQwenModelVersion(),
+		Mode:         GetQwenMode(),
+		ResultFormat: GetQwenResultFormat(),
+		EnableSearch: GetQwenEnableSearch(),
 	}
-
-	if cfg.HostUrl == "" {
-		err = errors.New("请配置qwenUrl")
-		return
+	// qwenUrl不填或仍是原生接口的默认地址时按接口模式选择默认地址
+	if cfg.HostUrl == "" || cfg.HostUrl == Qwen_Default_Dashscope_Url {
+		cfg.HostUrl = Qwen_Default_Dashscope_Url
+		if cfg.Mode == Qwen_Mode_OpenAI {
+			cfg.HostUrl = Qwen_Default_OpenAI_Url
+		}
 	}
+
 	if cfg.ApiKey == "" {
 		err = errors.New("请配置qwenApiKey")
 		return
@@ -53,6 +77,27 @@ func GetQwenModelVersion() string {
 	return os.Getenv(Qwen_Model_Version_Key)
 }
 
+// 不设置或者设置不合法时使用dashscope原生接口
+func GetQwenMode() string {
+	if os.Getenv(Qwen_Mode_Key) == Qwen_Mode_OpenAI {
+		return Qwen_Mode_OpenAI
+	}
+	return Qwen_Mode_Dashscope
+}
+
+// 仅dashscope原生接口生效，不设置时为text，多模态模型固定使用message
+func GetQwenResultFormat() string {
+	if os.Getenv(Qwen_Result_Format_Key) == Qwen_Result_Format_Message {
+		return Qwen_Result_Format_Message
+	}
+	return Qwen_Result_Format_Text
+}
+
+func GetQwenEnableSearch() bool {
+	enable, _ := strconv.ParseBool(os.Getenv(Qwen_Enable_Search_Key))
+	return enable
+}
+
 func GetQwenWelcomeReply() (r string) {
 	r = os.Getenv(Qwen_Welcome_Reply_Key)
 	if r == "" {
@@ -0,0 +1,26 @@
+package config
+
+import (
+	"os"
+	"slices"
+	"strings"
+)
+
+const (
+	Admin_Openids_Key = "ADMIN_OPENIDS"
+)
+
+// 管理员openid，多个用英文逗号分隔，这些用户始终是管理员
+func GetAdminOpenIds() []string {
+	var ids []string
+	for _, id := range strings.Split(os.Getenv(Admin_Openids_Key), ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+func IsAdminOpenId(userId string) bool {
+	return slices.Contains(GetAdminOpenIds(), userId)
+}
@@ -0,0 +1,35 @@
+package config
+
+import (
+	"os"
+	"strconv"
+)
+
+const (
+	Search_Provider_Key = "searchProvider"
+	Search_Url_Key      = "searchUrl"
+	Search_ApiKey_Key   = "searchApiKey"
+	Search_Count_Key    = "searchCount"
+)
+
+// 搜索后端，目前支持 bing、searxng
+func GetSearchProvider() string {
+	return os.Getenv(Search_Provider_Key)
+}
+
+func GetSearchUrl() string {
+	return os.Getenv(Search_Url_Key)
+}
+
+func GetSearchApiKey() string {
+	return os.Getenv(Search_ApiKey_Key)
+}
+
+// 注入到提示词中的搜索结果条数，默认5条
+func GetSearchCount() int {
+	count, err := strconv.Atoi(os.Getenv(Search_Count_Key))
+	if err != nil || count <= 0 {
+		return 5
+	}
+	return count
+}
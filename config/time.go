@@ -0,0 +1,28 @@
+package config
+
+import (
+	"os"
+	"time"
+)
+
+const (
+	Time_Zone_Key = "timeZone"
+)
+
+// 默认使用北京时间
+var defaultLocation = time.FixedZone("CST", 8*3600)
+
+// GetLocation 解析待办、提醒等时间使用的时区，vercel等服务器默认为UTC，可以配置为 Asia/Shanghai 等时区名
+func GetLocation() *time.Location {
+	if name := os.Getenv(Time_Zone_Key); name != "" {
+		if loc, err := time.LoadLocation(name); err == nil {
+			return loc
+		}
+	}
+	return defaultLocation
+}
+
+// Now 配置时区的当前时间
+func Now() time.Time {
+	return time.Now().In(GetLocation())
+}
@@ -0,0 +1,28 @@
+package config
+
+import "os"
+
+const (
+	Translate_Provider_Key = "translateProvider"
+	Deepl_Key_Key          = "deeplKey"
+	Deepl_Url_Key          = "deeplUrl"
+
+	Translate_Provider_Bot   = "bot"
+	Translate_Provider_Deepl = "deepl"
+)
+
+// GetTranslateProvider 翻译使用的后端，默认使用用户当前的bot
+func GetTranslateProvider() string {
+	if provider := os.Getenv(Translate_Provider_Key); provider != "" {
+		return provider
+	}
+	return Translate_Provider_Bot
+}
+
+func GetDeeplKey() string {
+	return os.Getenv(Deepl_Key_Key)
+}
+
+func GetDeeplUrl() string {
+	return os.Getenv(Deepl_Url_Key)
+}
@@ -0,0 +1,22 @@
+package config
+
+import "os"
+
+const (
+	Weather_Provider_Key = "weatherProvider"
+	Weather_Url_Key      = "weatherUrl"
+	Weather_Key_Key      = "weatherKey"
+)
+
+// GetWeatherProvider 天气后端，为空时使用open-meteo
+func GetWeatherProvider() string {
+	return os.Getenv(Weather_Provider_Key)
+}
+
+func GetWeatherUrl() string {
+	return os.Getenv(Weather_Url_Key)
+}
+
+func GetWeatherKey() string {
+	return os.Getenv(Weather_Key_Key)
+}
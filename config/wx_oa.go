@@ -31,12 +31,43 @@ const (
 	Wx_Todo_Add  = "/ta"
 	Wx_Todo_Del  = "/td"
 	Wx_Todo_List = "/tl"
+	Wx_Todo_Done = "/tdone"
+	Wx_Todo_Edit = "/tedit"
 
-	Wx_Coin = "/cb"
-)
+	Wx_Remind      = "/remind"
+	Wx_Remind_List = "/rl"
+	Wx_Remind_Del  = "/rd"
+
+	Wx_Coin         = "/cb"
+	Wx_Coin_Watch   = "/watch"
+	Wx_Coin_Unwatch = "/unwatch"
+	Wx_Coin_Alert   = "/alert"
+	Wx_Coin_Unalert = "/unalert"
+
+	Wx_Command_Search     = "/search"
+	Wx_Command_AutoSearch = "/autosearch"
+	Wx_Command_Doc        = "/doc"
+	Wx_Command_Web        = "/web"
+
+	Wx_Translate           = "/fy"
+	Wx_Translate_Mode      = "/fymode"
+	Wx_Translate_Bilingual = "/fybi"
 
-var (
-	Wx_Commands = []string{Wx_Command_Help, Wx_Command_Gpt, Wx_Command_Spark, Wx_Command_Qwen, Wx_Command_Gemini}
+	Wx_Weather = "/wec"
+
+	Wx_Ledger        = "/ledger"
+	Wx_Ledger_Undo   = "/lundo"
+	Wx_Ledger_Recent = "/lrecent"
+	Wx_Ledger_Edit   = "/ledit"
+	Wx_Ledger_Report = "/lstat"
+
+	Wx_Command_Ban       = "/ban"
+	Wx_Command_Unban     = "/unban"
+	Wx_Command_Role      = "/role"
+	Wx_Command_Broadcast = "/broadcast"
+	Wx_Command_Stats     = "/stats"
+	Wx_Command_Violation = "/violations"
+	Wx_Command_Audit     = "/audit"
 )
 
 func GetWxToken() string {
@@ -52,15 +83,10 @@ func GetWxSubscribeReply() string {
 	subscribeMsg := os.Getenv(Wx_Subscribe_Reply_key)
 	return strings.ReplaceAll(subscribeMsg, "\\n", "\n")
 }
+
+// GetWxHelpReply 自定义的帮助内容，未配置时返回空，由指令列表生成帮助
 func GetWxHelpReply() string {
-	helpMsg := os.Getenv(Wx_Help_Reply_key)
-	if helpMsg == "" {
-		helpMsg = "输入以下命令进行对话\n/help：查看帮助\n/gpt：与GPT对话\n/spark：与星火对话\n/qwen：与通义千问对话\n/gemini：与gemini对话\n" +
-			"/prompt 你的prompt: 设置system prompt\n/getpt: 获取当前设置prompt\n/cpt: 清除当前设置prompt\n" +
-			"/setmodel model: 设置自定义model\n/setmodel: 重置model为默认值\n/getmodel: 获取当前model\n" +
-			"/clear:清除历史对话\n" + "/ta 代办事项1:设置todo\n" + "/tl:获取代办列表\n" + "/td 2:删除索引代办事件\n" + "/cb 代币对:查询价格"
-	}
-	return strings.ReplaceAll(helpMsg, "\\n", "\n")
+	return strings.ReplaceAll(os.Getenv(Wx_Help_Reply_key), "\\n", "\n")
 }
 func GetWxEventKeyChatGpt() string {
 	return os.Getenv(Wx_Event_Key_Chat_Gpt_key)
@@ -2,10 +2,12 @@ package db
 
 import (
 	"context"
+	"crypto/sha1"
 	"crypto/tls"
 	"errors"
 	"fmt"
 	"os"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -19,13 +21,33 @@ var (
 	ChatDbInstance ChatDb        = nil
 	RedisClient    *redis.Client = nil
 	Cache          sync.Map
+
+	memoryLock    sync.Mutex
+	memoryLists   = map[string][]string{}
+	memorySets    = map[string]map[string]float64{}
+	memoryExpires = map[string]time.Time{}
 )
 
 const (
-	PROMPT_KEY = "prompt"
-	MSG_KEY    = "msg"
-	MODEL_KEY  = "model"
-	TODO_KEY   = "todo"
+	PROMPT_KEY  = "prompt"
+	MSG_KEY     = "msg"
+	MODEL_KEY   = "model"
+	TODO_KEY    = "todo"
+	SEARCH_KEY  = "autosearch"
+	PAGE_KEY    = "webpage"
+	DOC_KEY     = "doc"
+	KB_KEY      = "kb"
+	RULE_KEY    = "rule"
+	ROLE_KEY    = "role"
+	USER_KEY    = "user"
+	LIMIT_KEY   = "ratelimit"
+	MOD_KEY     = "moderation"
+	DEDUP_KEY   = "dedup"
+	REMIND_KEY  = "remind"
+	COIN_KEY    = "coin"
+	TRANS_KEY   = "translate"
+	WEATHER_KEY = "weather"
+	LEDGER_KEY  = "ledger"
 )
 
 func init() {
@@ -37,9 +59,33 @@ func init() {
 	ChatDbInstance = db
 }
 
+const (
+	MSG_PART_TEXT              = "text"
+	MSG_PART_BLOB              = "blob"
+	MSG_PART_FILE              = "file"
+	MSG_PART_FUNCTION_CALL     = "function_call"
+	MSG_PART_FUNCTION_RESPONSE = "function_response"
+	MSG_PART_CODE              = "executable_code"
+	MSG_PART_CODE_RESULT       = "code_execution_result"
+)
+
+// Msg 中Msg字段保存消息的纯文本，Parts保存包含图片、文件等非文本内容时的完整内容
 type Msg struct {
-	Role string
-	Msg  string
+	Role  string
+	Msg   string
+	Parts []MsgPart `json:",omitempty"`
+}
+
+// MsgPart 按Type使用对应字段：text使用Text，blob使用MimeType和Data，file使用MimeType和Uri，
+// function_call/function_response使用Name和Args，executable_code/code_execution_result使用Text和Name(语言或执行结果)
+type MsgPart struct {
+	Type     string
+	Text     string         `json:",omitempty"`
+	MimeType string         `json:",omitempty"`
+	Data     []byte         `json:",omitempty"`
+	Uri      string         `json:",omitempty"`
+	Name     string         `json:",omitempty"`
+	Args     map[string]any `json:",omitempty"`
 }
 
 type ChatDb interface {
@@ -84,13 +130,18 @@ func (r *RedisChatDb) SetMsgList(botType string, userId string, msgList []Msg) {
 		fmt.Println(err)
 		return
 	}
+	r.client.Set(context.Background(), fmt.Sprintf("%v:%v:%v", MSG_KEY, botType, userId), res, GetMsgTime())
+}
+
+// GetMsgTime 对话记忆时长，默认30分钟
+func GetMsgTime() time.Duration {
 	msgTime := os.Getenv("MSG_TIME")
 	//转换为数字
 	msgT, err := strconv.Atoi(msgTime)
 	if err != nil || msgT <= 0 {
 		msgT = 30
 	}
-	r.client.Set(context.Background(), fmt.Sprintf("%v:%v:%v", MSG_KEY, botType, userId), res, time.Minute*time.Duration(msgT))
+	return time.Minute * time.Duration(msgT)
 }
 
 func GetChatDb() (ChatDb, error) {
@@ -158,6 +209,91 @@ func DeleteKey(key string) {
 	RedisClient.Del(context.Background(), key)
 }
 
+// SetSessionValue 保存有过期时间的会话数据，直接写入redis，不使用内存缓存，
+// 避免其它实例已经删除或过期后仍读到旧值；没有配置redis时保存在内存并记录过期时间
+func SetSessionValue(key string, val string, expires time.Duration) error {
+	if RedisClient == nil {
+		memoryLock.Lock()
+		defer memoryLock.Unlock()
+		SetValueWithMemory(key, val)
+		memoryExpires[key] = time.Now().Add(expires)
+		return nil
+	}
+	return RedisClient.Set(context.Background(), key, val, expires).Err()
+}
+
+// GetSessionValue 读取会话数据，不存在或已过期时返回空字符串
+func GetSessionValue(key string) (string, error) {
+	if RedisClient == nil {
+		memoryLock.Lock()
+		defer memoryLock.Unlock()
+		if time.Now().After(memoryExpires[key]) {
+			DeleteKeyWithMemory(key)
+			delete(memoryExpires, key)
+			return "", nil
+		}
+		val, _ := GetValueWithMemory(key)
+		return val, nil
+	}
+	val, err := RedisClient.Get(context.Background(), key).Result()
+	if err == redis.Nil {
+		return "", nil
+	}
+	return val, err
+}
+
+// TakeSessionValue 读取并删除会话数据，多个实例同时读取时只有一个能拿到
+func TakeSessionValue(key string) (string, error) {
+	if RedisClient == nil {
+		memoryLock.Lock()
+		defer memoryLock.Unlock()
+		val, _ := GetValueWithMemory(key)
+		if time.Now().After(memoryExpires[key]) {
+			val = ""
+		}
+		DeleteKeyWithMemory(key)
+		delete(memoryExpires, key)
+		return val, nil
+	}
+	var get *redis.StringCmd
+	_, err := RedisClient.TxPipelined(context.Background(), func(pipe redis.Pipeliner) error {
+		get = pipe.Get(context.Background(), key)
+		pipe.Del(context.Background(), key)
+		return nil
+	})
+	if err == redis.Nil {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return get.Val(), nil
+}
+
+// ExpireSessionValue 延长会话数据的过期时间
+func ExpireSessionValue(key string, expires time.Duration) {
+	if RedisClient == nil {
+		memoryLock.Lock()
+		defer memoryLock.Unlock()
+		if _, ok := memoryExpires[key]; ok {
+			memoryExpires[key] = time.Now().Add(expires)
+		}
+		return
+	}
+	RedisClient.Expire(context.Background(), key, expires)
+}
+
+func DeleteSessionValue(key string) {
+	if RedisClient == nil {
+		memoryLock.Lock()
+		defer memoryLock.Unlock()
+		DeleteKeyWithMemory(key)
+		delete(memoryExpires, key)
+		return
+	}
+	RedisClient.Del(context.Background(), key)
+}
+
 func DeleteMsgList(botType string, userId string) {
 	RedisClient.Del(context.Background(), fmt.Sprintf("%v:%v:%v", MSG_KEY, botType, userId))
 }
@@ -175,42 +311,190 @@ func RemovePrompt(userId, botType string) {
 }
 
 // todolist format: "todo1|todo2|todo3"
-func GetTodoList(userId string) (string, error) {
-	tListStr, err := GetValue(fmt.Sprintf("%s:%s", TODO_KEY, userId))
-	if err != nil && RedisClient == nil {
-		return "", err
-	}
-	if tListStr == "" {
-		return "todolist为空", nil
+// 待办事项按id保存在用户的hash中，TODO_KEY:userId 为旧版本用|拼接的字符串
+func todoItemsKey(userId string) string {
+	return fmt.Sprintf("%s:%s:items", TODO_KEY, userId)
+}
+
+func SetTodo(userId string, id string, item string) error {
+	return SetHashValue(todoItemsKey(userId), id, item)
+}
+
+func GetTodos(userId string) (map[string]string, error) {
+	return GetHashValues(todoItemsKey(userId))
+}
+
+func DeleteTodo(userId string, id string) error {
+	return DeleteHashValue(todoItemsKey(userId), id)
+}
+
+// PopLegacyTodoList 读取并删除旧版本的待办字符串，用于迁移
+func PopLegacyTodoList(userId string) string {
+	key := fmt.Sprintf("%s:%s", TODO_KEY, userId)
+	val, _ := GetValue(key)
+	if val != "" {
+		DeleteKey(key)
 	}
-	split := strings.Split(tListStr, "|")
-	var sb strings.Builder
-	for index, todo := range split {
-		sb.WriteString(fmt.Sprintf("%d. %s\n", index+1, todo))
+	return val
+}
+
+// 有截止时间的待办按截止时间保存在有序集合中，成员为 userId:id
+func SetTodoDue(member string, due int64) error {
+	return AddSortedValue(TODO_KEY+":due", member, float64(due))
+}
+
+func GetDueTodos(before int64) ([]string, error) {
+	return GetSortedValues(TODO_KEY+":due", float64(before))
+}
+
+func DeleteTodoDue(member string) error {
+	return DeleteSortedValue(TODO_KEY+":due", member)
+}
+
+// ClaimTodoDue 从截止时间索引中取出待办，返回false时已被其它定时任务取出
+func ClaimTodoDue(member string) (bool, error) {
+	return TakeSortedValue(TODO_KEY+":due", member)
+}
+
+// 提醒按id保存在用户的hash中，下次提醒时间保存在有序集合中，成员为 userId:id
+func SetReminder(userId string, id string, reminder string) error {
+	return SetHashValue(fmt.Sprintf("%s:%s:items", REMIND_KEY, userId), id, reminder)
+}
+
+func GetReminders(userId string) (map[string]string, error) {
+	return GetHashValues(fmt.Sprintf("%s:%s:items", REMIND_KEY, userId))
+}
+
+func DeleteReminder(userId string, id string) error {
+	return DeleteHashValue(fmt.Sprintf("%s:%s:items", REMIND_KEY, userId), id)
+}
+
+func SetReminderDue(member string, due int64) error {
+	return AddSortedValue(REMIND_KEY+":due", member, float64(due))
+}
+
+func GetDueReminders(before int64) ([]string, error) {
+	return GetSortedValues(REMIND_KEY+":due", float64(before))
+}
+
+func DeleteReminderDue(member string) error {
+	return DeleteSortedValue(REMIND_KEY+":due", member)
+}
+
+// ClaimReminderDue 从提醒时间索引中取出提醒，返回false时已被其它定时任务取出
+func ClaimReminderDue(member string) (bool, error) {
+	return TakeSortedValue(REMIND_KEY+":due", member)
+}
+
+// 自选币种保存在hash中，值为逗号分隔的交易对
+func SetWatchList(userId string, symbols string) error {
+	if symbols == "" {
+		return DeleteHashValue(COIN_KEY+":watch", userId)
 	}
-	return sb.String(), nil
+	return SetHashValue(COIN_KEY+":watch", userId, symbols)
 }
 
-func AddTodoList(userId string, todo string) error {
-	todoList, err := GetValue(fmt.Sprintf("%s:%s", TODO_KEY, userId))
-	if err != nil && RedisClient == nil {
-		return err
+func GetWatchList(userId string) (string, error) {
+	return GetHashValue(COIN_KEY+":watch", userId)
+}
+
+// 价格提醒保存在hash中，字段为 userId:id
+func SetPriceAlert(field string, alert string) error {
+	return SetHashValue(COIN_KEY+":alert", field, alert)
+}
+
+func GetPriceAlerts() (map[string]string, error) {
+	return GetHashValues(COIN_KEY + ":alert")
+}
+
+func DeletePriceAlert(field string) error {
+	return DeleteHashValue(COIN_KEY+":alert", field)
+}
+
+// 翻译设置按用户保存在hash中
+func SetTranslateSettings(userId string, settings string) error {
+	return SetHashValue(TRANS_KEY, userId, settings)
+}
+
+func GetTranslateSettings(userId string) (string, error) {
+	return GetHashValue(TRANS_KEY, userId)
+}
+
+// 用户查询天气的默认城市保存在hash中
+func SetWeatherCity(userId string, city string) error {
+	return SetHashValue(WEATHER_KEY, userId, city)
+}
+
+func GetWeatherCity(userId string) (string, error) {
+	return GetHashValue(WEATHER_KEY, userId)
+}
+
+// 记账字段设置按用户和数据库类型保存在hash中，字段为 userId:数据库类型
+func SetLedgerSchema(field string, schema string) error {
+	if schema == "" {
+		return DeleteHashValue(LEDGER_KEY+":schema", field)
 	}
-	if todoList == "" {
-		todoList = todo
-	} else {
-		todoList = fmt.Sprintf("%s|%s", todoList, todo)
+	return SetHashValue(LEDGER_KEY+":schema", field, schema)
+}
+
+func GetLedgerSchema(field string) (string, error) {
+	return GetHashValue(LEDGER_KEY+":schema", field)
+}
+
+// 从Notion读取的数据库属性缓存10分钟，不使用没有过期时间的内存缓存，修改数据库后最多10分钟生效
+func SetLedgerDatabase(databaseId string, info string) error {
+	return SetSessionValue(fmt.Sprintf("%s:db:%s", LEDGER_KEY, databaseId), info, 10*time.Minute)
+}
+
+func GetLedgerDatabase(databaseId string) (string, error) {
+	return GetSessionValue(fmt.Sprintf("%s:db:%s", LEDGER_KEY, databaseId))
+}
+
+func DeleteLedgerDatabase(databaseId string) {
+	DeleteSessionValue(fmt.Sprintf("%s:db:%s", LEDGER_KEY, databaseId))
+}
+
+// 等待确认的记账记录保存10分钟，超时后需要重新发送
+func SetLedgerPending(userId string, batch string) error {
+	return SetSessionValue(fmt.Sprintf("%s:pending:%s", LEDGER_KEY, userId), batch, 10*time.Minute)
+}
+
+func GetLedgerPending(userId string) (string, error) {
+	return GetSessionValue(fmt.Sprintf("%s:pending:%s", LEDGER_KEY, userId))
+}
+
+// TakeLedgerPending 读取并删除等待确认的记录，避免同一批记录被确认两次
+func TakeLedgerPending(userId string) (string, error) {
+	return TakeSessionValue(fmt.Sprintf("%s:pending:%s", LEDGER_KEY, userId))
+}
+
+func DeleteLedgerPending(userId string) {
+	DeleteSessionValue(fmt.Sprintf("%s:pending:%s", LEDGER_KEY, userId))
+}
+
+// 最近写入的记账记录按用户保存在hash中，用于撤销和修改
+func SetLedgerHistory(userId string, history string) error {
+	if history == "" {
+		return DeleteHashValue(LEDGER_KEY+":history", userId)
 	}
-	return SetValue(fmt.Sprintf("%s:%s", TODO_KEY, userId), todoList, 0)
+	return SetHashValue(LEDGER_KEY+":history", userId, history)
+}
+
+func GetLedgerHistory(userId string) (string, error) {
+	return GetHashValue(LEDGER_KEY+":history", userId)
 }
 
-func DelTodoList(userId string, todoIndex int) error {
-	todoList, err := GetValue(fmt.Sprintf("%s:%s", TODO_KEY, userId))
-	if err != nil && RedisClient == nil {
-		return err
+// 开启自动确认的用户记账时不预览，直接写入
+func SetLedgerAutoConfirm(userId string, enable bool) error {
+	if !enable {
+		return DeleteHashValue(LEDGER_KEY+":auto", userId)
 	}
-	todoList = strings.Split(todoList, "|")[todoIndex-1]
-	return SetValue(fmt.Sprintf("%s:%s", TODO_KEY, userId), todoList, 0)
+	return SetHashValue(LEDGER_KEY+":auto", userId, "1")
+}
+
+func IsLedgerAutoConfirm(userId string) bool {
+	val, _ := GetHashValue(LEDGER_KEY+":auto", userId)
+	return val == "1"
 }
 
 func SetModel(userId, botType, model string) error {
@@ -225,3 +509,263 @@ func SetModel(userId, botType, model string) error {
 func GetModel(userId, botType string) (string, error) {
 	return GetValue(fmt.Sprintf("%s:%s:%s", MODEL_KEY, userId, botType))
 }
+
+// 开启自动搜索的用户保存在hash中，不会过期
+func SetAutoSearch(userId string, enable bool) error {
+	if !enable {
+		return DeleteHashValue(SEARCH_KEY, userId)
+	}
+	return SetHashValue(SEARCH_KEY, userId, "1")
+}
+
+func IsAutoSearch(userId string) bool {
+	val, _ := GetHashValue(SEARCH_KEY, userId)
+	return val == "1"
+}
+
+// 网页内容缓存一天，同一链接不重复抓取
+func SetWebPage(pageUrl string, page string) error {
+	return SetValue(fmt.Sprintf("%s:%x", PAGE_KEY, sha1.Sum([]byte(pageUrl))), page, 24*time.Hour)
+}
+
+func GetWebPage(pageUrl string) (string, error) {
+	return GetValue(fmt.Sprintf("%s:%x", PAGE_KEY, sha1.Sum([]byte(pageUrl))))
+}
+
+// 用户发送的文档和对话记录一起过期
+func SetDocument(userId string, doc string) error {
+	return SetSessionValue(fmt.Sprintf("%s:%s", DOC_KEY, userId), doc, GetMsgTime())
+}
+
+func GetDocument(userId string) (string, error) {
+	return GetSessionValue(fmt.Sprintf("%s:%s", DOC_KEY, userId))
+}
+
+// TouchDocument 继续围绕文档提问时延长过期时间
+func TouchDocument(userId string) {
+	ExpireSessionValue(fmt.Sprintf("%s:%s", DOC_KEY, userId), GetMsgTime())
+}
+
+func DeleteDocument(userId string) {
+	DeleteSessionValue(fmt.Sprintf("%s:%s", DOC_KEY, userId))
+}
+
+// SetHashValue 写入hash字段，没有配置redis时保存在内存
+func SetHashValue(key, field string, val string) error {
+	if RedisClient == nil {
+		SetValueWithMemory(fmt.Sprintf("%s:%s", key, field), val)
+		return nil
+	}
+	return RedisClient.HSet(context.Background(), key, field, val).Err()
+}
+
+func GetHashValues(key string) (map[string]string, error) {
+	if RedisClient == nil {
+		values := map[string]string{}
+		prefix := key + ":"
+		Cache.Range(func(k, v any) bool {
+			if field, ok := strings.CutPrefix(k.(string), prefix); ok {
+				values[field] = v.(string)
+			}
+			return true
+		})
+		return values, nil
+	}
+	return RedisClient.HGetAll(context.Background(), key).Result()
+}
+
+func GetHashValue(key, field string) (string, error) {
+	if RedisClient == nil {
+		val, _ := GetValueWithMemory(fmt.Sprintf("%s:%s", key, field))
+		return val, nil
+	}
+	val, err := RedisClient.HGet(context.Background(), key, field).Result()
+	if err == redis.Nil {
+		return "", nil
+	}
+	return val, err
+}
+
+func DeleteHashValue(key, field string) error {
+	if RedisClient == nil {
+		DeleteKeyWithMemory(fmt.Sprintf("%s:%s", key, field))
+		return nil
+	}
+	return RedisClient.HDel(context.Background(), key, field).Err()
+}
+
+// 知识库按文件名保存在hash中
+func SetKnowledge(name string, chunks string) error {
+	return SetHashValue(KB_KEY, name, chunks)
+}
+
+func GetKnowledge() (map[string]string, error) {
+	return GetHashValues(KB_KEY)
+}
+
+func DeleteKnowledge(name string) error {
+	return DeleteHashValue(KB_KEY, name)
+}
+
+// 自动回复规则按id保存在hash中
+func SetRule(id string, rule string) error {
+	return SetHashValue(RULE_KEY, id, rule)
+}
+
+func GetRules() (map[string]string, error) {
+	return GetHashValues(RULE_KEY)
+}
+
+func DeleteRule(id string) error {
+	return DeleteHashValue(RULE_KEY, id)
+}
+
+// 用户角色按openid保存在hash中，普通用户不保存
+func SetRole(userId string, role string) error {
+	return SetHashValue(ROLE_KEY, userId, role)
+}
+
+func GetRole(userId string) (string, error) {
+	return GetHashValue(ROLE_KEY, userId)
+}
+
+func GetRoles() (map[string]string, error) {
+	return GetHashValues(ROLE_KEY)
+}
+
+func DeleteRole(userId string) error {
+	return DeleteHashValue(ROLE_KEY, userId)
+}
+
+// TouchUser 记录用户最后一次发消息的时间，用于统计和群发
+func TouchUser(userId string) error {
+	return SetHashValue(USER_KEY, userId, strconv.FormatInt(time.Now().Unix(), 10))
+}
+
+// GetUsers 返回openid到最后活跃时间(unix秒)的映射
+func GetUsers() (map[string]int64, error) {
+	values, err := GetHashValues(USER_KEY)
+	if err != nil {
+		return nil, err
+	}
+	users := make(map[string]int64, len(values))
+	for userId, value := range values {
+		t, _ := strconv.ParseInt(value, 10, 64)
+		users[userId] = t
+	}
+	return users, nil
+}
+
+// PushList 在列表头部插入，只保留最新的max条，没有配置redis时保存在内存
+func PushList(key string, val string, max int) error {
+	if RedisClient == nil {
+		memoryLock.Lock()
+		defer memoryLock.Unlock()
+		list := append([]string{val}, memoryLists[key]...)
+		if len(list) > max {
+			list = list[:max]
+		}
+		memoryLists[key] = list
+		return nil
+	}
+	ctx := context.Background()
+	_, err := RedisClient.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.LPush(ctx, key, val)
+		pipe.LTrim(ctx, key, 0, int64(max-1))
+		return nil
+	})
+	return err
+}
+
+// GetList 返回列表最新的n条
+func GetList(key string, n int) ([]string, error) {
+	if RedisClient == nil {
+		memoryLock.Lock()
+		defer memoryLock.Unlock()
+		list := memoryLists[key]
+		if len(list) > n {
+			list = list[:n]
+		}
+		return append([]string(nil), list...), nil
+	}
+	return RedisClient.LRange(context.Background(), key, 0, int64(n-1)).Result()
+}
+
+// 限流记录保存在列表中，只保留最近的记录
+func AddViolation(violation string) error {
+	return PushList(LIMIT_KEY+":violations", violation, 200)
+}
+
+func GetViolations(n int) ([]string, error) {
+	return GetList(LIMIT_KEY+":violations", n)
+}
+
+// 内容审核的命中记录保存在列表中，只保留最近的记录
+func AddModerationLog(log string) error {
+	return PushList(MOD_KEY+":log", log, 200)
+}
+
+func GetModerationLogs(n int) ([]string, error) {
+	return GetList(MOD_KEY+":log", n)
+}
+
+// AddSortedValue 写入有序集合，没有配置redis时保存在内存
+func AddSortedValue(key, member string, score float64) error {
+	if RedisClient == nil {
+		memoryLock.Lock()
+		defer memoryLock.Unlock()
+		if memorySets[key] == nil {
+			memorySets[key] = map[string]float64{}
+		}
+		memorySets[key][member] = score
+		return nil
+	}
+	return RedisClient.ZAdd(context.Background(), key, &redis.Z{Score: score, Member: member}).Err()
+}
+
+// GetSortedValues 返回分数不大于max的成员，按分数从小到大排序
+func GetSortedValues(key string, max float64) ([]string, error) {
+	if RedisClient == nil {
+		memoryLock.Lock()
+		defer memoryLock.Unlock()
+		var members []string
+		for member, score := range memorySets[key] {
+			if score <= max {
+				members = append(members, member)
+			}
+		}
+		sort.Slice(members, func(i, j int) bool {
+			return memorySets[key][members[i]] < memorySets[key][members[j]]
+		})
+		return members, nil
+	}
+	return RedisClient.ZRangeByScore(context.Background(), key, &redis.ZRangeBy{
+		Min: "-inf",
+		Max: strconv.FormatFloat(max, 'f', -1, 64),
+	}).Result()
+}
+
+func DeleteSortedValue(key, member string) error {
+	if RedisClient == nil {
+		memoryLock.Lock()
+		defer memoryLock.Unlock()
+		delete(memorySets[key], member)
+		return nil
+	}
+	return RedisClient.ZRem(context.Background(), key, member).Err()
+}
+
+// TakeSortedValue 删除有序集合中的成员，成员存在时返回true，同时删除时只有一个调用返回true
+func TakeSortedValue(key, member string) (bool, error) {
+	if RedisClient == nil {
+		memoryLock.Lock()
+		defer memoryLock.Unlock()
+		if _, ok := memorySets[key][member]; !ok {
+			return false, nil
+		}
+		delete(memorySets[key], member)
+		return true, nil
+	}
+	n, err := RedisClient.ZRem(context.Background(), key, member).Result()
+	return n > 0, err
+}
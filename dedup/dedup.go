@@ -0,0 +1,109 @@
+package dedup
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/pwh-pwh/aiwechat-vercel/db"
+	"github.com/pwh-pwh/aiwechat-vercel/reply"
+	"github.com/silenceper/wechat/v2/officialaccount/message"
+)
+
+const (
+	// 微信最多重试三次，每次等待5秒，处理标记保留到重试结束之后
+	Processing_Ttl = 2 * time.Minute
+	// 结果保留时间
+	Result_Ttl = 10 * time.Minute
+	// 重试的请求最多等到消息发送后14秒，在微信最后一次重试的5秒超时之前回复
+	Wait_Window = 14 * time.Second
+	// 重试的请求至少等待的时间，需要小于微信的5秒超时
+	Wait_Timeout  = 4 * time.Second
+	Poll_Interval = 200 * time.Millisecond
+
+	Processing_Reply = "正在处理中，请稍后再发一次相同的消息获取结果"
+)
+
+// Backend 不为空时替代默认的存储，用于测试
+var Backend Store
+
+var memoryStore = NewMemoryStore()
+
+func getStore() Store {
+	if Backend != nil {
+		return Backend
+	}
+	if db.RedisClient != nil {
+		return NewRedisStore(db.RedisClient)
+	}
+	return memoryStore
+}
+
+// Key 普通消息使用MsgId，事件没有MsgId，使用发送者和发送时间
+func Key(msg *message.MixMessage) string {
+	if msg.MsgID != 0 {
+		return fmt.Sprintf("%s:%d", db.DEDUP_KEY, msg.MsgID)
+	}
+	return fmt.Sprintf("%s:%s:%d", db.DEDUP_KEY, msg.FromUserName, msg.CreateTime)
+}
+
+// Do 同一条消息只执行一次f，微信重试的请求等待并返回第一次的回复，sent是消息的发送时间
+// 处理超过5秒时微信会重试，f完成前处理标记一直保留，完成后保存结果，之后到达的重试直接返回真实的回复
+func Do(key string, sent time.Time, f func() *message.Reply) *message.Reply {
+	store := getStore()
+	acquired, err := store.Acquire(key, Processing_Ttl)
+	if err != nil {
+		// 存储出错时直接处理，避免消息无法回复
+		fmt.Println("dedup acquire error:", err)
+		return f()
+	}
+	if !acquired {
+		return wait(store, key, waitDeadline(sent))
+	}
+	r := f()
+	if r == nil {
+		return r
+	}
+	data, err := reply.Marshal(r)
+	if err == nil {
+		err = store.SetResult(key, data, Result_Ttl)
+	}
+	if err != nil {
+		fmt.Println("dedup save error:", err)
+	}
+	return r
+}
+
+// waitDeadline 超过5秒仍未得到结果的重试微信会放弃并再次重试，只有最后一次重试需要在超时前回复正在处理
+func waitDeadline(sent time.Time) time.Time {
+	now := time.Now()
+	deadline := sent.Add(Wait_Window)
+	if deadline.Before(now.Add(Wait_Timeout)) {
+		return now.Add(Wait_Timeout)
+	}
+	// 发送时间不准确时最多等待一个窗口
+	if deadline.After(now.Add(Wait_Window)) {
+		return now.Add(Wait_Window)
+	}
+	return deadline
+}
+
+func wait(store Store, key string, deadline time.Time) *message.Reply {
+	for {
+		data, ok, err := store.GetResult(key)
+		if err != nil {
+			fmt.Println("dedup get error:", err)
+		}
+		if ok {
+			r, err := reply.Unmarshal(data)
+			if err == nil {
+				return r
+			}
+			fmt.Println("dedup unmarshal error:", err)
+			return reply.Text(Processing_Reply)
+		}
+		if time.Now().After(deadline) {
+			return reply.Text(Processing_Reply)
+		}
+		time.Sleep(Poll_Interval)
+	}
+}
@@ -0,0 +1,121 @@
+package dedup
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pwh-pwh/aiwechat-vercel/reply"
+	"github.com/silenceper/wechat/v2/officialaccount/message"
+)
+
+func useMemoryStore(t *testing.T) *MemoryStore {
+	t.Helper()
+	store := NewMemoryStore()
+	Backend = store
+	t.Cleanup(func() { Backend = nil })
+	return store
+}
+
+func content(r *message.Reply) string {
+	return string(r.MsgData.(*message.Text).Content)
+}
+
+func TestKey(t *testing.T) {
+	msg := &message.MixMessage{MsgID: 123}
+	msg.FromUserName = "u1"
+	msg.CreateTime = 1700000000
+	if Key(msg) != "dedup:123" {
+		t.Errorf("message key = %s", Key(msg))
+	}
+	msg.MsgID = 0
+	if Key(msg) != "dedup:u1:1700000000" {
+		t.Errorf("event key = %s", Key(msg))
+	}
+}
+
+func TestDoRetry(t *testing.T) {
+	useMemoryStore(t)
+	var calls atomic.Int32
+	f := func() *message.Reply {
+		calls.Add(1)
+		time.Sleep(300 * time.Millisecond)
+		return reply.Text("记账成功")
+	}
+
+	var wg sync.WaitGroup
+	results := make([]*message.Reply, 3)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			time.Sleep(time.Duration(i) * 50 * time.Millisecond)
+			results[i] = Do("dedup:1", time.Now(), f)
+		}(i)
+	}
+	wg.Wait()
+	if calls.Load() != 1 {
+		t.Errorf("f called %d times", calls.Load())
+	}
+	for i, r := range results {
+		if content(r) != "记账成功" {
+			t.Errorf("result %d = %q", i, content(r))
+		}
+	}
+	// 处理完成后再次重试直接返回结果
+	if r := Do("dedup:1", time.Now(), f); content(r) != "记账成功" || calls.Load() != 1 {
+		t.Errorf("late retry = %q, calls %d", content(r), calls.Load())
+	}
+}
+
+func TestDoProcessing(t *testing.T) {
+	store := useMemoryStore(t)
+	store.Acquire("dedup:2", time.Minute)
+	if r := wait(store, "dedup:2", time.Now().Add(100*time.Millisecond)); content(r) != Processing_Reply {
+		t.Errorf("still processing = %q", content(r))
+	}
+
+	// 空回复同样保存，重试时不再回复正在处理
+	Do("dedup:3", time.Now(), func() *message.Reply { return reply.Text("") })
+	if data, ok, _ := store.GetResult("dedup:3"); !ok || data == "" {
+		t.Error("empty reply should be saved")
+	}
+}
+
+func TestDoSlow(t *testing.T) {
+	useMemoryStore(t)
+	sent := time.Now()
+	done := make(chan *message.Reply)
+	go func() {
+		done <- Do("dedup:4", sent, func() *message.Reply {
+			time.Sleep(5500 * time.Millisecond)
+			return reply.Text("很慢的回答")
+		})
+	}()
+
+	// 微信在5秒后重试，重试的请求等待并得到真实的回复
+	time.Sleep(5 * time.Second)
+	if r := Do("dedup:4", sent, nil); content(r) != "很慢的回答" {
+		t.Errorf("retry = %q", content(r))
+	}
+	if r := <-done; content(r) != "很慢的回答" {
+		t.Errorf("first = %q", content(r))
+	}
+}
+
+func TestWaitDeadline(t *testing.T) {
+	now := time.Now()
+	// 第一次重试等到最后一次重试超时之前
+	if d := waitDeadline(now.Add(-5 * time.Second)).Sub(now); d < 8*time.Second || d > 10*time.Second {
+		t.Errorf("first retry waits %v", d)
+	}
+	// 最后一次重试至少等待Wait_Timeout
+	if d := waitDeadline(now.Add(-15 * time.Second)).Sub(now); d < Wait_Timeout || d > Wait_Timeout+time.Second {
+		t.Errorf("last retry waits %v", d)
+	}
+	// 发送时间在未来时最多等待一个窗口
+	if d := waitDeadline(now.Add(time.Hour)).Sub(now); d > Wait_Window+time.Second {
+		t.Errorf("future sent waits %v", d)
+	}
+}
@@ -0,0 +1,96 @@
+package dedup
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// Store 保存消息的处理状态，Acquire成功的请求负责处理，其余请求读取结果
+type Store interface {
+	Acquire(key string, ttl time.Duration) (bool, error)
+	SetResult(key, result string, ttl time.Duration) error
+	// GetResult 消息处理完成时返回结果和true，仍在处理时返回false
+	GetResult(key string) (string, bool, error)
+}
+
+// 处理中的占位值，结果是json不会与它相同
+const processingMarker = "processing"
+
+type RedisStore struct {
+	client *redis.Client
+}
+
+func NewRedisStore(client *redis.Client) *RedisStore {
+	return &RedisStore{client: client}
+}
+
+func (s *RedisStore) Acquire(key string, ttl time.Duration) (bool, error) {
+	return s.client.SetNX(context.Background(), key, processingMarker, ttl).Result()
+}
+
+func (s *RedisStore) SetResult(key, result string, ttl time.Duration) error {
+	return s.client.Set(context.Background(), key, result, ttl).Err()
+}
+
+func (s *RedisStore) GetResult(key string) (string, bool, error) {
+	val, err := s.client.Get(context.Background(), key).Result()
+	if err == redis.Nil {
+		return "", false, nil
+	}
+	if err != nil || val == processingMarker {
+		return "", false, err
+	}
+	return val, true, nil
+}
+
+// MemoryStore 没有配置redis时使用，只能识别同一实例收到的重试
+type MemoryStore struct {
+	lock    sync.Mutex
+	entries map[string]entry
+}
+
+type entry struct {
+	value   string
+	expires time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{entries: map[string]entry{}}
+}
+
+func (s *MemoryStore) Acquire(key string, ttl time.Duration) (bool, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	now := time.Now()
+	// 顺便清理过期的记录
+	for k, e := range s.entries {
+		if now.After(e.expires) {
+			delete(s.entries, k)
+		}
+	}
+	if _, ok := s.entries[key]; ok {
+		return false, nil
+	}
+	s.entries[key] = entry{value: processingMarker, expires: now.Add(ttl)}
+	return true, nil
+}
+
+func (s *MemoryStore) SetResult(key, result string, ttl time.Duration) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.entries[key] = entry{value: result, expires: time.Now().Add(ttl)}
+	return nil
+}
+
+func (s *MemoryStore) GetResult(key string) (string, bool, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	e, ok := s.entries[key]
+	if !ok || e.value == processingMarker || time.Now().After(e.expires) {
+		return "", false, nil
+	}
+	return e.value, true, nil
+}
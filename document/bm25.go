@@ -0,0 +1,120 @@
+package document
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+// Index 基于BM25的关键词检索，不依赖外部服务
+type Index struct {
+	docs   [][]string
+	tf     []map[string]int
+	df     map[string]int
+	avgLen float64
+}
+
+// Result 检索结果，Id为建立索引时文本的下标
+type Result struct {
+	Id    int
+	Score float64
+}
+
+func NewIndex(texts []string) *Index {
+	index := &Index{df: map[string]int{}}
+	total := 0
+	for _, text := range texts {
+		tokens := Tokenize(text)
+		tf := map[string]int{}
+		for _, token := range tokens {
+			tf[token]++
+		}
+		for token := range tf {
+			index.df[token]++
+		}
+		index.docs = append(index.docs, tokens)
+		index.tf = append(index.tf, tf)
+		total += len(tokens)
+	}
+	if len(texts) > 0 {
+		index.avgLen = float64(total) / float64(len(texts))
+	}
+	return index
+}
+
+// Search 返回得分最高的k条结果，不包含得分为0的结果
+func (index *Index) Search(query string, k int) []Result {
+	terms := map[string]bool{}
+	for _, token := range Tokenize(query) {
+		terms[token] = true
+	}
+	n := float64(len(index.docs))
+	var results []Result
+	for id, tf := range index.tf {
+		score := 0.0
+		for term := range terms {
+			freq := float64(tf[term])
+			if freq == 0 {
+				continue
+			}
+			df := float64(index.df[term])
+			idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+			norm := 1 - bm25B + bm25B*float64(len(index.docs[id]))/index.avgLen
+			score += idf * freq * (bm25K1 + 1) / (freq + bm25K1*norm)
+		}
+		if score > 0 {
+			results = append(results, Result{Id: id, Score: score})
+		}
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Score > results[j].Score
+	})
+	if k > 0 && len(results) > k {
+		results = results[:k]
+	}
+	return results
+}
+
+// Tokenize 英文和数字按单词切分并转为小写，中文没有分词器，使用单字加相邻两字组合
+func Tokenize(text string) []string {
+	var tokens []string
+	var word []rune
+	var han []rune
+	flushWord := func() {
+		if len(word) > 0 {
+			tokens = append(tokens, strings.ToLower(string(word)))
+			word = word[:0]
+		}
+	}
+	flushHan := func() {
+		for i, r := range han {
+			tokens = append(tokens, string(r))
+			if i+1 < len(han) {
+				tokens = append(tokens, string(han[i:i+2]))
+			}
+		}
+		han = han[:0]
+	}
+	for _, r := range text {
+		switch {
+		case unicode.Is(unicode.Han, r):
+			flushWord()
+			han = append(han, r)
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			flushHan()
+			word = append(word, r)
+		default:
+			flushWord()
+			flushHan()
+		}
+	}
+	flushWord()
+	flushHan()
+	return tokens
+}
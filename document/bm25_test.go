@@ -0,0 +1,32 @@
+package document
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTokenize(t *testing.T) {
+	got := Tokenize("保修期 iPhone15，Pro")
+	want := []string{"保", "保修", "修", "修期", "期", "iphone15", "pro"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Tokenize = %q, want %q", got, want)
+	}
+}
+
+func TestIndexSearch(t *testing.T) {
+	index := NewIndex([]string{
+		"本产品保修期为一年，人为损坏不在保修范围内",
+		"收到商品七天内可以无理由退货",
+		"客服工作时间为每天9点到18点",
+	})
+	results := index.Search("退货要几天内", 2)
+	if len(results) == 0 || results[0].Id != 1 {
+		t.Fatalf("results = %v", results)
+	}
+	if results := index.Search("保修多久", 1); len(results) != 1 || results[0].Id != 0 {
+		t.Errorf("results = %v", results)
+	}
+	if results := index.Search("xyz", 3); len(results) != 0 {
+		t.Errorf("unrelated query should return nothing: %v", results)
+	}
+}
@@ -0,0 +1,58 @@
+package document
+
+import (
+	"strings"
+)
+
+// Chunk 检索的最小单位，Page为所在页码
+type Chunk struct {
+	Page int
+	Text string
+}
+
+// Split 把每一页按段落切分为不超过size个字符的片段，相邻片段重叠overlap个字符，片段不跨页以便标注页码
+func Split(pages []Page, size, overlap int) []Chunk {
+	if overlap >= size {
+		overlap = 0
+	}
+	var chunks []Chunk
+	for _, page := range pages {
+		var current []rune
+		added := 0 // 上次切分后新加入的字符数
+		flush := func() {
+			chunks = append(chunks, Chunk{Page: page.Number, Text: strings.TrimSpace(string(current))})
+			if overlap > 0 && len(current) > overlap {
+				current = append([]rune(nil), current[len(current)-overlap:]...)
+			} else {
+				current = nil
+			}
+			added = 0
+		}
+		for _, paragraph := range strings.Split(page.Text, "\n") {
+			text := []rune(strings.TrimSpace(paragraph))
+			if len(text) == 0 {
+				continue
+			}
+			if added > 0 && len(current)+len(text) > size {
+				flush()
+			}
+			// 超长段落直接按长度切分
+			for len(current)+len(text) > size {
+				n := size - len(current)
+				current = append(current, text[:n]...)
+				added += n
+				text = text[n:]
+				flush()
+			}
+			if len(text) > 0 {
+				current = append(current, text...)
+				current = append(current, '\n')
+				added += len(text)
+			}
+		}
+		if added > 0 {
+			flush()
+		}
+	}
+	return chunks
+}
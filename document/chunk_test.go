@@ -0,0 +1,39 @@
+package document
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSplit(t *testing.T) {
+	pages := []Page{
+		{Number: 1, Text: "第一段\n第二段\n第三段"},
+		{Number: 2, Text: strings.Repeat("长", 25)},
+	}
+	chunks := Split(pages, 10, 0)
+	want := []Chunk{
+		{1, "第一段\n第二段"},
+		{1, "第三段"},
+		{2, strings.Repeat("长", 10)},
+		{2, strings.Repeat("长", 10)},
+		{2, strings.Repeat("长", 5)},
+	}
+	if len(chunks) != len(want) {
+		t.Fatalf("chunks = %q", chunks)
+	}
+	for i := range want {
+		if chunks[i] != want[i] {
+			t.Errorf("chunk %d = %q, want %q", i, chunks[i], want[i])
+		}
+	}
+}
+
+func TestSplitOverlap(t *testing.T) {
+	chunks := Split([]Page{{Number: 1, Text: "abcdefghij\nklmnopqrst"}}, 14, 3)
+	if len(chunks) != 2 {
+		t.Fatalf("chunks = %q", chunks)
+	}
+	if !strings.HasPrefix(chunks[1].Text, "ij") || !strings.HasSuffix(chunks[1].Text, "klmnopqrst") {
+		t.Errorf("second chunk should overlap the first: %q", chunks[1].Text)
+	}
+}
@@ -0,0 +1,202 @@
+package document
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/ledongthuc/pdf"
+	"golang.org/x/text/encoding/simplifiedchinese"
+)
+
+const (
+	TYPE_PDF  = "pdf"
+	TYPE_DOCX = "docx"
+	TYPE_TXT  = "txt"
+)
+
+// Page 文档中的一页，Number从1开始
+type Page struct {
+	Number int
+	Text   string
+}
+
+// DetectType 根据文件名和Content-Type判断文档类型，不支持时返回空字符串
+func DetectType(name, contentType string) string {
+	switch strings.ToLower(path.Ext(name)) {
+	case ".pdf":
+		return TYPE_PDF
+	case ".docx":
+		return TYPE_DOCX
+	case ".txt", ".md":
+		return TYPE_TXT
+	}
+	contentType = strings.ToLower(contentType)
+	switch {
+	case strings.HasPrefix(contentType, "application/pdf"):
+		return TYPE_PDF
+	case strings.HasPrefix(contentType, "application/vnd.openxmlformats-officedocument.wordprocessingml.document"):
+		return TYPE_DOCX
+	case strings.HasPrefix(contentType, "text/plain"), strings.HasPrefix(contentType, "text/markdown"):
+		return TYPE_TXT
+	}
+	return ""
+}
+
+// Extract 按文档类型提取每一页的文本，跳过没有文字的页
+func Extract(docType string, data []byte) ([]Page, error) {
+	var pages []Page
+	var err error
+	switch docType {
+	case TYPE_PDF:
+		pages, err = extractPdf(data)
+	case TYPE_DOCX:
+		pages, err = extractDocx(data)
+	case TYPE_TXT:
+		pages = extractTxt(data)
+	default:
+		return nil, fmt.Errorf("不支持的文档类型: %s", docType)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(pages) == 0 {
+		return nil, errors.New("没有在文档中找到文字，扫描版文档暂不支持")
+	}
+	return pages, nil
+}
+
+func extractPdf(data []byte) ([]Page, error) {
+	reader, err := pdf.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("解析pdf失败: %w", err)
+	}
+	var pages []Page
+	for i := 1; i <= reader.NumPage(); i++ {
+		p := reader.Page(i)
+		if p.V.IsNull() {
+			continue
+		}
+		text, err := p.GetPlainText(nil)
+		if err != nil {
+			return nil, fmt.Errorf("解析pdf第%d页失败: %w", i, err)
+		}
+		if text = strings.TrimSpace(text); text != "" {
+			pages = append(pages, Page{Number: i, Text: text})
+		}
+	}
+	return pages, nil
+}
+
+// extractDocx 读取word/document.xml，docx本身不记录分页，按分页符和word保存时记录的分页位置计算页码
+func extractDocx(data []byte) ([]Page, error) {
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("解析docx失败: %w", err)
+	}
+	var file *zip.File
+	for _, f := range archive.File {
+		if f.Name == "word/document.xml" {
+			file = f
+			break
+		}
+	}
+	if file == nil {
+		return nil, errors.New("解析docx失败: 缺少word/document.xml")
+	}
+	rc, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	var pages []Page
+	var page, paragraph strings.Builder
+	number := 1
+	newPage := func() {
+		if text := strings.TrimSpace(page.String()); text != "" {
+			pages = append(pages, Page{Number: number, Text: text})
+		}
+		page.Reset()
+		number++
+	}
+	decoder := xml.NewDecoder(rc)
+	inText := false
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("解析docx失败: %w", err)
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "t":
+				inText = true
+			case "tab":
+				paragraph.WriteString(" ")
+			case "lastRenderedPageBreak":
+				page.WriteString(paragraph.String())
+				paragraph.Reset()
+				newPage()
+			case "br":
+				if docxAttr(t, "type") == "page" {
+					page.WriteString(paragraph.String())
+					paragraph.Reset()
+					newPage()
+				} else {
+					paragraph.WriteString("\n")
+				}
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "t":
+				inText = false
+			case "p":
+				page.WriteString(paragraph.String())
+				page.WriteString("\n")
+				paragraph.Reset()
+			}
+		case xml.CharData:
+			if inText {
+				paragraph.Write(t)
+			}
+		}
+	}
+	page.WriteString(paragraph.String())
+	newPage()
+	return pages, nil
+}
+
+func docxAttr(e xml.StartElement, name string) string {
+	for _, a := range e.Attr {
+		if a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// extractTxt 非utf-8编码的文本按gbk解码，换页符\f分页
+func extractTxt(data []byte) []Page {
+	if !utf8.Valid(data) {
+		if decoded, err := simplifiedchinese.GBK.NewDecoder().Bytes(data); err == nil {
+			data = decoded
+		}
+	}
+	var pages []Page
+	for i, text := range strings.Split(string(data), "\f") {
+		if text = strings.TrimSpace(text); text != "" {
+			pages = append(pages, Page{Number: i + 1, Text: text})
+		}
+	}
+	return pages
+}
@@ -0,0 +1,119 @@
+package document
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"golang.org/x/text/encoding/simplifiedchinese"
+)
+
+func TestDetectType(t *testing.T) {
+	cases := []struct {
+		name, contentType, want string
+	}{
+		{"手册.PDF", "", TYPE_PDF},
+		{"report.docx", "application/octet-stream", TYPE_DOCX},
+		{"notes.txt", "", TYPE_TXT},
+		{"download", "application/pdf", TYPE_PDF},
+		{"download", "text/plain; charset=utf-8", TYPE_TXT},
+		{"photo.png", "image/png", ""},
+	}
+	for _, c := range cases {
+		if got := DetectType(c.name, c.contentType); got != c.want {
+			t.Errorf("DetectType(%q, %q) = %q, want %q", c.name, c.contentType, got, c.want)
+		}
+	}
+}
+
+func buildDocx(t *testing.T, body string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	f, err := w.Create("word/document.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fmt.Fprintf(f, `<?xml version="1.0" encoding="UTF-8"?><w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"><w:body>%s</w:body></w:document>`, body)
+	w.Close()
+	return buf.Bytes()
+}
+
+func TestExtractDocx(t *testing.T) {
+	data := buildDocx(t, `<w:p><w:r><w:t>第一章</w:t></w:r></w:p>`+
+		`<w:p><w:r><w:t xml:space="preserve">保修期为</w:t></w:r><w:r><w:t>一年</w:t></w:r></w:p>`+
+		`<w:p><w:r><w:br w:type="page"/><w:t>第二章</w:t></w:r></w:p>`+
+		`<w:p><w:r><w:lastRenderedPageBreak/><w:t>退货</w:t><w:tab/><w:t>七天</w:t></w:r></w:p>`)
+	pages, err := Extract(TYPE_DOCX, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Page{{1, "第一章\n保修期为一年"}, {2, "第二章"}, {3, "退货 七天"}}
+	if fmt.Sprint(pages) != fmt.Sprint(want) {
+		t.Errorf("pages = %q, want %q", pages, want)
+	}
+}
+
+func TestExtractTxt(t *testing.T) {
+	gbk, _ := simplifiedchinese.GBK.NewEncoder().String("第一页内容\f\f第三页内容")
+	pages, err := Extract(TYPE_TXT, []byte(gbk))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Page{{1, "第一页内容"}, {3, "第三页内容"}}
+	if fmt.Sprint(pages) != fmt.Sprint(want) {
+		t.Errorf("pages = %q, want %q", pages, want)
+	}
+	if _, err = Extract(TYPE_TXT, []byte("  \n")); err == nil {
+		t.Error("expected error for empty document")
+	}
+}
+
+// buildPdf 生成每页一行文字的最简pdf
+func buildPdf(texts ...string) []byte {
+	var objs []string
+	kids := make([]string, len(texts))
+	for i := range texts {
+		kids[i] = fmt.Sprintf("%d 0 R", 4+i*2)
+	}
+	objs = append(objs, "<< /Type /Catalog /Pages 2 0 R >>")
+	objs = append(objs, fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(texts)))
+	objs = append(objs, "<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>")
+	for i, text := range texts {
+		stream := fmt.Sprintf("BT /F1 12 Tf 72 720 Td (%s) Tj ET", text)
+		objs = append(objs, fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] /Resources << /Font << /F1 3 0 R >> >> /Contents %d 0 R >>", 5+i*2))
+		objs = append(objs, fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(stream), stream))
+	}
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objs))
+	for i, obj := range objs {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(objs)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objs)+1, xref)
+	return buf.Bytes()
+}
+
+func TestExtractPdf(t *testing.T) {
+	pages, err := Extract(TYPE_PDF, buildPdf("Warranty is one year", "", "Returns within seven days"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pages) != 2 || pages[0].Number != 1 || pages[1].Number != 3 {
+		t.Fatalf("pages = %q", pages)
+	}
+	if !strings.Contains(pages[1].Text, "seven days") {
+		t.Errorf("page 3 text = %q", pages[1].Text)
+	}
+	if _, err = Extract(TYPE_PDF, []byte("not a pdf")); err == nil {
+		t.Error("expected error for invalid pdf")
+	}
+}
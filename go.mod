@@ -8,8 +8,10 @@ require (
 	github.com/google/generative-ai-go v0.18.0
 	github.com/gorilla/websocket v1.5.1
 	github.com/joho/godotenv v1.5.1
+	github.com/ledongthuc/pdf v0.0.0-20220302134840-0c2507a12d80
 	github.com/sashabaranov/go-openai v1.20.2
 	github.com/silenceper/wechat/v2 v2.1.6
+	golang.org/x/net v0.26.0
 	golang.org/x/text v0.16.0
 	google.golang.org/api v0.186.0
 )
@@ -51,7 +53,6 @@ require (
 	go.opentelemetry.io/otel/trace v1.26.0 // indirect
 	golang.org/x/arch v0.0.0-20210923205945-b76863e36670 // indirect
 	golang.org/x/crypto v0.24.0 // indirect
-	golang.org/x/net v0.26.0 // indirect
 	golang.org/x/oauth2 v0.21.0 // indirect
 	golang.org/x/sync v0.7.0 // indirect
 	golang.org/x/sys v0.21.0 // indirect
@@ -0,0 +1,131 @@
+package ledger
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/pwh-pwh/aiwechat-vercel/config"
+	"github.com/pwh-pwh/aiwechat-vercel/notion"
+)
+
+// 数据库类型
+const (
+	Type_Expense = "0" // 记账数据库
+	Type_Income  = "1" // 工资入账数据库
+)
+
+// 配置数据库的属性名
+const (
+	Prop_User_Id       = "用户id"
+	Prop_Api_Key       = "NOTION_API_KEY"
+	Prop_Database_Id   = "DATABASE_ID"
+	Prop_Database_Type = "数据库类型"
+)
+
+var ErrNoAccount = errors.New("用户未绑定，请先绑定账号和 Notion 数据库")
+
+// Account 用户绑定的记账数据库，保存在NOTION_CONFIG_DATABASE_ID配置数据库中
+type Account struct {
+	UserId     string
+	ApiKey     string
+	DatabaseId string
+	Type       string
+}
+
+// Client 使用用户自己的key访问记账数据库
+func (a *Account) Client() *notion.Client {
+	return notion.New(config.GetNotionUrl(), a.ApiKey)
+}
+
+func configClient() (*notion.Client, string, error) {
+	databaseId := config.GetNotionConfigDatabaseId()
+	if databaseId == "" {
+		return nil, "", errors.New("NOTION_CONFIG_DATABASE_ID 未设置")
+	}
+	apiKey := config.GetNotionApiKey()
+	if apiKey == "" {
+		return nil, "", errors.New("NOTION_API_KEY 未设置")
+	}
+	return notion.New(config.GetNotionUrl(), apiKey), databaseId, nil
+}
+
+// findAccountPage 查询配置数据库中用户和数据库类型对应的记录，没有时返回nil
+func findAccountPage(c *notion.Client, configDatabaseId, userId, databaseType string) (*notion.Page, error) {
+	pages, err := c.QueryDatabase(configDatabaseId, notion.Query{
+		Filter: notion.And(
+			notion.TitleEquals(Prop_User_Id, userId),
+			notion.SelectEquals(Prop_Database_Type, databaseType),
+		),
+		Limit: 1,
+	})
+	if err != nil || len(pages) == 0 {
+		return nil, err
+	}
+	return &pages[0], nil
+}
+
+// GetAccount 查询用户绑定的数据库，没有绑定时返回ErrNoAccount
+func GetAccount(userId, databaseType string) (*Account, error) {
+	c, configDatabaseId, err := configClient()
+	if err != nil {
+		return nil, err
+	}
+	page, err := findAccountPage(c, configDatabaseId, userId, databaseType)
+	if err != nil {
+		return nil, fmt.Errorf("查询记账账号失败: %w", err)
+	}
+	if page == nil {
+		return nil, ErrNoAccount
+	}
+	props := page.Properties
+	account := &Account{
+		UserId:     props[Prop_User_Id].PlainText(),
+		ApiKey:     props[Prop_Api_Key].PlainText(),
+		DatabaseId: props[Prop_Database_Id].PlainText(),
+		Type:       props[Prop_Database_Type].SelectName(),
+	}
+	if account.ApiKey == "" || account.DatabaseId == "" {
+		return nil, ErrNoAccount
+	}
+	return account, nil
+}
+
+// SaveAccount 绑定记账数据库，已绑定同类型的数据库时覆盖，updated表示是否为覆盖
+func SaveAccount(userId, apiKey, databaseId, databaseType string) (updated bool, err error) {
+	c, configDatabaseId, err := configClient()
+	if err != nil {
+		return false, err
+	}
+	page, err := findAccountPage(c, configDatabaseId, userId, databaseType)
+	if err != nil {
+		return false, fmt.Errorf("查询记账账号失败: %w", err)
+	}
+	props := map[string]notion.Property{
+		Prop_User_Id:       notion.Title(userId),
+		Prop_Api_Key:       notion.RichTextOf(apiKey),
+		Prop_Database_Id:   notion.RichTextOf(databaseId),
+		Prop_Database_Type: notion.Select(databaseType),
+	}
+	if page != nil {
+		_, err = c.UpdatePage(page.Id, props)
+		return true, err
+	}
+	_, err = c.CreatePage(configDatabaseId, props)
+	return false, err
+}
+
+// DeleteAccount 解除绑定，配置数据库中的记录会被归档，没有绑定时返回ErrNoAccount
+func DeleteAccount(userId, databaseType string) error {
+	c, configDatabaseId, err := configClient()
+	if err != nil {
+		return err
+	}
+	page, err := findAccountPage(c, configDatabaseId, userId, databaseType)
+	if err != nil {
+		return fmt.Errorf("查询记账账号失败: %w", err)
+	}
+	if page == nil {
+		return ErrNoAccount
+	}
+	return c.ArchivePage(page.Id)
+}
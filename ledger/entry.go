@@ -0,0 +1,288 @@
+package ledger
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pwh-pwh/aiwechat-vercel/notion"
+)
+
+// Entry 可以写入Notion数据库的一条记录
+type Entry interface {
+	Title() string
+	Value(field string) string
+	Properties(s Schema) map[string]notion.Property
+	Validate(s Schema) error
+}
+
+// Expense 记账数据库的一笔支出，json字段名为字段名，写入时按Schema换成属性名
+type Expense struct {
+	Name        string  `json:"名称"`
+	Amount      float64 `json:"金额"`
+	Tag         string  `json:"标签"`
+	Date        string  `json:"日期"`
+	Payment     string  `json:"支付方式"`
+	Type        string  `json:"开支类型"`
+	Description string  `json:"说明,omitempty"`
+	Remark      string  `json:"备注,omitempty"`
+}
+
+func (e Expense) Title() string {
+	return e.Name
+}
+
+func (e Expense) Value(field string) string {
+	switch field {
+	case Field_Name:
+		return e.Name
+	case Field_Amount:
+		return formatAmount(e.Amount)
+	case Field_Tag:
+		return e.Tag
+	case Field_Date:
+		return e.Date
+	case Field_Payment:
+		return e.Payment
+	case Field_Type:
+		return e.Type
+	case Field_Description:
+		return e.Description
+	case Field_Remark:
+		return e.Remark
+	}
+	return ""
+}
+
+// Set 按字段名修改记录，用于用户纠正模型识别的结果
+func (e *Expense) Set(field, value string) (err error) {
+	switch field {
+	case Field_Name:
+		e.Name = value
+	case Field_Amount:
+		e.Amount, err = parseAmount(value)
+	case Field_Tag:
+		e.Tag = value
+	case Field_Date:
+		e.Date = value
+	case Field_Payment:
+		e.Payment = value
+	case Field_Type:
+		e.Type = value
+	case Field_Description:
+		e.Description = value
+	case Field_Remark:
+		e.Remark = value
+	default:
+		return ErrUnknownField
+	}
+	return
+}
+
+func (e Expense) Properties(s Schema) map[string]notion.Property {
+	return properties(s, map[string]notion.Property{
+		Field_Name:        notion.Title(e.Name),
+		Field_Amount:      notion.Number(e.Amount),
+		Field_Tag:         notion.Select(e.Tag),
+		Field_Date:        notion.DateOf(e.Date),
+		Field_Payment:     notion.Select(e.Payment),
+		Field_Type:        notion.Select(e.Type),
+		Field_Description: notion.RichTextOf(e.Description),
+		Field_Remark:      notion.RichTextOf(e.Remark),
+	})
+}
+
+func (e Expense) Validate(s Schema) error {
+	if err := validate(e.Name, e.Amount, e.Date); err != nil {
+		return err
+	}
+	for _, c := range [][2]string{{Field_Tag, e.Tag}, {Field_Payment, e.Payment}, {Field_Type, e.Type}} {
+		if err := s.Check(c[0], c[1]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Income 工资数据库的一笔收入
+type Income struct {
+	Name    string  `json:"名称"`
+	Amount  float64 `json:"金额"`
+	Tags    Tags    `json:"标签"`
+	Date    string  `json:"日期"`
+	Company string  `json:"单位"`
+}
+
+func (i Income) Title() string {
+	return i.Name
+}
+
+func (i Income) Value(field string) string {
+	switch field {
+	case Field_Name:
+		return i.Name
+	case Field_Amount:
+		return formatAmount(i.Amount)
+	case Field_Tag:
+		return strings.Join(i.Tags, "、")
+	case Field_Date:
+		return i.Date
+	case Field_Company:
+		return i.Company
+	}
+	return ""
+}
+
+// Set 按字段名修改记录，标签可以用逗号分隔多个
+func (i *Income) Set(field, value string) (err error) {
+	switch field {
+	case Field_Name:
+		i.Name = value
+	case Field_Amount:
+		i.Amount, err = parseAmount(value)
+	case Field_Tag:
+		i.Tags = strings.FieldsFunc(value, func(r rune) bool { return r == ',' || r == '，' || r == '、' })
+	case Field_Date:
+		i.Date = value
+	case Field_Company:
+		i.Company = value
+	default:
+		return ErrUnknownField
+	}
+	return
+}
+
+func (i Income) Properties(s Schema) map[string]notion.Property {
+	return properties(s, map[string]notion.Property{
+		Field_Name:    notion.Title(i.Name),
+		Field_Tag:     notion.MultiSelect(i.Tags...),
+		Field_Date:    notion.DateOf(i.Date),
+		Field_Amount:  notion.Number(i.Amount),
+		Field_Company: notion.Select(i.Company),
+	})
+}
+
+func (i Income) Validate(s Schema) error {
+	if err := validate(i.Name, i.Amount, i.Date); err != nil {
+		return err
+	}
+	for _, tag := range i.Tags {
+		if err := s.Check(Field_Tag, tag); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// properties 按Schema把字段名换成Notion属性名，跳过不写入的字段
+func properties(s Schema, fields map[string]notion.Property) map[string]notion.Property {
+	props := make(map[string]notion.Property, len(fields))
+	for field, p := range fields {
+		if property := s.Property(field); property != "" {
+			props[property] = p
+		}
+	}
+	return props
+}
+
+func validate(name string, amount float64, date string) error {
+	if strings.TrimSpace(name) == "" {
+		return fmt.Errorf("%s不能为空", Field_Name)
+	}
+	if amount <= 0 {
+		return fmt.Errorf("%s必须大于0: %v", Field_Amount, amount)
+	}
+	if _, err := time.Parse(time.DateOnly, date); err != nil {
+		return fmt.Errorf("%s格式不正确: %s", Field_Date, date)
+	}
+	return nil
+}
+
+// formatAmount 金额保留到分，去掉末尾的0
+func formatAmount(amount float64) string {
+	return strconv.FormatFloat(math.Round(amount*100)/100, 'f', -1, 64)
+}
+
+// parseAmount 解析用户输入的金额，允许带 元
+func parseAmount(value string) (float64, error) {
+	amount, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(value), "元"), 64)
+	if err != nil {
+		return 0, fmt.Errorf("%s格式不正确: %s", Field_Amount, value)
+	}
+	return amount, nil
+}
+
+// Tags 多选标签，模型有时返回单个字符串，有时返回数组
+type Tags []string
+
+func (t *Tags) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*t = Tags{s}
+		return nil
+	}
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return err
+	}
+	*t = list
+	return nil
+}
+
+// ValidateAll 检查全部记录，有多条时错误中标明是第几条
+func ValidateAll[T Entry](s Schema, entries []T) error {
+	if len(entries) == 0 {
+		return errors.New("没有识别到记录")
+	}
+	for i, entry := range entries {
+		if err := entry.Validate(s); err != nil {
+			if len(entries) == 1 {
+				return err
+			}
+			return fmt.Errorf("第%d条%v", i+1, err)
+		}
+	}
+	return nil
+}
+
+// Result 一条记录的写入结果
+type Result struct {
+	Name   string
+	PageId string
+	Err    error
+}
+
+// Insert 按Schema逐条写入用户的数据库，某条失败时继续写入其余记录，写入成功的记录保存到历史中
+func Insert[T Entry](account *Account, s Schema, entries []T) []Result {
+	c := account.Client()
+	results := make([]Result, 0, len(entries))
+	for _, entry := range entries {
+		r := Result{Name: entry.Title()}
+		page, err := c.CreatePage(account.DatabaseId, entry.Properties(s))
+		if err != nil {
+			r.Err = err
+		} else {
+			r.PageId = page.Id
+		}
+		results = append(results, r)
+	}
+	addHistory(account, entries, results)
+	return results
+}
+
+// Feedback 每条记录一行的写入结果
+func Feedback(results []Result) string {
+	var lines []string
+	for _, r := range results {
+		if r.Err != nil {
+			lines = append(lines, fmt.Sprintf("Failed to add %s: %v", r.Name, r.Err))
+		} else {
+			lines = append(lines, fmt.Sprintf("Successfully added: %s", r.Name))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
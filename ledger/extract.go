@@ -0,0 +1,118 @@
+package ledger
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/bytedance/sonic"
+)
+
+// Completer 调用模型按json schema输出json
+type Completer func(system, prompt string, schema map[string]any) (string, error)
+
+// JSONSchema 模型输出的json schema，records为记录列表，单选、多选字段限制为可选值
+func (s Schema) JSONSchema() map[string]any {
+	properties := map[string]any{}
+	required := []string{}
+	for _, field := range s.Fields() {
+		if s.Property(field) == "" {
+			continue
+		}
+		property := map[string]any{"type": "string"}
+		switch field {
+		case Field_Amount:
+			property["type"] = "number"
+		case Field_Date:
+			property["description"] = "格式为YYYY-MM-DD"
+		}
+		if choices := s.Choices(field); len(choices) > 0 && slices.Contains(choiceFields[s.Type], field) {
+			property["enum"] = choices
+		}
+		if s.Type == Type_Income && field == Field_Tag {
+			property = map[string]any{"type": "array", "items": property}
+		}
+		properties[field] = property
+		if !slices.Contains(optionalFields, field) {
+			required = append(required, field)
+		}
+	}
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"records": map[string]any{
+				"type":  "array",
+				"items": map[string]any{"type": "object", "properties": properties, "required": required},
+			},
+		},
+		"required": []string{"records"},
+	}
+}
+
+// system 识别记录的系统提示
+func (s Schema) system(today time.Time) string {
+	var sb strings.Builder
+	if s.Type == Type_Income {
+		sb.WriteString("你是记账助手，请把用户描述的工资或收入整理成记录，一次可以有多条。")
+	} else {
+		sb.WriteString("你是记账助手，请把用户描述的支出整理成记录，一次可以有多条。")
+	}
+	fmt.Fprintf(&sb, "今天是%s，没有指定日期时使用今天；没有金额时估算一个合理的数值。", today.Format(time.DateOnly))
+	if s.Type == Type_Income {
+		sb.WriteString("单位默认是公司单位。")
+	}
+	if prompt := s.Prompt(); prompt != "" {
+		sb.WriteString(prompt + "。")
+	}
+	return sb.String()
+}
+
+// ExtractExpenses 用模型从文字中识别支出记录并校验
+func ExtractExpenses(complete Completer, s Schema, text string, today time.Time) ([]Expense, error) {
+	expenses, err := extract[Expense](complete, s, text, today)
+	if err != nil {
+		return nil, err
+	}
+	for i := range expenses {
+		if expenses[i].Date == "" {
+			expenses[i].Date = today.Format(time.DateOnly)
+		}
+	}
+	return expenses, ValidateAll(s, expenses)
+}
+
+// ExtractIncomes 用模型从文字中识别工资记录并校验
+func ExtractIncomes(complete Completer, s Schema, text string, today time.Time) ([]Income, error) {
+	incomes, err := extract[Income](complete, s, text, today)
+	if err != nil {
+		return nil, err
+	}
+	for i := range incomes {
+		if incomes[i].Date == "" {
+			incomes[i].Date = today.Format(time.DateOnly)
+		}
+	}
+	return incomes, ValidateAll(s, incomes)
+}
+
+func extract[T Entry](complete Completer, s Schema, text string, today time.Time) ([]T, error) {
+	r, err := complete(s.system(today), text, s.JSONSchema())
+	if err != nil {
+		return nil, err
+	}
+	r = strings.TrimSpace(r)
+	var result struct {
+		Records []T `json:"records"`
+	}
+	// 不支持json模式的模型有时直接返回数组
+	if strings.HasPrefix(r, "[") {
+		err = sonic.UnmarshalString(r, &result.Records)
+	} else {
+		err = sonic.UnmarshalString(r, &result)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("模型返回的json格式不正确: %v", err)
+	}
+	return result.Records, nil
+}
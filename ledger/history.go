@@ -0,0 +1,161 @@
+package ledger
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/bytedance/sonic"
+	"github.com/pwh-pwh/aiwechat-vercel/db"
+	"github.com/pwh-pwh/aiwechat-vercel/notion"
+)
+
+// Max_History 每个用户保存最近写入的记录数
+const Max_History = 50
+
+// Record 写入成功的一条记录，Batch相同的记录是同一次写入的，按数据库类型只使用Expense或Income
+type Record struct {
+	PageId  string
+	Type    string
+	Batch   int64
+	Expense *Expense `json:",omitempty"`
+	Income  *Income  `json:",omitempty"`
+}
+
+func (r Record) Entry() Entry {
+	if r.Type == Type_Income {
+		return *r.Income
+	}
+	return *r.Expense
+}
+
+// String 日期、名称和金额，收入加上标记
+func (r Record) String() string {
+	entry := r.Entry()
+	s := fmt.Sprintf("%s %s %s元", entry.Value(Field_Date), entry.Title(), entry.Value(Field_Amount))
+	if r.Type == Type_Income {
+		s += "（收入）"
+	}
+	return s
+}
+
+// GetHistory 最近写入的记录，最新的在前面
+func GetHistory(userId string) []Record {
+	var history []Record
+	if val, err := db.GetLedgerHistory(userId); err == nil && val != "" {
+		sonic.UnmarshalString(val, &history)
+	}
+	return history
+}
+
+func saveHistory(userId string, history []Record) error {
+	if len(history) == 0 {
+		return db.SetLedgerHistory(userId, "")
+	}
+	if len(history) > Max_History {
+		history = history[:Max_History]
+	}
+	data, err := sonic.MarshalString(history)
+	if err != nil {
+		return err
+	}
+	return db.SetLedgerHistory(userId, data)
+}
+
+// addHistory 保存写入成功的记录，同一次写入的记录作为一批
+func addHistory[T Entry](account *Account, entries []T, results []Result) {
+	batch := time.Now().UnixNano()
+	var records []Record
+	for i := len(entries) - 1; i >= 0; i-- {
+		if results[i].Err != nil {
+			continue
+		}
+		r := Record{PageId: results[i].PageId, Type: account.Type, Batch: batch}
+		switch e := any(entries[i]).(type) {
+		case Expense:
+			r.Expense = &e
+		case Income:
+			r.Income = &e
+		}
+		records = append(records, r)
+	}
+	if len(records) == 0 {
+		return
+	}
+	if err := saveHistory(account.UserId, append(records, GetHistory(account.UserId)...)); err != nil {
+		log.Println("Error saving ledger history:", err)
+	}
+}
+
+// Undo 归档最近一次写入的全部记录，归档失败的记录保留在历史中
+func Undo(userId string) ([]Result, error) {
+	history := GetHistory(userId)
+	if len(history) == 0 {
+		return nil, errors.New("没有可以撤销的记录")
+	}
+	n := 1
+	for n < len(history) && history[n].Batch == history[0].Batch {
+		n++
+	}
+	var results []Result
+	var remaining []Record
+	for _, r := range history[:n] {
+		result := Result{Name: r.Entry().Title(), PageId: r.PageId}
+		var account *Account
+		if account, result.Err = GetAccount(userId, r.Type); result.Err == nil {
+			result.Err = account.Client().ArchivePage(r.PageId)
+		}
+		if result.Err != nil {
+			remaining = append(remaining, r)
+		}
+		results = append(results, result)
+	}
+	return results, saveHistory(userId, append(remaining, history[n:]...))
+}
+
+// Edit 修改最近第index条(从1开始)记录的字段，只更新该字段对应的属性
+// 字段不存在时同时返回未修改的记录和ErrUnknownField，用于提示该类型可以修改的字段
+func Edit(userId string, index int, field, value string) (*Record, error) {
+	history := GetHistory(userId)
+	if index < 1 || index > len(history) {
+		return nil, fmt.Errorf("没有第%d条记录", index)
+	}
+	r := history[index-1]
+	account, err := GetAccount(userId, r.Type)
+	if err != nil {
+		return nil, err
+	}
+	s := LoadSchema(account)
+	var entry Entry
+	if r.Type == Type_Income {
+		income := *r.Income
+		if err = income.Set(field, value); errors.Is(err, ErrUnknownField) {
+			return &r, err
+		} else if err != nil {
+			return nil, err
+		}
+		entry, r.Income = income, &income
+	} else {
+		expense := *r.Expense
+		if err = expense.Set(field, value); errors.Is(err, ErrUnknownField) {
+			return &r, err
+		} else if err != nil {
+			return nil, err
+		}
+		entry, r.Expense = expense, &expense
+	}
+	if err = entry.Validate(s); err != nil {
+		return nil, err
+	}
+	property := s.Property(field)
+	if property == "" {
+		return nil, fmt.Errorf("%s不写入数据库", field)
+	}
+	props := map[string]notion.Property{property: entry.Properties(s)[property]}
+	if _, err = account.Client().UpdatePage(r.PageId, props); err != nil {
+		return nil, err
+	}
+	history[index-1] = r
+	return &r, saveHistory(userId, history)
+}
@@ -0,0 +1,486 @@
+package ledger
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pwh-pwh/aiwechat-vercel/config"
+)
+
+// fakeNotion 在内存中模拟Notion的建页、改页和查询接口，查询只支持配置数据库的用户id和数据库类型过滤
+type fakeNotion struct {
+	mu        sync.Mutex
+	pages     map[string]map[string]any
+	parent    map[string]string
+	order     []string
+	tokens    []string
+	databases map[string]string
+	retrieved int
+}
+
+func newFakeNotion(t *testing.T) *fakeNotion {
+	f := &fakeNotion{pages: map[string]map[string]any{}, parent: map[string]string{}, databases: map[string]string{}}
+	server := httptest.NewServer(f)
+	t.Cleanup(server.Close)
+	t.Setenv(config.Notion_Url_Key, server.URL)
+	t.Setenv(config.Notion_Api_Key_Key, "config-secret")
+	t.Setenv(config.Notion_Config_Database_Id_Key, "config-db")
+	return f
+}
+
+func (f *fakeNotion) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.tokens = append(f.tokens, strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "))
+	var body map[string]any
+	json.NewDecoder(r.Body).Decode(&body)
+	switch {
+	case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/v1/databases/"):
+		f.retrieved++
+		database, ok := f.databases[strings.TrimPrefix(r.URL.Path, "/v1/databases/")]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(database))
+	case r.Method == http.MethodPost && r.URL.Path == "/v1/pages":
+		id := fmt.Sprintf("page%d", len(f.order)+1)
+		if name, _ := json.Marshal(body["properties"]); strings.Contains(string(name), "坏数据") {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"status":400,"code":"validation_error","message":"bad"}`))
+			return
+		}
+		f.pages[id] = body["properties"].(map[string]any)
+		f.parent[id] = body["parent"].(map[string]any)["database_id"].(string)
+		f.order = append(f.order, id)
+		json.NewEncoder(w).Encode(map[string]any{"id": id})
+	case r.Method == http.MethodPatch && strings.HasPrefix(r.URL.Path, "/v1/pages/"):
+		id := strings.TrimPrefix(r.URL.Path, "/v1/pages/")
+		if _, ok := f.pages[id]; !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if body["archived"] == true {
+			delete(f.pages, id)
+		} else {
+			for k, v := range body["properties"].(map[string]any) {
+				f.pages[id][k] = v
+			}
+		}
+		json.NewEncoder(w).Encode(map[string]any{"id": id})
+	case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/query"):
+		databaseId := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/v1/databases/"), "/query")
+		data, _ := json.Marshal(body["filter"])
+		var filter struct {
+			And []struct {
+				Title  *struct{ Equals string } `json:"title"`
+				Select *struct{ Equals string } `json:"select"`
+			} `json:"and"`
+		}
+		json.Unmarshal(data, &filter)
+		var results []any
+		for _, id := range f.order {
+			props, ok := f.pages[id]
+			if !ok || f.parent[id] != databaseId {
+				continue
+			}
+			match := true
+			for _, cond := range filter.And {
+				if cond.Title != nil && plainText(props[Prop_User_Id], "title") != cond.Title.Equals {
+					match = false
+				}
+				if cond.Select != nil && selectName(props[Prop_Database_Type]) != cond.Select.Equals {
+					match = false
+				}
+			}
+			if match {
+				results = append(results, map[string]any{"id": id, "properties": readable(props)})
+			}
+		}
+		json.NewEncoder(w).Encode(map[string]any{"results": results, "has_more": false})
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func plainText(prop any, typ string) string {
+	list, _ := prop.(map[string]any)[typ].([]any)
+	var sb strings.Builder
+	for _, item := range list {
+		sb.WriteString(item.(map[string]any)["text"].(map[string]any)["content"].(string))
+	}
+	return sb.String()
+}
+
+func selectName(prop any) string {
+	s, _ := prop.(map[string]any)["select"].(map[string]any)
+	name, _ := s["name"].(string)
+	return name
+}
+
+// readable 把写入的文本转换为读取时带plain_text的格式
+func readable(props map[string]any) map[string]any {
+	r := map[string]any{}
+	for k, v := range props {
+		prop := v.(map[string]any)
+		for _, typ := range []string{"title", "rich_text"} {
+			if _, ok := prop[typ]; ok {
+				prop = map[string]any{"type": typ, typ: []any{map[string]any{"plain_text": plainText(prop, typ)}}}
+			}
+		}
+		r[k] = prop
+	}
+	return r
+}
+
+func TestAccount(t *testing.T) {
+	f := newFakeNotion(t)
+
+	if _, err := GetAccount("u1", Type_Expense); !errors.Is(err, ErrNoAccount) {
+		t.Fatalf("GetAccount without account = %v", err)
+	}
+	if updated, err := SaveAccount("u1", "key1", "db1", Type_Expense); err != nil || updated {
+		t.Fatalf("SaveAccount = %v, %v", updated, err)
+	}
+	if updated, err := SaveAccount("u1", "key2", "db2", Type_Expense); err != nil || !updated {
+		t.Fatalf("SaveAccount again = %v, %v", updated, err)
+	}
+	if _, err := SaveAccount("u1", "key3", "db3", Type_Income); err != nil {
+		t.Fatal(err)
+	}
+	if len(f.pages) != 2 {
+		t.Errorf("pages = %v", f.pages)
+	}
+	account, err := GetAccount("u1", Type_Expense)
+	if err != nil || *account != (Account{UserId: "u1", ApiKey: "key2", DatabaseId: "db2", Type: Type_Expense}) {
+		t.Errorf("GetAccount = %+v, %v", account, err)
+	}
+	if account, err = GetAccount("u1", Type_Income); err != nil || account.DatabaseId != "db3" {
+		t.Errorf("GetAccount income = %+v, %v", account, err)
+	}
+	if f.tokens[0] != "config-secret" {
+		t.Errorf("config token = %v", f.tokens)
+	}
+
+	if err = DeleteAccount("u1", Type_Expense); err != nil {
+		t.Fatal(err)
+	}
+	if err = DeleteAccount("u1", Type_Expense); !errors.Is(err, ErrNoAccount) {
+		t.Errorf("DeleteAccount again = %v", err)
+	}
+	if _, err = GetAccount("u1", Type_Income); err != nil {
+		t.Errorf("income account deleted: %v", err)
+	}
+
+	t.Setenv(config.Notion_Config_Database_Id_Key, "")
+	if _, err = GetAccount("u1", Type_Income); err == nil || err.Error() != "NOTION_CONFIG_DATABASE_ID 未设置" {
+		t.Errorf("GetAccount without config = %v", err)
+	}
+}
+
+func TestInsert(t *testing.T) {
+	f := newFakeNotion(t)
+	t.Cleanup(func() { saveHistory("u1", nil) })
+	account := &Account{UserId: "u1", ApiKey: "user-secret", DatabaseId: "db1", Type: Type_Expense}
+
+	results := Insert(account, defaultSchema(Type_Expense), []Expense{
+		{Name: "午饭", Amount: 35, Tag: "生活吃喝加买菜", Date: "2025-01-12", Payment: "微信", Type: "日常开支"},
+		{Name: "坏数据", Amount: 1},
+	})
+	if len(results) != 2 || results[0].PageId == "" || results[1].Err == nil {
+		t.Fatalf("Insert = %+v", results)
+	}
+	if feedback := Feedback(results); feedback != "Successfully added: 午饭\nFailed to add 坏数据: notion validation_error: bad" {
+		t.Errorf("Feedback = %q", feedback)
+	}
+	props := f.pages[results[0].PageId]
+	if f.parent[results[0].PageId] != "db1" || f.tokens[0] != "user-secret" || plainText(props["名称"], "title") != "午饭" ||
+		props["金额"].(map[string]any)["number"] != float64(35) || selectName(props["支付方式"]) != "微信" {
+		t.Errorf("page = %v", props)
+	}
+
+	var incomes []Income
+	if err := json.Unmarshal([]byte(`[{"名称":"1月工资","标签":"固定工资","日期":"2025-01-12","金额":10000,"单位":"某公司"},
+		{"名称":"稿费","标签":["其他兼职","兼职写代码"],"日期":"2025-01-13","金额":500,"单位":"某人"}]`), &incomes); err != nil {
+		t.Fatal(err)
+	}
+	if len(incomes[0].Tags) != 1 || len(incomes[1].Tags) != 2 {
+		t.Errorf("incomes = %+v", incomes)
+	}
+	results = Insert(account, defaultSchema(Type_Income), incomes)
+	props = f.pages[results[1].PageId]
+	if tags := props["标签"].(map[string]any)["multi_select"].([]any); len(tags) != 2 || selectName(props["工作单位"]) != "某人" {
+		t.Errorf("income page = %v", props)
+	}
+}
+
+func TestSchema(t *testing.T) {
+	f := newFakeNotion(t)
+	f.databases["schema-db"] = `{"id":"schema-db","properties":{
+		"名称":{"name":"名称","type":"title","title":{}},
+		"花费":{"name":"花费","type":"number","number":{}},
+		"标签":{"name":"标签","type":"select","select":{"options":[{"name":"吃饭"},{"name":"交通"}]}},
+		"支付方式":{"name":"支付方式","type":"select","select":{"options":[{"name":"微信"},{"name":"信用卡"}]}},
+		"开支类型":{"name":"开支类型","type":"select","select":{"options":[]}},
+		"日期":{"name":"日期","type":"date","date":{}},
+		"备注":{"name":"备注","type":"rich_text","rich_text":{}}}}`
+	account := &Account{UserId: "schema-u1", ApiKey: "key", DatabaseId: "schema-db", Type: Type_Expense}
+	t.Cleanup(func() {
+		ResetSettings(account.UserId, Type_Expense)
+		RefreshSchema(account)
+	})
+
+	if err := SetProperty(account.UserId, Type_Expense, Field_Amount, "花费"); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetProperty(account.UserId, Type_Expense, "颜色", "x"); !errors.Is(err, ErrUnknownField) {
+		t.Errorf("SetProperty unknown = %v", err)
+	}
+	if err := SetProperty(account.UserId, Type_Expense, Field_Name, ""); err == nil {
+		t.Errorf("SetProperty required field to empty should fail")
+	}
+	if err := SetOptions(account.UserId, Type_Expense, Field_Tag, []string{"吃饭", "交通", "数码"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetOptions(account.UserId, Type_Expense, Field_Date, []string{"x"}); err == nil {
+		t.Errorf("SetOptions on 日期 should fail")
+	}
+
+	s := LoadSchema(account)
+	// 标签使用用户设置，支付方式使用数据库选项，开支类型数据库没有选项时使用默认值，说明在数据库中不存在
+	if strings.Join(s.Choices(Field_Tag), ",") != "吃饭,交通,数码" || strings.Join(s.Choices(Field_Payment), ",") != "微信,信用卡" ||
+		len(s.Choices(Field_Type)) != len(DefaultOptions(Type_Expense, Field_Type)) || s.Property(Field_Description) != "" || s.Property(Field_Amount) != "花费" {
+		t.Fatalf("schema = %+v", s)
+	}
+	if !strings.Contains(s.Prompt(), "支付方式只能从以下选项中选择：微信、信用卡") {
+		t.Errorf("Prompt = %s", s.Prompt())
+	}
+	if !strings.Contains(s.String(), "金额：属性「花费」") || !strings.Contains(s.String(), "说明：不写入") {
+		t.Errorf("String = %s", s)
+	}
+
+	expense := Expense{Name: "打车", Amount: 30, Tag: "交通", Date: "2025-01-12", Payment: "银行卡", Type: "日常开支", Description: "加班"}
+	if err := expense.Validate(s); err == nil || err.Error() != "支付方式只能是微信、信用卡，不能是银行卡" {
+		t.Errorf("Validate = %v", err)
+	}
+	expense.Payment = "信用卡"
+	if err := ValidateAll(s, []Expense{expense, {Name: "x", Amount: 0, Date: "2025-01-12"}}); err == nil || !strings.HasPrefix(err.Error(), "第2条金额必须大于0") {
+		t.Errorf("ValidateAll = %v", err)
+	}
+	props := expense.Properties(s)
+	if _, ok := props["说明"]; ok || props["花费"].NumberValue() != 30 || len(props) != 7 {
+		t.Errorf("Properties = %v", props)
+	}
+
+	// 数据库属性缓存后不再重复读取，refresh后重新读取
+	LoadSchema(account)
+	if f.retrieved != 1 {
+		t.Errorf("retrieved = %d", f.retrieved)
+	}
+	RefreshSchema(account)
+	LoadSchema(account)
+	if f.retrieved != 2 {
+		t.Errorf("retrieved after refresh = %d", f.retrieved)
+	}
+
+	// 读取数据库失败时使用默认选项
+	s = LoadSchema(&Account{UserId: "schema-u2", ApiKey: "key", DatabaseId: "missing-db", Type: Type_Expense})
+	if strings.Join(s.Choices(Field_Payment), ",") != "支付宝,微信,银行卡" || s.Property(Field_Description) != Field_Description {
+		t.Errorf("default schema = %+v", s)
+	}
+}
+
+func TestExtract(t *testing.T) {
+	today := time.Date(2025, 3, 8, 0, 0, 0, 0, time.Local)
+	var system string
+	var schema map[string]any
+	complete := func(reply string) Completer {
+		return func(s, prompt string, js map[string]any) (string, error) {
+			system, schema = s, js
+			return reply, nil
+		}
+	}
+
+	expenses, err := ExtractExpenses(complete(`{"records":[{"名称":"打车","金额":20,"标签":"出行","日期":"","支付方式":"微信","开支类型":"日常开支"}]}`),
+		defaultSchema(Type_Expense), "打车20", today)
+	if err != nil || len(expenses) != 1 || expenses[0].Date != "2025-03-08" {
+		t.Fatalf("ExtractExpenses = %+v, %v", expenses, err)
+	}
+	if !strings.Contains(system, "今天是2025-03-08") || !strings.Contains(system, "支付方式只能从以下选项中选择") {
+		t.Errorf("system = %q", system)
+	}
+	items := schema["properties"].(map[string]any)["records"].(map[string]any)["items"].(map[string]any)
+	if payment := items["properties"].(map[string]any)[Field_Payment].(map[string]any); len(payment["enum"].([]string)) != 3 {
+		t.Errorf("支付方式 = %v", payment)
+	}
+	if required := items["required"].([]string); slices.Contains(required, Field_Remark) || !slices.Contains(required, Field_Amount) {
+		t.Errorf("required = %v", required)
+	}
+
+	if _, err := ExtractExpenses(complete(`{"records":[{"名称":"打车","金额":20,"标签":"出行","日期":"2025-03-08","支付方式":"现金","开支类型":"日常开支"}]}`),
+		defaultSchema(Type_Expense), "打车20", today); err == nil || !strings.Contains(err.Error(), "不能是现金") {
+		t.Errorf("invalid payment err = %v", err)
+	}
+	if _, err := ExtractExpenses(complete("好的"), defaultSchema(Type_Expense), "打车20", today); err == nil {
+		t.Error("expected json error")
+	}
+
+	// 不支持json模式的模型直接返回数组，标签为字符串
+	incomes, err := ExtractIncomes(complete(`[{"名称":"3月工资","金额":10000,"标签":"固定工资","日期":"2025-03-05","单位":"公司"}]`),
+		defaultSchema(Type_Income), "3月工资10000", today)
+	if err != nil || len(incomes) != 1 || incomes[0].Tags[0] != "固定工资" {
+		t.Fatalf("ExtractIncomes = %+v, %v", incomes, err)
+	}
+	tag := schema["properties"].(map[string]any)["records"].(map[string]any)["items"].(map[string]any)["properties"].(map[string]any)[Field_Tag].(map[string]any)
+	if tag["type"] != "array" {
+		t.Errorf("income 标签 = %v", tag)
+	}
+}
+
+func TestPending(t *testing.T) {
+	t.Cleanup(func() { DeletePending("pending-u1") })
+	s := defaultSchema(Type_Income)
+	batch := &Batch{Type: Type_Income, Incomes: []Income{{Name: "3月工资", Amount: 10000, Tags: Tags{"固定工资"}, Date: "2025-03-05", Company: "公司"}}}
+	if err := batch.Edit(s, 1, Field_Tag, "固定工资，其他兼职"); err != nil || len(batch.Incomes[0].Tags) != 2 {
+		t.Fatalf("edit tags = %v, %v", batch.Incomes[0].Tags, err)
+	}
+	if err := batch.Edit(s, 1, Field_Amount, "abc"); err == nil || batch.Incomes[0].Amount != 10000 {
+		t.Errorf("edit invalid amount = %v, %v", batch.Incomes[0].Amount, err)
+	}
+	if err := batch.Edit(s, 1, Field_Payment, "微信"); !errors.Is(err, ErrUnknownField) {
+		t.Errorf("edit unknown field = %v", err)
+	}
+	if p := batch.Preview(s); p != "1. 3月工资 10000元\n   标签：固定工资、其他兼职，日期：2025-03-05，单位：公司" {
+		t.Errorf("preview = %q", p)
+	}
+
+	if err := SavePending("pending-u1", batch); err != nil {
+		t.Fatal(err)
+	}
+	if b := GetPending("pending-u1"); b == nil || b.Type != Type_Income || b.Incomes[0].Tags[1] != "其他兼职" {
+		t.Fatalf("pending = %+v", b)
+	}
+	if b := TakePending("pending-u1"); b == nil || b.Len() != 1 {
+		t.Fatalf("take pending = %+v", b)
+	}
+	if b := TakePending("pending-u1"); b != nil {
+		t.Errorf("pending taken twice = %+v", b)
+	}
+	SavePending("pending-u1", batch)
+	DeletePending("pending-u1")
+	if b := GetPending("pending-u1"); b != nil {
+		t.Errorf("pending after delete = %+v", b)
+	}
+}
+
+func TestHistory(t *testing.T) {
+	f := newFakeNotion(t)
+	t.Cleanup(func() { saveHistory("history-u1", nil) })
+	if _, err := SaveAccount("history-u1", "user-secret", "db1", Type_Expense); err != nil {
+		t.Fatal(err)
+	}
+	account, _ := GetAccount("history-u1", Type_Expense)
+	s := defaultSchema(Type_Expense)
+	Insert(account, s, []Expense{
+		{Name: "午饭", Amount: 35, Tag: "生活吃喝加买菜", Date: "2025-01-12", Payment: "微信", Type: "日常开支"},
+		{Name: "坏数据", Amount: 1},
+		{Name: "晚饭", Amount: 40, Tag: "生活吃喝加买菜", Date: "2025-01-12", Payment: "微信", Type: "日常开支"},
+	})
+	time.Sleep(time.Millisecond)
+	Insert(account, s, []Expense{{Name: "打车", Amount: 20, Tag: "出行", Date: "2025-01-13", Payment: "支付宝", Type: "日常开支"}})
+
+	history := GetHistory("history-u1")
+	if len(history) != 3 || history[0].String() != "2025-01-13 打车 20元" || history[2].Entry().Title() != "午饭" {
+		t.Fatalf("history = %+v", history)
+	}
+
+	if _, err := Edit("history-u1", 1, Field_Amount, "25"); err != nil {
+		t.Fatal(err)
+	}
+	page := f.pages[history[0].PageId]
+	if page[Field_Amount].(map[string]any)["number"] != 25.0 {
+		t.Errorf("edited page = %v", page)
+	}
+	if r := GetHistory("history-u1")[0]; r.Expense.Amount != 25 || r.Expense.Payment != "支付宝" {
+		t.Errorf("edited record = %+v", r.Expense)
+	}
+	if _, err := Edit("history-u1", 2, Field_Payment, "现金"); err == nil || !strings.Contains(err.Error(), "不能是现金") {
+		t.Errorf("edit invalid payment = %v", err)
+	}
+	if r, err := Edit("history-u1", 2, "颜色", "红"); !errors.Is(err, ErrUnknownField) || r == nil || r.Type != Type_Expense {
+		t.Errorf("edit unknown field = %+v, %v", r, err)
+	}
+	if _, err := Edit("history-u1", 4, Field_Amount, "1"); err == nil || err.Error() != "没有第4条记录" {
+		t.Errorf("edit out of range = %v", err)
+	}
+
+	// 最近一次写入只有打车一条
+	if results, err := Undo("history-u1"); err != nil || len(results) != 1 || results[0].Name != "打车" {
+		t.Fatalf("Undo = %+v, %v", results, err)
+	}
+	if _, ok := f.pages[history[0].PageId]; ok || len(GetHistory("history-u1")) != 2 {
+		t.Errorf("page not archived: %v", f.pages)
+	}
+	if results, _ := Undo("history-u1"); len(results) != 2 || results[0].Name != "晚饭" {
+		t.Errorf("Undo batch = %+v", results)
+	}
+	if _, err := Undo("history-u1"); err == nil {
+		t.Error("expected error without history")
+	}
+}
+
+func TestReport(t *testing.T) {
+	now := time.Date(2025, 3, 12, 20, 0, 0, 0, time.Local) // 周三
+	for name, want := range map[string]string{
+		"今天": "2025-03-12",
+		"昨天": "2025-03-11",
+		"本周": "2025-03-10 ~ 2025-03-16",
+		"上周": "2025-03-03 ~ 2025-03-09",
+		"本月": "2025-03-01 ~ 2025-03-31",
+		"上月": "2025-02-01 ~ 2025-02-28",
+		"去年": "2024-01-01 ~ 2024-12-31",
+	} {
+		if p, ok := ParsePeriod(name, now); !ok || p.String() != want {
+			t.Errorf("%s = %s, want %s", name, p, want)
+		}
+	}
+	if p, _ := ParsePeriod("本月", now); p.Previous().String() != "2025-02-01 ~ 2025-02-28" {
+		t.Errorf("previous = %s", p.Previous())
+	}
+	if _, ok := ParsePeriod("明天", now); ok {
+		t.Error("明天 parsed")
+	}
+
+	s := defaultSchema(Type_Expense)
+	s.Options[Field_Payment] = []string{"微信支付", "支付宝"}
+	if field, choice, ok := s.FindChoice("微信"); !ok || field != Field_Payment || choice != "微信支付" {
+		t.Errorf("FindChoice = %s, %s, %v", field, choice, ok)
+	}
+	if field, _, _ := s.FindChoice("出行"); field != Field_Tag {
+		t.Errorf("FindChoice 出行 = %s", field)
+	}
+
+	p, _ := ParsePeriod("本周", now)
+	r := Summarize(p, map[string]string{Field_Payment: "微信支付"}, []Expense{
+		{Name: "打车", Amount: 20.1, Tag: "出行", Type: "日常开支", Payment: "微信支付"},
+		{Name: "午饭", Amount: 30.2, Tag: "生活吃喝加买菜", Type: "日常开支", Payment: "微信支付"},
+		{Name: "地铁", Amount: 9.7, Tag: "出行", Type: "交通开支(出行)", Payment: "微信支付"},
+	})
+	want := "本周支出（2025-03-10 ~ 2025-03-16），支付方式：微信支付\n共3笔，合计60元" +
+		"\n按标签：\n  生活吃喝加买菜 30.2元 50%，1笔\n  出行 29.8元 50%，2笔" +
+		"\n按开支类型：\n  日常开支 50.3元 84%，2笔\n  交通开支(出行) 9.7元 16%，1笔"
+	if r.String() != want {
+		t.Errorf("report = %q, want %q", r, want)
+	}
+	if r := Summarize(p, nil, nil); r.String() != "本周支出（2025-03-10 ~ 2025-03-16）\n没有支出记录" {
+		t.Errorf("empty report = %q", r)
+	}
+}
@@ -0,0 +1,131 @@
+package ledger
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bytedance/sonic"
+	"github.com/pwh-pwh/aiwechat-vercel/db"
+)
+
+const pendingTime = 10 * time.Minute
+
+// Batch 模型识别出、等待用户确认的一批记录，按数据库类型只使用其中一个列表
+type Batch struct {
+	Time     int64
+	Type     string
+	Expenses []Expense `json:",omitempty"`
+	Incomes  []Income  `json:",omitempty"`
+}
+
+func (b *Batch) Len() int {
+	if b.Type == Type_Income {
+		return len(b.Incomes)
+	}
+	return len(b.Expenses)
+}
+
+func (b *Batch) entry(i int) Entry {
+	if b.Type == Type_Income {
+		return b.Incomes[i]
+	}
+	return b.Expenses[i]
+}
+
+// Edit 修改第index条(从1开始)记录的字段，修改后的记录不合法时不修改
+func (b *Batch) Edit(s Schema, index int, field, value string) error {
+	if index < 1 || index > b.Len() {
+		return fmt.Errorf("没有第%d条记录", index)
+	}
+	if b.Type == Type_Income {
+		income := b.Incomes[index-1]
+		if err := income.Set(field, value); err != nil {
+			return err
+		}
+		if err := income.Validate(s); err != nil {
+			return err
+		}
+		b.Incomes[index-1] = income
+		return nil
+	}
+	expense := b.Expenses[index-1]
+	if err := expense.Set(field, value); err != nil {
+		return err
+	}
+	if err := expense.Validate(s); err != nil {
+		return err
+	}
+	b.Expenses[index-1] = expense
+	return nil
+}
+
+// Insert 写入用户的数据库
+func (b *Batch) Insert(account *Account, s Schema) []Result {
+	if b.Type == Type_Income {
+		return Insert(account, s, b.Incomes)
+	}
+	return Insert(account, s, b.Expenses)
+}
+
+// Preview 每条记录显示名称和金额，下一行显示其它字段
+func (b *Batch) Preview(s Schema) string {
+	var sb strings.Builder
+	for i := 0; i < b.Len(); i++ {
+		entry := b.entry(i)
+		if i > 0 {
+			sb.WriteString("\n")
+		}
+		fmt.Fprintf(&sb, "%d. %s %s元", i+1, entry.Title(), entry.Value(Field_Amount))
+		var fields []string
+		for _, field := range s.Fields() {
+			if field == Field_Name || field == Field_Amount || s.Property(field) == "" {
+				continue
+			}
+			if value := entry.Value(field); value != "" {
+				fields = append(fields, field+"："+value)
+			}
+		}
+		if len(fields) > 0 {
+			sb.WriteString("\n   " + strings.Join(fields, "，"))
+		}
+	}
+	return sb.String()
+}
+
+// SavePending 保存等待确认的记录，新识别的记录会替换之前未确认的记录
+func SavePending(userId string, b *Batch) error {
+	b.Time = time.Now().Unix()
+	data, err := sonic.MarshalString(b)
+	if err != nil {
+		return err
+	}
+	return db.SetLedgerPending(userId, data)
+}
+
+// GetPending 等待确认的记录，没有或已超时时返回nil
+func GetPending(userId string) *Batch {
+	val, err := db.GetLedgerPending(userId)
+	return parsePending(val, err)
+}
+
+// TakePending 取出并删除等待确认的记录，同时确认时只有一次能取到
+func TakePending(userId string) *Batch {
+	val, err := db.TakeLedgerPending(userId)
+	return parsePending(val, err)
+}
+
+func parsePending(val string, err error) *Batch {
+	if err != nil || val == "" {
+		return nil
+	}
+	var b Batch
+	if sonic.UnmarshalString(val, &b) != nil || time.Since(time.Unix(b.Time, 0)) >= pendingTime {
+		return nil
+	}
+	return &b
+}
+
+func DeletePending(userId string) {
+	db.DeleteLedgerPending(userId)
+}
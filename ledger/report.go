@@ -0,0 +1,191 @@
+package ledger
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pwh-pwh/aiwechat-vercel/notion"
+)
+
+// PeriodNames 支持统计的时间范围
+var PeriodNames = []string{"今天", "昨天", "本周", "上周", "本月", "上月", "今年", "去年"}
+
+// Period 统计的时间范围，不包含End当天
+type Period struct {
+	Name  string
+	Start time.Time
+	End   time.Time
+	unit  string
+}
+
+// ParsePeriod 按名称计算now所在的时间范围，一周从周一开始
+func ParsePeriod(name string, now time.Time) (Period, bool) {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	p := Period{Name: name}
+	switch name {
+	case "今天", "昨天":
+		p.unit, p.Start = "day", today
+	case "本周", "上周":
+		p.unit, p.Start = "week", today.AddDate(0, 0, -(int(today.Weekday())+6)%7)
+	case "本月", "上月":
+		p.unit, p.Start = "month", today.AddDate(0, 0, 1-today.Day())
+	case "今年", "去年":
+		p.unit, p.Start = "year", time.Date(today.Year(), 1, 1, 0, 0, 0, 0, today.Location())
+	default:
+		return p, false
+	}
+	p.End = p.shift(p.Start, 1)
+	if name == "昨天" || name == "上周" || name == "上月" || name == "去年" {
+		p.Start, p.End = p.shift(p.Start, -1), p.Start
+	}
+	return p, true
+}
+
+func (p Period) shift(t time.Time, n int) time.Time {
+	switch p.unit {
+	case "week":
+		return t.AddDate(0, 0, 7*n)
+	case "month":
+		return t.AddDate(0, n, 0)
+	case "year":
+		return t.AddDate(n, 0, 0)
+	}
+	return t.AddDate(0, 0, n)
+}
+
+// Previous 上一个同样长度的时间范围，用于比较变化
+func (p Period) Previous() Period {
+	return Period{Name: "上一期", Start: p.shift(p.Start, -1), End: p.Start, unit: p.unit}
+}
+
+func (p Period) String() string {
+	last := p.End.AddDate(0, 0, -1)
+	if last.Equal(p.Start) {
+		return p.Start.Format(time.DateOnly)
+	}
+	return p.Start.Format(time.DateOnly) + " ~ " + last.Format(time.DateOnly)
+}
+
+// FindChoice 在单选字段的可选值中查找，没有完全相同的选项时使用包含value的选项，如 微信 对应 微信支付
+func (s Schema) FindChoice(value string) (field, choice string, ok bool) {
+	for _, exact := range []bool{true, false} {
+		for _, field := range choiceFields[s.Type] {
+			for _, choice := range s.Choices(field) {
+				if choice == value || !exact && strings.Contains(choice, value) {
+					return field, choice, true
+				}
+			}
+		}
+	}
+	return "", "", false
+}
+
+// QueryExpenses 查询时间范围内的支出，filters的key为字段名，值为单选字段的选项
+func QueryExpenses(account *Account, s Schema, p Period, filters map[string]string) ([]Expense, error) {
+	date := s.Property(Field_Date)
+	conditions := []notion.Filter{
+		{Property: date, Date: &notion.Condition{OnOrAfter: p.Start.Format(time.DateOnly)}},
+		{Property: date, Date: &notion.Condition{Before: p.End.Format(time.DateOnly)}},
+	}
+	for field, value := range filters {
+		conditions = append(conditions, notion.SelectEquals(s.Property(field), value))
+	}
+	pages, err := account.Client().QueryDatabase(account.DatabaseId, notion.Query{Filter: notion.And(conditions...)})
+	if err != nil {
+		return nil, err
+	}
+	expenses := make([]Expense, 0, len(pages))
+	for _, page := range pages {
+		props := page.Properties
+		expenses = append(expenses, Expense{
+			Name:    props[s.Property(Field_Name)].PlainText(),
+			Amount:  props[s.Property(Field_Amount)].NumberValue(),
+			Tag:     props[s.Property(Field_Tag)].SelectName(),
+			Date:    props[s.Property(Field_Date)].DateStart(),
+			Payment: props[s.Property(Field_Payment)].SelectName(),
+			Type:    props[s.Property(Field_Type)].SelectName(),
+		})
+	}
+	return expenses, nil
+}
+
+// reportFields 统计时分组的字段
+var reportFields = []string{Field_Tag, Field_Type, Field_Payment}
+
+// Group 一个分组的合计
+type Group struct {
+	Name   string
+	Amount float64
+	Count  int
+}
+
+// Report 一段时间的支出统计，Groups的key为字段名，分组按金额从大到小排列
+type Report struct {
+	Period  Period
+	Filters map[string]string
+	Count   int
+	Total   float64
+	Groups  map[string][]Group
+}
+
+// Summarize 合计支出并按标签、开支类型、支付方式分组
+func Summarize(p Period, filters map[string]string, expenses []Expense) Report {
+	r := Report{Period: p, Filters: filters, Count: len(expenses), Groups: map[string][]Group{}}
+	for _, field := range reportFields {
+		index := map[string]int{}
+		for _, e := range expenses {
+			name := e.Value(field)
+			if name == "" {
+				name = "未分类"
+			}
+			i, ok := index[name]
+			if !ok {
+				i = len(r.Groups[field])
+				index[name] = i
+				r.Groups[field] = append(r.Groups[field], Group{Name: name})
+			}
+			r.Groups[field][i].Amount += e.Amount
+			r.Groups[field][i].Count++
+		}
+		sort.SliceStable(r.Groups[field], func(i, j int) bool {
+			return r.Groups[field][i].Amount > r.Groups[field][j].Amount
+		})
+	}
+	for _, e := range expenses {
+		r.Total += e.Amount
+	}
+	return r
+}
+
+// String 合计和各分组的金额、占比，按筛选的字段不再分组
+func (r Report) String() string {
+	var sb strings.Builder
+	sb.WriteString(r.Period.Name + "支出（" + r.Period.String() + "）")
+	var filters []string
+	for _, field := range reportFields {
+		if value, ok := r.Filters[field]; ok {
+			filters = append(filters, field+"："+value)
+		}
+	}
+	if len(filters) > 0 {
+		sb.WriteString("，" + strings.Join(filters, "，"))
+	}
+	if r.Count == 0 {
+		sb.WriteString("\n没有支出记录")
+		return sb.String()
+	}
+	fmt.Fprintf(&sb, "\n共%d笔，合计%s元", r.Count, formatAmount(r.Total))
+	for _, field := range reportFields {
+		if _, ok := r.Filters[field]; ok {
+			continue
+		}
+		sb.WriteString("\n按" + field + "：")
+		for _, g := range r.Groups[field] {
+			fmt.Fprintf(&sb, "\n  %s %s元 %d%%，%d笔", g.Name, formatAmount(g.Amount), int(math.Round(g.Amount/r.Total*100)), g.Count)
+		}
+	}
+	return sb.String()
+}
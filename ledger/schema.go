@@ -0,0 +1,266 @@
+package ledger
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/bytedance/sonic"
+	"github.com/pwh-pwh/aiwechat-vercel/db"
+)
+
+// 记录的字段名，也是模型输出的json字段名，默认与Notion数据库的属性名相同
+const (
+	Field_Name        = "名称"
+	Field_Amount      = "金额"
+	Field_Tag         = "标签"
+	Field_Date        = "日期"
+	Field_Payment     = "支付方式"
+	Field_Type        = "开支类型"
+	Field_Description = "说明"
+	Field_Remark      = "备注"
+	Field_Company     = "单位"
+)
+
+// 每种数据库的字段和需要从可选值中选择的字段
+var (
+	schemaFields = map[string][]string{
+		Type_Expense: {Field_Name, Field_Amount, Field_Tag, Field_Date, Field_Payment, Field_Type, Field_Description, Field_Remark},
+		Type_Income:  {Field_Name, Field_Amount, Field_Tag, Field_Date, Field_Company},
+	}
+	choiceFields = map[string][]string{
+		Type_Expense: {Field_Tag, Field_Payment, Field_Type},
+		Type_Income:  {Field_Tag},
+	}
+	// 可以不写入的字段，数据库中没有对应的属性时跳过
+	optionalFields = []string{Field_Description, Field_Remark}
+)
+
+// 没有读取到数据库的选项时使用的默认值
+func defaultSchema(databaseType string) Schema {
+	if databaseType == Type_Income {
+		return Schema{
+			Type:       Type_Income,
+			Properties: map[string]string{Field_Company: "工作单位"},
+			Options: map[string][]string{
+				Field_Tag: {"固定工资", "兼职写代码", "家人资助", "人情往来礼金", "其他兼职"},
+			},
+		}
+	}
+	return Schema{
+		Type:       Type_Expense,
+		Properties: map[string]string{},
+		Options: map[string][]string{
+			Field_Tag:     {"生活吃喝加买菜", "房贷-银行金", "医疗保健", "水电物业", "出行", "家人-互动生活穿衣用品", "家用设备", "电子设备", "电话费", "旅游", "其他", "摩托车", "网购", "学习课程"},
+			Field_Payment: {"支付宝", "微信", "银行卡"},
+			Field_Type:    {"其他", "日常开支", "固定开支", "社交娱乐开支", "节假日开支", "教育和自我提升开支", "医疗保健开支", "意外或紧急开支!", "交通开支(出行)", "加油", "购物"},
+		},
+	}
+}
+
+// DefaultOptions 默认的可选值，供无法按用户区分的场景使用
+func DefaultOptions(databaseType, field string) []string {
+	return defaultSchema(databaseType).Options[field]
+}
+
+// Schema 数据库的字段设置
+// Properties为字段对应的Notion属性名，不在其中的字段属性名与字段名相同，属性名为空时不写入该字段
+// Options为单选、多选字段的可选值，为空时不限制
+type Schema struct {
+	Type       string              `json:",omitempty"`
+	Properties map[string]string   `json:",omitempty"`
+	Options    map[string][]string `json:",omitempty"`
+}
+
+func (s Schema) Fields() []string {
+	return schemaFields[s.Type]
+}
+
+// Property 字段对应的Notion属性名
+func (s Schema) Property(field string) string {
+	if name, ok := s.Properties[field]; ok {
+		return name
+	}
+	return field
+}
+
+func (s Schema) Choices(field string) []string {
+	return s.Options[field]
+}
+
+// Check 检查字段的值是否在可选值中
+func (s Schema) Check(field, value string) error {
+	choices := s.Choices(field)
+	if len(choices) == 0 || slices.Contains(choices, value) {
+		return nil
+	}
+	return fmt.Errorf("%s只能是%s，不能是%s", field, strings.Join(choices, "、"), value)
+}
+
+// Prompt 告诉模型每个字段的可选值
+func (s Schema) Prompt() string {
+	var lines []string
+	for _, field := range choiceFields[s.Type] {
+		if choices := s.Choices(field); len(choices) > 0 {
+			lines = append(lines, fmt.Sprintf("%s只能从以下选项中选择：%s", field, strings.Join(choices, "、")))
+		}
+	}
+	return strings.Join(lines, "；")
+}
+
+// String 每个字段一行，显示属性名和可选值
+func (s Schema) String() string {
+	var sb strings.Builder
+	for i, field := range s.Fields() {
+		if i > 0 {
+			sb.WriteString("\n")
+		}
+		sb.WriteString(field)
+		if property := s.Property(field); property == "" {
+			sb.WriteString("：不写入")
+		} else if property != field {
+			sb.WriteString("：属性「" + property + "」")
+		}
+		if choices := s.Choices(field); len(choices) > 0 {
+			sb.WriteString("\n  " + strings.Join(choices, "、"))
+		}
+	}
+	return sb.String()
+}
+
+func settingsField(userId, databaseType string) string {
+	return userId + ":" + databaseType
+}
+
+// GetSettings 用户自己设置的属性名和可选值，没有设置时返回空的Schema
+func GetSettings(userId, databaseType string) Schema {
+	s := Schema{Type: databaseType}
+	if val, err := db.GetLedgerSchema(settingsField(userId, databaseType)); err == nil && val != "" {
+		sonic.UnmarshalString(val, &s)
+	}
+	return s
+}
+
+func saveSettings(userId string, s Schema) error {
+	if len(s.Properties) == 0 && len(s.Options) == 0 {
+		return db.SetLedgerSchema(settingsField(userId, s.Type), "")
+	}
+	data, err := sonic.MarshalString(s)
+	if err != nil {
+		return err
+	}
+	return db.SetLedgerSchema(settingsField(userId, s.Type), data)
+}
+
+var ErrUnknownField = errors.New("没有这个字段")
+
+// SetProperty 设置字段对应的Notion属性名，property为空时不写入该字段(只能用于说明、备注)
+func SetProperty(userId, databaseType, field, property string) error {
+	if !slices.Contains(schemaFields[databaseType], field) {
+		return ErrUnknownField
+	}
+	if property == "" && !slices.Contains(optionalFields, field) {
+		return fmt.Errorf("%s必须写入", field)
+	}
+	s := GetSettings(userId, databaseType)
+	if s.Properties == nil {
+		s.Properties = map[string]string{}
+	}
+	s.Properties[field] = property
+	return saveSettings(userId, s)
+}
+
+// SetOptions 设置字段的可选值，choices为空时恢复为从数据库读取
+func SetOptions(userId, databaseType, field string, choices []string) error {
+	if !slices.Contains(schemaFields[databaseType], field) {
+		return ErrUnknownField
+	}
+	if !slices.Contains(choiceFields[databaseType], field) {
+		return fmt.Errorf("%s不是选择字段，只能设置%s", field, strings.Join(choiceFields[databaseType], "、"))
+	}
+	s := GetSettings(userId, databaseType)
+	if len(choices) == 0 {
+		delete(s.Options, field)
+	} else {
+		if s.Options == nil {
+			s.Options = map[string][]string{}
+		}
+		s.Options[field] = choices
+	}
+	return saveSettings(userId, s)
+}
+
+// ResetSettings 删除用户的设置，全部从数据库读取
+func ResetSettings(userId, databaseType string) error {
+	return db.SetLedgerSchema(settingsField(userId, databaseType), "")
+}
+
+// databaseInfo 缓存的数据库属性，key为属性名，值为单选、多选的选项
+type databaseInfo struct {
+	Time       int64
+	Properties map[string][]string
+}
+
+const databaseCacheTime = 10 * time.Minute
+
+// getDatabaseInfo 读取数据库的属性，缓存10分钟
+func getDatabaseInfo(account *Account) (*databaseInfo, error) {
+	if val, err := db.GetLedgerDatabase(account.DatabaseId); err == nil && val != "" {
+		var info databaseInfo
+		if sonic.UnmarshalString(val, &info) == nil && time.Since(time.Unix(info.Time, 0)) < databaseCacheTime {
+			return &info, nil
+		}
+	}
+	database, err := account.Client().RetrieveDatabase(account.DatabaseId)
+	if err != nil {
+		return nil, err
+	}
+	info := &databaseInfo{Time: time.Now().Unix(), Properties: map[string][]string{}}
+	for name, p := range database.Properties {
+		info.Properties[name] = p.OptionNames()
+	}
+	if data, err := sonic.MarshalString(info); err == nil {
+		db.SetLedgerDatabase(account.DatabaseId, data)
+	}
+	return info, nil
+}
+
+// RefreshSchema 清除缓存，下次记账时重新读取数据库
+func RefreshSchema(account *Account) {
+	db.DeleteLedgerDatabase(account.DatabaseId)
+}
+
+// LoadSchema 依次使用默认值、用户设置的属性名、数据库中的选项和用户设置的可选值，没有绑定数据库或读取失败时使用默认选项
+func LoadSchema(account *Account) Schema {
+	s := defaultSchema(account.Type)
+	settings := GetSettings(account.UserId, account.Type)
+	for field, property := range settings.Properties {
+		s.Properties[field] = property
+	}
+	var info *databaseInfo
+	if account.DatabaseId != "" {
+		var err error
+		if info, err = getDatabaseInfo(account); err != nil {
+			log.Println("Error retrieving notion database:", err)
+		}
+	}
+	if info != nil {
+		for _, field := range choiceFields[s.Type] {
+			if choices := info.Properties[s.Property(field)]; len(choices) > 0 {
+				s.Options[field] = choices
+			}
+		}
+		for _, field := range optionalFields {
+			if _, ok := info.Properties[s.Property(field)]; !ok && slices.Contains(s.Fields(), field) {
+				s.Properties[field] = ""
+			}
+		}
+	}
+	for field, choices := range settings.Options {
+		s.Options[field] = choices
+	}
+	return s
+}
@@ -0,0 +1,117 @@
+package limit
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/bytedance/sonic"
+	"github.com/pwh-pwh/aiwechat-vercel/config"
+	"github.com/pwh-pwh/aiwechat-vercel/db"
+	"github.com/pwh-pwh/aiwechat-vercel/role"
+)
+
+const (
+	SCOPE_USER   = "user"
+	SCOPE_BOT    = "bot"
+	SCOPE_GLOBAL = "global"
+)
+
+// Violation 一次被限流的记录
+type Violation struct {
+	UserId string
+	Scope  string
+	Bot    string `json:",omitempty"`
+	Rule   string
+	Time   int64
+}
+
+// Backend 不为空时替代默认的限流实现，用于测试
+var Backend Limiter
+
+var memoryLimiter = NewMemoryLimiter()
+
+func getLimiter() Limiter {
+	if Backend != nil {
+		return Backend
+	}
+	if db.RedisClient != nil {
+		return NewRedisLimiter(db.RedisClient)
+	}
+	return memoryLimiter
+}
+
+// Check 检查用户本次请求是否超过限流规则，超过时返回提示语
+// 依次检查用户角色的规则、用户当前bot的规则和全局规则，都未超过时才计数
+func Check(userId string) (string, bool) {
+	botType := config.GetUserBotType(userId)
+	var (
+		counters []Counter
+		scopes   []string
+	)
+	add := func(scope, key, rules string) {
+		parsed, err := ParseRules(rules)
+		if err != nil {
+			fmt.Println("rate limit config error:", err)
+			return
+		}
+		for _, rule := range parsed {
+			counters = append(counters, Counter{Key: windowKey(key, rule), Rule: rule})
+			scopes = append(scopes, scope)
+		}
+	}
+	add(SCOPE_USER, fmt.Sprintf("%s:%s:%s", db.LIMIT_KEY, SCOPE_USER, userId), config.GetRateLimit(role.Get(userId)))
+	add(SCOPE_BOT, fmt.Sprintf("%s:%s:%s:%s", db.LIMIT_KEY, SCOPE_BOT, botType, userId), config.GetBotRateLimit(botType))
+	add(SCOPE_GLOBAL, fmt.Sprintf("%s:%s", db.LIMIT_KEY, SCOPE_GLOBAL), config.GetGlobalRateLimit())
+
+	i, err := getLimiter().Allow(counters)
+	if err != nil {
+		// 限流出错时放行，避免redis异常导致所有用户无法使用
+		fmt.Println("rate limit error:", err)
+		return "", true
+	}
+	if i < 0 {
+		return "", true
+	}
+	violation := Violation{UserId: userId, Scope: scopes[i], Rule: counters[i].Rule.String(), Time: time.Now().Unix()}
+	if violation.Scope == SCOPE_BOT {
+		violation.Bot = botType
+	}
+	record(violation)
+	return violation.Message(), false
+}
+
+func (v Violation) Message() string {
+	switch v.Scope {
+	case SCOPE_BOT:
+		return fmt.Sprintf("%s使用太频繁啦(%s)，休息一下再来，也可以切换其它bot", v.Bot, v.Rule)
+	case SCOPE_GLOBAL:
+		return "现在找我聊天的人太多啦，请稍后再试"
+	}
+	return fmt.Sprintf("发送太频繁啦(%s)，休息一下再来吧", v.Rule)
+}
+
+func record(v Violation) {
+	data, err := sonic.MarshalString(v)
+	if err == nil {
+		err = db.AddViolation(data)
+	}
+	if err != nil {
+		fmt.Println("record violation error:", err)
+	}
+}
+
+// Violations 返回最近n条限流记录
+func Violations(n int) ([]Violation, error) {
+	values, err := db.GetViolations(n)
+	if err != nil {
+		return nil, err
+	}
+	violations := make([]Violation, 0, len(values))
+	for _, value := range values {
+		var v Violation
+		if err := sonic.UnmarshalString(value, &v); err == nil {
+			violations = append(violations, v)
+		}
+	}
+	return violations, nil
+}
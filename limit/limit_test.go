@@ -0,0 +1,94 @@
+package limit
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseRules(t *testing.T) {
+	rules, err := ParseRules("10/1m, 200/1d,3/30s")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Rule{{10, time.Minute}, {200, 24 * time.Hour}, {3, 30 * time.Second}}
+	if len(rules) != len(want) {
+		t.Fatalf("ParseRules = %v", rules)
+	}
+	for i := range want {
+		if rules[i] != want[i] {
+			t.Errorf("rule %d = %v, want %v", i, rules[i], want[i])
+		}
+	}
+	if rules[0].String() != "每分钟10次" || rules[1].String() != "每天200次" || rules[2].String() != "每30秒3次" {
+		t.Errorf("String = %s %s %s", rules[0], rules[1], rules[2])
+	}
+	if rules, err = ParseRules(""); err != nil || len(rules) != 0 {
+		t.Errorf("empty rules = %v, %v", rules, err)
+	}
+	for _, s := range []string{"10", "0/1m", "x/1m", "10/abc", "10/-1m", "10/500ms", "10/1500ms"} {
+		if _, err = ParseRules(s); err == nil {
+			t.Errorf("ParseRules(%q) should fail", s)
+		}
+	}
+}
+
+func TestMemoryLimiter(t *testing.T) {
+	l := NewMemoryLimiter()
+	now := time.Unix(1700000000, 0)
+	l.now = func() time.Time { return now }
+	counters := []Counter{
+		{Key: "a", Rule: Rule{Limit: 2, Window: time.Minute}},
+		{Key: "b", Rule: Rule{Limit: 3, Window: time.Hour}},
+	}
+	for i := 0; i < 2; i++ {
+		if n, _ := l.Allow(counters); n != -1 {
+			t.Fatalf("request %d blocked by %d", i, n)
+		}
+	}
+	if n, _ := l.Allow(counters); n != 0 {
+		t.Errorf("third request in a minute = %d, want 0", n)
+	}
+	// 窗口滑过后分钟限制恢复，被拒绝的请求不计数
+	now = now.Add(time.Minute)
+	if n, _ := l.Allow(counters); n != -1 {
+		t.Errorf("after a minute = %d, want -1", n)
+	}
+	if n, _ := l.Allow(counters); n != 1 {
+		t.Errorf("hourly limit = %d, want 1", n)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	Backend = NewMemoryLimiter()
+	t.Cleanup(func() { Backend = nil })
+	t.Setenv("ADMIN_OPENIDS", "boss")
+	t.Setenv("rateLimitUser", "2/1m")
+	t.Setenv("rateLimitAdmin", "")
+	t.Setenv("rateLimitBot", "")
+	t.Setenv("rateLimitGlobal", "5/1m")
+
+	for i := 0; i < 2; i++ {
+		if text, ok := Check("limit-u1"); !ok {
+			t.Fatalf("request %d throttled: %s", i, text)
+		}
+	}
+	text, ok := Check("limit-u1")
+	if ok || !strings.Contains(text, "每分钟2次") {
+		t.Errorf("third request = %q, %v", text, ok)
+	}
+	violations, err := Violations(1)
+	if err != nil || len(violations) != 1 || violations[0].UserId != "limit-u1" || violations[0].Scope != SCOPE_USER {
+		t.Errorf("Violations = %+v, %v", violations, err)
+	}
+
+	// 管理员不受用户限流，但计入全局限流
+	for i := 0; i < 3; i++ {
+		if text, ok = Check("boss"); !ok {
+			t.Fatalf("admin request %d throttled: %s", i, text)
+		}
+	}
+	if text, ok = Check("boss"); ok || text != "现在找我聊天的人太多啦，请稍后再试" {
+		t.Errorf("global limit = %q, %v", text, ok)
+	}
+}
@@ -0,0 +1,108 @@
+package limit
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// Counter 一个限流计数，Key相同的计数共享同一个滑动窗口
+type Counter struct {
+	Key  string
+	Rule Rule
+}
+
+// Limiter 滑动窗口限流，所有计数都未超限时才记录本次请求，返回第一个超限的计数下标，未超限时返回-1
+type Limiter interface {
+	Allow(counters []Counter) (int, error)
+}
+
+// RedisLimiter 每个计数用一个有序集合保存窗口内的请求时间，检查和记录在一个脚本中完成
+type RedisLimiter struct {
+	client *redis.Client
+}
+
+func NewRedisLimiter(client *redis.Client) *RedisLimiter {
+	return &RedisLimiter{client: client}
+}
+
+// KEYS为每个计数的key，ARGV为 当前毫秒, 本次请求的member, 之后每个计数的 窗口毫秒, 次数
+var allowScript = redis.NewScript(`
+local now = tonumber(ARGV[1])
+for i, key in ipairs(KEYS) do
+	local window = tonumber(ARGV[1 + i * 2])
+	local limit = tonumber(ARGV[2 + i * 2])
+	redis.call('ZREMRANGEBYSCORE', key, 0, now - window)
+	if redis.call('ZCARD', key) >= limit then
+		return i
+	end
+end
+for i, key in ipairs(KEYS) do
+	redis.call('ZADD', key, now, ARGV[2])
+	redis.call('PEXPIRE', key, tonumber(ARGV[1 + i * 2]))
+end
+return 0
+`)
+
+var sequence atomic.Int64
+
+func (l *RedisLimiter) Allow(counters []Counter) (int, error) {
+	if len(counters) == 0 {
+		return -1, nil
+	}
+	now := time.Now().UnixMilli()
+	keys := make([]string, 0, len(counters))
+	args := []any{now, fmt.Sprintf("%d-%d", now, sequence.Add(1))}
+	for _, c := range counters {
+		keys = append(keys, c.Key)
+		args = append(args, c.Rule.Window.Milliseconds(), c.Rule.Limit)
+	}
+	n, err := allowScript.Run(context.Background(), l.client, keys, args...).Int()
+	if err != nil {
+		return -1, err
+	}
+	return n - 1, nil
+}
+
+// MemoryLimiter 没有配置redis时使用，只在当前实例内生效
+type MemoryLimiter struct {
+	lock   sync.Mutex
+	events map[string][]time.Time
+	now    func() time.Time
+}
+
+func NewMemoryLimiter() *MemoryLimiter {
+	return &MemoryLimiter{events: map[string][]time.Time{}, now: time.Now}
+}
+
+func (l *MemoryLimiter) Allow(counters []Counter) (int, error) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	now := l.now()
+	for i, c := range counters {
+		events := l.events[c.Key]
+		start := 0
+		for start < len(events) && !events[start].After(now.Add(-c.Rule.Window)) {
+			start++
+		}
+		events = events[start:]
+		l.events[c.Key] = events
+		if len(events) >= c.Rule.Limit {
+			return i, nil
+		}
+	}
+	for _, c := range counters {
+		l.events[c.Key] = append(l.events[c.Key], now)
+	}
+	return -1, nil
+}
+
+// windowKey 同一个key不同窗口的计数分开保存
+func windowKey(key string, rule Rule) string {
+	return key + ":" + strconv.FormatInt(int64(rule.Window/time.Second), 10)
+}
@@ -0,0 +1,70 @@
+package limit
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Rule 时间窗口内最多Limit次
+type Rule struct {
+	Limit  int
+	Window time.Duration
+}
+
+// ParseRules 解析 次数/时间窗口 格式的规则，多条用英文逗号分隔，时间窗口支持s、m、h、d且至少1秒，如 10/1m,200/1d
+func ParseRules(s string) ([]Rule, error) {
+	var rules []Rule
+	for _, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		count, window, ok := strings.Cut(item, "/")
+		if !ok {
+			return nil, fmt.Errorf("限流规则格式错误: %s", item)
+		}
+		limit, err := strconv.Atoi(strings.TrimSpace(count))
+		if err != nil || limit <= 0 {
+			return nil, fmt.Errorf("限流次数错误: %s", item)
+		}
+		// 计数按整秒的窗口长度区分，不支持小于1秒或不是整秒的窗口
+		d, err := parseWindow(strings.TrimSpace(window))
+		if err != nil || d < time.Second || d%time.Second != 0 {
+			return nil, fmt.Errorf("限流时间窗口错误: %s", item)
+		}
+		rules = append(rules, Rule{Limit: limit, Window: d})
+	}
+	return rules, nil
+}
+
+func parseWindow(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		return time.Duration(n) * 24 * time.Hour, err
+	}
+	return time.ParseDuration(s)
+}
+
+// String 友好的规则描述，如 每分钟10次
+func (r Rule) String() string {
+	var window string
+	switch {
+	case r.Window == time.Minute:
+		window = "每分钟"
+	case r.Window == time.Hour:
+		window = "每小时"
+	case r.Window == 24*time.Hour:
+		window = "每天"
+	case r.Window%(24*time.Hour) == 0:
+		window = fmt.Sprintf("每%d天", r.Window/(24*time.Hour))
+	case r.Window%time.Hour == 0:
+		window = fmt.Sprintf("每%d小时", r.Window/time.Hour)
+	case r.Window%time.Minute == 0:
+		window = fmt.Sprintf("每%d分钟", r.Window/time.Minute)
+	default:
+		window = fmt.Sprintf("每%d秒", r.Window/time.Second)
+	}
+	return fmt.Sprintf("%s%d次", window, r.Limit)
+}
@@ -0,0 +1,116 @@
+package moderation
+
+import (
+	"unicode"
+)
+
+// Matcher 基于Aho-Corasick自动机的多模式匹配，一次扫描找出文本中所有敏感词
+type Matcher struct {
+	nodes []node
+	words []word
+}
+
+type node struct {
+	next map[rune]int
+	fail int
+	// 以该节点结尾的词
+	out []int
+}
+
+type word struct {
+	text     string
+	category string
+	length   int
+}
+
+// Match 文本中命中的敏感词，Start和End为rune下标
+type Match struct {
+	Word     string
+	Category string
+	Start    int
+	End      int
+}
+
+// NewMatcher 由词到类别的映射构建自动机，匹配时忽略英文大小写和全角半角
+func NewMatcher(words map[string]string) *Matcher {
+	m := &Matcher{nodes: []node{{next: map[rune]int{}}}}
+	for text, category := range words {
+		runes := normalize(text)
+		if len(runes) == 0 {
+			continue
+		}
+		cur := 0
+		for _, r := range runes {
+			next, ok := m.nodes[cur].next[r]
+			if !ok {
+				next = len(m.nodes)
+				m.nodes = append(m.nodes, node{next: map[rune]int{}})
+				m.nodes[cur].next[r] = next
+			}
+			cur = next
+		}
+		m.nodes[cur].out = append(m.nodes[cur].out, len(m.words))
+		m.words = append(m.words, word{text: text, category: category, length: len(runes)})
+	}
+	m.build()
+	return m
+}
+
+// build 按广度优先计算失败指针，并把失败链上的输出合并到当前节点
+func (m *Matcher) build() {
+	queue := make([]int, 0, len(m.nodes))
+	for _, child := range m.nodes[0].next {
+		queue = append(queue, child)
+	}
+	for len(queue) > 0 {
+		cur := queue[0]
+		queue = queue[1:]
+		for r, child := range m.nodes[cur].next {
+			fail := m.nodes[cur].fail
+			for fail > 0 {
+				if _, ok := m.nodes[fail].next[r]; ok {
+					break
+				}
+				fail = m.nodes[fail].fail
+			}
+			if next, ok := m.nodes[fail].next[r]; ok && next != child {
+				m.nodes[child].fail = next
+			}
+			m.nodes[child].out = append(m.nodes[child].out, m.nodes[m.nodes[child].fail].out...)
+			queue = append(queue, child)
+		}
+	}
+}
+
+func (m *Matcher) Find(text string) []Match {
+	var matches []Match
+	cur := 0
+	for i, r := range normalize(text) {
+		for cur > 0 {
+			if _, ok := m.nodes[cur].next[r]; ok {
+				break
+			}
+			cur = m.nodes[cur].fail
+		}
+		cur = m.nodes[cur].next[r]
+		for _, idx := range m.nodes[cur].out {
+			w := m.words[idx]
+			matches = append(matches, Match{Word: w.text, Category: w.category, Start: i + 1 - w.length, End: i + 1})
+		}
+	}
+	return matches
+}
+
+// normalize 逐个rune转换，保证下标与原文一致
+func normalize(text string) []rune {
+	runes := []rune(text)
+	for i, r := range runes {
+		if r >= '！' && r <= '～' {
+			r -= 0xFEE0
+		} else if r == '　' {
+			r = ' '
+		}
+		runes[i] = unicode.ToLower(r)
+	}
+	return runes
+}
@@ -0,0 +1,47 @@
+package moderation
+
+import (
+	"sort"
+	"testing"
+)
+
+func TestMatcherFind(t *testing.T) {
+	m := NewMatcher(map[string]string{
+		"he":   "a",
+		"she":  "a",
+		"hers": "a",
+		"代开发票": "广告",
+		"发票":   "广告",
+		"SPAM": "广告",
+		"":     "空",
+		"加微信号": "广告",
+	})
+	tests := []struct {
+		text string
+		want []Match
+	}{
+		{"ushers", []Match{{"she", "a", 1, 4}, {"he", "a", 2, 4}, {"hers", "a", 2, 6}}},
+		{"可以代开发票吗", []Match{{"代开发票", "广告", 2, 6}, {"发票", "广告", 4, 6}}},
+		{"ｓｐａｍ and Spam", []Match{{"SPAM", "广告", 0, 4}, {"SPAM", "广告", 9, 13}}},
+		{"加微信", nil},
+		{"", nil},
+	}
+	for _, tt := range tests {
+		got := m.Find(tt.text)
+		sort.Slice(got, func(i, j int) bool {
+			if got[i].Start != got[j].Start {
+				return got[i].Start < got[j].Start
+			}
+			return got[i].End < got[j].End
+		})
+		if len(got) != len(tt.want) {
+			t.Errorf("Find(%q) = %+v, want %+v", tt.text, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("Find(%q)[%d] = %+v, want %+v", tt.text, i, got[i], tt.want[i])
+			}
+		}
+	}
+}
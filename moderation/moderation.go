@@ -0,0 +1,241 @@
+package moderation
+
+import (
+	"bufio"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bytedance/sonic"
+	"github.com/pwh-pwh/aiwechat-vercel/client"
+	"github.com/pwh-pwh/aiwechat-vercel/config"
+	"github.com/pwh-pwh/aiwechat-vercel/db"
+)
+
+const (
+	ACTION_BLOCK = "block"
+	ACTION_MASK  = "mask"
+	ACTION_WARN  = "warn"
+
+	STAGE_INPUT  = "input"
+	STAGE_OUTPUT = "output"
+
+	Default_Category = "默认"
+	Mask_Char        = '*'
+
+	// 敏感词在内存中缓存的时间
+	cacheDuration = 10 * time.Minute
+)
+
+// Hit 一次命中，审核接口的命中没有Word，Start和End为0
+type Hit struct {
+	Category string
+	Word     string `json:",omitempty"`
+	Action   string
+	Start    int `json:"-"`
+	End      int `json:"-"`
+}
+
+// Result 审核结果，Text为处理后的文本，Blocked时不能使用
+type Result struct {
+	Text    string
+	Blocked bool
+	Hits    []Hit
+}
+
+// Log 审核记录，供管理员查看
+type Log struct {
+	UserId string
+	Stage  string
+	Hits   []Hit
+	Time   int64
+}
+
+var (
+	// MatcherBackend、ClassifierBackend 不为空时替代配置的敏感词和审核接口，用于测试
+	MatcherBackend    *Matcher
+	ClassifierBackend client.Classifier
+
+	cacheLock    sync.Mutex
+	cacheMatcher *Matcher
+	cacheTime    time.Time
+)
+
+// Enabled 是否配置了敏感词或审核接口
+func Enabled() bool {
+	return MatcherBackend != nil || ClassifierBackend != nil ||
+		config.GetModerationWords() != "" || config.GetModerationWordsUrl() != "" || config.GetModerationApiUrl() != ""
+}
+
+// Moderate 用敏感词和审核接口检查文本，按类别配置的处理方式处理
+// 敏感词默认打码，审核接口命中时默认拦截；审核接口无法定位违规内容，配置为mask时也会拦截
+func Moderate(text string) Result {
+	result := Result{Text: text}
+	if strings.TrimSpace(text) == "" {
+		return result
+	}
+	if matcher := getMatcher(); matcher != nil {
+		for _, m := range matcher.Find(text) {
+			result.Hits = append(result.Hits, Hit{Category: m.Category, Word: m.Word, Action: action(m.Category, ACTION_MASK), Start: m.Start, End: m.End})
+		}
+	}
+	if classifier := getClassifier(); classifier != nil {
+		categories, err := classifier.Classify(text)
+		if err != nil {
+			// 审核接口出错时只使用敏感词，避免接口异常导致无法使用
+			fmt.Println("moderation api error:", err)
+		}
+		for _, category := range categories {
+			hit := Hit{Category: category, Action: action(category, ACTION_BLOCK)}
+			if hit.Action == ACTION_MASK {
+				hit.Action = ACTION_BLOCK
+			}
+			result.Hits = append(result.Hits, hit)
+		}
+	}
+	runes := []rune(text)
+	masked := false
+	for _, hit := range result.Hits {
+		switch hit.Action {
+		case ACTION_BLOCK:
+			result.Blocked = true
+		case ACTION_MASK:
+			for i := hit.Start; i < hit.End; i++ {
+				runes[i] = Mask_Char
+			}
+			masked = true
+		}
+	}
+	if masked {
+		result.Text = string(runes)
+	}
+	return result
+}
+
+func action(category, def string) string {
+	switch a := config.GetModerationAction(category); a {
+	case ACTION_BLOCK, ACTION_MASK, ACTION_WARN:
+		return a
+	case "":
+	default:
+		fmt.Printf("moderation action %s of %s is invalid\n", a, category)
+	}
+	return def
+}
+
+func getClassifier() client.Classifier {
+	if ClassifierBackend != nil {
+		return ClassifierBackend
+	}
+	if config.GetModerationApiUrl() == "" {
+		return nil
+	}
+	return &client.ModerationApi{
+		BaseUrl: config.GetModerationApiUrl(),
+		Token:   config.GetModerationApiToken(),
+		Model:   config.GetModerationApiModel(),
+	}
+}
+
+func getMatcher() *Matcher {
+	if MatcherBackend != nil {
+		return MatcherBackend
+	}
+	cacheLock.Lock()
+	defer cacheLock.Unlock()
+	if !cacheTime.IsZero() && time.Since(cacheTime) < cacheDuration {
+		return cacheMatcher
+	}
+	words := ParseWords(config.GetModerationWords())
+	if wordsUrl := config.GetModerationWordsUrl(); wordsUrl != "" {
+		remote, err := fetchWords(wordsUrl)
+		if err != nil {
+			fmt.Println("fetch moderation words error:", err)
+		}
+		for w, category := range remote {
+			words[w] = category
+		}
+	}
+	cacheMatcher = nil
+	if len(words) > 0 {
+		cacheMatcher = NewMatcher(words)
+	}
+	cacheTime = time.Now()
+	return cacheMatcher
+}
+
+// ParseWords 解析 类别:词,词;类别:词 格式的敏感词
+func ParseWords(s string) map[string]string {
+	words := map[string]string{}
+	for _, group := range strings.Split(s, ";") {
+		category, list, ok := strings.Cut(group, ":")
+		if !ok {
+			category, list = Default_Category, group
+		}
+		category = strings.TrimSpace(category)
+		for _, w := range strings.Split(list, ",") {
+			if w = strings.TrimSpace(w); w != "" {
+				words[w] = category
+			}
+		}
+	}
+	return words
+}
+
+// ParseWordList 解析敏感词文件，每行一个词，词后可以用空格或tab分隔写类别，#开头的行为注释
+func ParseWordList(s string) map[string]string {
+	words := map[string]string{}
+	scanner := bufio.NewScanner(strings.NewReader(s))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		category := Default_Category
+		if len(fields) > 1 {
+			category = fields[1]
+		}
+		words[fields[0]] = category
+	}
+	return words
+}
+
+func fetchWords(wordsUrl string) (map[string]string, error) {
+	file, err := client.FetchFile(wordsUrl, 5<<20)
+	if err != nil {
+		return nil, err
+	}
+	return ParseWordList(string(file.Data)), nil
+}
+
+// Record 记录有命中的审核结果
+func Record(userId, stage string, result Result) {
+	if len(result.Hits) == 0 {
+		return
+	}
+	data, err := sonic.MarshalString(Log{UserId: userId, Stage: stage, Hits: result.Hits, Time: time.Now().Unix()})
+	if err == nil {
+		err = db.AddModerationLog(data)
+	}
+	if err != nil {
+		fmt.Println("record moderation error:", err)
+	}
+}
+
+// Logs 返回最近n条审核记录
+func Logs(n int) ([]Log, error) {
+	values, err := db.GetModerationLogs(n)
+	if err != nil {
+		return nil, err
+	}
+	logs := make([]Log, 0, len(values))
+	for _, value := range values {
+		var l Log
+		if err := sonic.UnmarshalString(value, &l); err == nil {
+			logs = append(logs, l)
+		}
+	}
+	return logs, nil
+}
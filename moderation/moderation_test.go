@@ -0,0 +1,85 @@
+package moderation
+
+import (
+	"errors"
+	"testing"
+)
+
+type fakeClassifier struct {
+	categories []string
+	err        error
+}
+
+func (f *fakeClassifier) Classify(text string) ([]string, error) {
+	if text == "bad" {
+		return f.categories, f.err
+	}
+	return nil, f.err
+}
+
+func useBackends(t *testing.T, words map[string]string, classifier *fakeClassifier) {
+	t.Helper()
+	MatcherBackend = NewMatcher(words)
+	if classifier != nil {
+		ClassifierBackend = classifier
+	}
+	t.Cleanup(func() {
+		MatcherBackend, ClassifierBackend = nil, nil
+	})
+}
+
+func TestModerate(t *testing.T) {
+	t.Setenv("moderationActions", "辱骂=warn;政治=block;violence=mask")
+	useBackends(t, map[string]string{"代开发票": "广告", "笨蛋": "辱骂", "敏感人物": "政治"}, &fakeClassifier{categories: []string{"violence"}})
+
+	r := Moderate("提供代开发票服务，笨蛋")
+	if r.Blocked || r.Text != "提供****服务，笨蛋" || len(r.Hits) != 2 {
+		t.Errorf("mask and warn = %+v", r)
+	}
+	if r = Moderate("关于敏感人物"); !r.Blocked {
+		t.Errorf("block = %+v", r)
+	}
+	// 审核接口无法定位违规内容，mask按拦截处理
+	if r = Moderate("bad"); !r.Blocked || len(r.Hits) != 1 || r.Hits[0].Action != ACTION_BLOCK {
+		t.Errorf("api hit = %+v", r)
+	}
+	if r = Moderate("正常内容"); r.Blocked || r.Text != "正常内容" || len(r.Hits) != 0 {
+		t.Errorf("clean = %+v", r)
+	}
+
+	ClassifierBackend = &fakeClassifier{err: errors.New("timeout")}
+	if r = Moderate("代开发票"); r.Blocked || r.Text != "****" {
+		t.Errorf("api error should fall back to words: %+v", r)
+	}
+}
+
+func TestParseWords(t *testing.T) {
+	words := ParseWords("广告: 代开发票, 加微信 ;刷单")
+	want := map[string]string{"代开发票": "广告", "加微信": "广告", "刷单": Default_Category}
+	if len(words) != len(want) {
+		t.Errorf("ParseWords = %v", words)
+	}
+	for w, c := range want {
+		if words[w] != c {
+			t.Errorf("ParseWords[%s] = %q, want %q", w, words[w], c)
+		}
+	}
+
+	words = ParseWordList("# 注释\n代开发票\t广告\n\n刷单\n")
+	if len(words) != 2 || words["代开发票"] != "广告" || words["刷单"] != Default_Category {
+		t.Errorf("ParseWordList = %v", words)
+	}
+}
+
+func TestRecord(t *testing.T) {
+	useBackends(t, map[string]string{"代开发票": "广告"}, nil)
+	Record("mod-u1", STAGE_OUTPUT, Moderate("代开发票"))
+	Record("mod-u1", STAGE_INPUT, Moderate("正常"))
+	logs, err := Logs(10)
+	if err != nil || len(logs) == 0 {
+		t.Fatalf("Logs = %v, %v", logs, err)
+	}
+	if l := logs[0]; l.UserId != "mod-u1" || l.Stage != STAGE_OUTPUT || l.Hits[0].Word != "代开发票" {
+		t.Errorf("latest log = %+v", l)
+	}
+}
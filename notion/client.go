@@ -0,0 +1,200 @@
+package notion
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	API_URL     = "https://api.notion.com"
+	API_VERSION = "2022-06-28"
+
+	// 429和5xx最多重试的次数，Retry-After超过Max_Retry_Wait时按Max_Retry_Wait等待，避免超过vercel的函数时限
+	Max_Retries    = 3
+	Max_Retry_Wait = 5 * time.Second
+)
+
+// Client Notion接口，BaseUrl为空时使用 https://api.notion.com
+type Client struct {
+	BaseUrl string
+	Token   string
+}
+
+func New(baseUrl, token string) *Client {
+	if baseUrl == "" {
+		baseUrl = API_URL
+	}
+	return &Client{BaseUrl: strings.TrimSuffix(baseUrl, "/"), Token: token}
+}
+
+// Error Notion接口返回的错误，如 {"object":"error","status":404,"code":"object_not_found","message":"..."}
+type Error struct {
+	Status  int    `json:"status"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("notion request failed with status code %d", e.Status)
+	}
+	return fmt.Sprintf("notion %s: %s", e.Code, e.Message)
+}
+
+var (
+	httpClient = &http.Client{Timeout: 8 * time.Second}
+	sleep      = time.Sleep
+)
+
+// do 发送请求并解析结果到v，429和5xx时按Retry-After等待后重试
+func (c *Client) do(method, path string, body, v any) error {
+	var data []byte
+	if body != nil {
+		var err error
+		if data, err = json.Marshal(body); err != nil {
+			return err
+		}
+	}
+	for i := 0; ; i++ {
+		req, err := http.NewRequest(method, c.BaseUrl+path, bytes.NewReader(data))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+		req.Header.Set("Notion-Version", API_VERSION)
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := httpClient.Do(req)
+		if err != nil {
+			return err
+		}
+		respData, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return err
+		}
+		if resp.StatusCode == http.StatusOK {
+			if v == nil {
+				return nil
+			}
+			return json.Unmarshal(respData, v)
+		}
+		if i < Max_Retries && retryable(resp.StatusCode) {
+			sleep(retryWait(resp.Header.Get("Retry-After"), i))
+			continue
+		}
+		e := &Error{}
+		json.Unmarshal(respData, e)
+		e.Status = resp.StatusCode
+		return e
+	}
+}
+
+func retryable(status int) bool {
+	return status == http.StatusTooManyRequests || status >= http.StatusInternalServerError
+}
+
+// retryWait Retry-After为秒数，没有时按0.5s、1s、2s退避
+func retryWait(retryAfter string, attempt int) time.Duration {
+	wait := 500 * time.Millisecond << attempt
+	if sec, err := strconv.ParseFloat(strings.TrimSpace(retryAfter), 64); err == nil && sec >= 0 {
+		wait = time.Duration(sec * float64(time.Second))
+	}
+	return min(wait, Max_Retry_Wait)
+}
+
+// Page 数据库中的一条记录
+type Page struct {
+	Id             string              `json:"id"`
+	Url            string              `json:"url,omitempty"`
+	CreatedTime    string              `json:"created_time,omitempty"`
+	LastEditedTime string              `json:"last_edited_time,omitempty"`
+	Archived       bool                `json:"archived,omitempty"`
+	Properties     map[string]Property `json:"properties"`
+}
+
+// CreatePage 在数据库中新建记录
+func (c *Client) CreatePage(databaseId string, props map[string]Property) (*Page, error) {
+	body := map[string]any{
+		"parent":     map[string]string{"database_id": databaseId},
+		"properties": props,
+	}
+	page := &Page{}
+	if err := c.do(http.MethodPost, "/v1/pages", body, page); err != nil {
+		return nil, err
+	}
+	return page, nil
+}
+
+// UpdatePage 修改记录的属性，只修改props中的属性
+func (c *Client) UpdatePage(pageId string, props map[string]Property) (*Page, error) {
+	page := &Page{}
+	if err := c.do(http.MethodPatch, "/v1/pages/"+pageId, map[string]any{"properties": props}, page); err != nil {
+		return nil, err
+	}
+	return page, nil
+}
+
+// ArchivePage 归档(删除)记录，可以在Notion的回收站中恢复
+func (c *Client) ArchivePage(pageId string) error {
+	return c.do(http.MethodPatch, "/v1/pages/"+pageId, map[string]any{"archived": true}, nil)
+}
+
+// Query 数据库查询条件，Limit为0时返回全部结果
+type Query struct {
+	Filter *Filter
+	Sorts  []Sort
+	Limit  int
+}
+
+// Sort 按属性排序，Direction为 ascending 或 descending
+type Sort struct {
+	Property  string `json:"property,omitempty"`
+	Timestamp string `json:"timestamp,omitempty"`
+	Direction string `json:"direction"`
+}
+
+// Max_Page_Size Notion每次查询最多返回100条
+const Max_Page_Size = 100
+
+// QueryDatabase 查询数据库，结果超过一页时按next_cursor继续查询
+func (c *Client) QueryDatabase(databaseId string, q Query) ([]Page, error) {
+	var pages []Page
+	cursor := ""
+	for {
+		body := map[string]any{}
+		if q.Filter != nil {
+			body["filter"] = q.Filter
+		}
+		if len(q.Sorts) > 0 {
+			body["sorts"] = q.Sorts
+		}
+		if q.Limit > 0 && q.Limit-len(pages) < Max_Page_Size {
+			body["page_size"] = q.Limit - len(pages)
+		}
+		if cursor != "" {
+			body["start_cursor"] = cursor
+		}
+		var result struct {
+			Results    []Page `json:"results"`
+			HasMore    bool   `json:"has_more"`
+			NextCursor string `json:"next_cursor"`
+		}
+		if err := c.do(http.MethodPost, "/v1/databases/"+databaseId+"/query", body, &result); err != nil {
+			return nil, err
+		}
+		pages = append(pages, result.Results...)
+		if q.Limit > 0 && len(pages) >= q.Limit {
+			return pages[:q.Limit], nil
+		}
+		if !result.HasMore || result.NextCursor == "" {
+			return pages, nil
+		}
+		cursor = result.NextCursor
+	}
+}
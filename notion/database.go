@@ -0,0 +1,47 @@
+package notion
+
+import "net/http"
+
+// Database 数据库的结构，Properties的key为属性名
+type Database struct {
+	Id         string                      `json:"id"`
+	Title      []RichText                  `json:"title"`
+	Properties map[string]DatabaseProperty `json:"properties"`
+}
+
+type DatabaseProperty struct {
+	Id          string   `json:"id"`
+	Name        string   `json:"name"`
+	Type        string   `json:"type"`
+	Select      *Options `json:"select,omitempty"`
+	MultiSelect *Options `json:"multi_select,omitempty"`
+}
+
+type Options struct {
+	Options []SelectOption `json:"options"`
+}
+
+// OptionNames 单选、多选属性的选项，其它类型返回nil
+func (p DatabaseProperty) OptionNames() []string {
+	opts := p.Select
+	if p.Type == Type_Multi_Select {
+		opts = p.MultiSelect
+	}
+	if opts == nil {
+		return nil
+	}
+	names := make([]string, 0, len(opts.Options))
+	for _, o := range opts.Options {
+		names = append(names, o.Name)
+	}
+	return names
+}
+
+// RetrieveDatabase 查询数据库的属性和单选、多选的选项
+func (c *Client) RetrieveDatabase(databaseId string) (*Database, error) {
+	database := &Database{}
+	if err := c.do(http.MethodGet, "/v1/databases/"+databaseId, nil, database); err != nil {
+		return nil, err
+	}
+	return database, nil
+}
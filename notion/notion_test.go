@@ -0,0 +1,166 @@
+package notion
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func noSleep(t *testing.T) *[]time.Duration {
+	var waits []time.Duration
+	old := sleep
+	sleep = func(d time.Duration) { waits = append(waits, d) }
+	t.Cleanup(func() { sleep = old })
+	return &waits
+}
+
+func TestRetry(t *testing.T) {
+	waits := noSleep(t)
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if r.Header.Get("Authorization") != "Bearer secret" || r.Header.Get("Notion-Version") != API_VERSION {
+			t.Errorf("headers = %v", r.Header)
+		}
+		body, _ := io.ReadAll(r.Body)
+		if string(body) != `{"archived":true}` {
+			t.Errorf("body = %s", body)
+		}
+		switch calls {
+		case 1:
+			w.Header().Set("Retry-After", "2")
+			w.WriteHeader(http.StatusTooManyRequests)
+		case 2:
+			w.WriteHeader(http.StatusBadGateway)
+		default:
+			w.Write([]byte(`{"id":"p1","archived":true}`))
+		}
+	}))
+	defer server.Close()
+
+	if err := New(server.URL+"/", "secret").ArchivePage("p1"); err != nil {
+		t.Fatal(err)
+	}
+	if calls != 3 || len(*waits) != 2 || (*waits)[0] != 2*time.Second || (*waits)[1] != time.Second {
+		t.Errorf("calls = %d, waits = %v", calls, *waits)
+	}
+}
+
+func TestError(t *testing.T) {
+	waits := noSleep(t)
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if r.URL.Path == "/v1/pages/busy" {
+			w.Header().Set("Retry-After", "60")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"object":"error","status":404,"code":"object_not_found","message":"Could not find page"}`))
+	}))
+	defer server.Close()
+
+	c := New(server.URL, "secret")
+	_, err := c.UpdatePage("missing", map[string]Property{"金额": Number(1)})
+	if e, ok := err.(*Error); !ok || e.Status != 404 || e.Code != "object_not_found" || calls != 1 {
+		t.Errorf("404 error = %v, calls = %d", err, calls)
+	}
+	// 4xx不重试，429重试Max_Retries次后返回错误，等待时间不超过Max_Retry_Wait
+	calls = 0
+	err = c.ArchivePage("busy")
+	if e, ok := err.(*Error); !ok || e.Status != http.StatusTooManyRequests || calls != Max_Retries+1 {
+		t.Errorf("429 error = %v, calls = %d", err, calls)
+	}
+	for _, wait := range *waits {
+		if wait != Max_Retry_Wait {
+			t.Errorf("wait = %v", wait)
+		}
+	}
+}
+
+func TestQueryDatabase(t *testing.T) {
+	var bodies []map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/databases/db1/query" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		var body map[string]any
+		json.NewDecoder(r.Body).Decode(&body)
+		bodies = append(bodies, body)
+		if body["start_cursor"] == nil {
+			w.Write([]byte(`{"results":[{"id":"a"},{"id":"b"}],"has_more":true,"next_cursor":"c2"}`))
+		} else {
+			w.Write([]byte(`{"results":[{"id":"c"}],"has_more":false,"next_cursor":null}`))
+		}
+	}))
+	defer server.Close()
+
+	c := New(server.URL, "secret")
+	filter := And(TitleEquals("用户id", "u1"), SelectEquals("数据库类型", "0"))
+	pages, err := c.QueryDatabase("db1", Query{Filter: filter, Sorts: []Sort{{Property: "日期", Direction: "descending"}}})
+	if err != nil || len(pages) != 3 || pages[2].Id != "c" {
+		t.Fatalf("QueryDatabase = %v, %v", pages, err)
+	}
+	if len(bodies) != 2 || bodies[1]["start_cursor"] != "c2" {
+		t.Errorf("bodies = %v", bodies)
+	}
+	data, _ := json.Marshal(bodies[0]["filter"])
+	if string(data) != `{"and":[{"property":"用户id","title":{"equals":"u1"}},{"property":"数据库类型","select":{"equals":"0"}}]}` {
+		t.Errorf("filter = %s", data)
+	}
+
+	bodies = nil
+	pages, err = c.QueryDatabase("db1", Query{Limit: 1})
+	if err != nil || len(pages) != 1 || len(bodies) != 1 || bodies[0]["page_size"] != float64(1) {
+		t.Errorf("limit = %v, %v, %v", pages, err, bodies)
+	}
+}
+
+func TestProperty(t *testing.T) {
+	props := map[string]Property{
+		"名称": Title("午饭"),
+		"金额": Number(35.5),
+		"标签": Select("生活吃喝加买菜"),
+		"单位": MultiSelect("固定工资", ""),
+		"日期": DateOf("2025-01-12"),
+		"备注": RichTextOf(""),
+	}
+	data, err := json.Marshal(props)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"单位":{"multi_select":[{"name":"固定工资"}]},"名称":{"title":[{"text":{"content":"午饭"}}]},"备注":{"rich_text":[]},` +
+		`"日期":{"date":{"start":"2025-01-12"}},"标签":{"select":{"name":"生活吃喝加买菜"}},"金额":{"number":35.5}}`
+	if string(data) != want {
+		t.Errorf("properties = %s", data)
+	}
+
+	var page Page
+	err = json.Unmarshal([]byte(`{"id":"p1","properties":{
+		"名称":{"id":"title","type":"title","title":[{"type":"text","text":{"content":"午"},"plain_text":"午"},{"plain_text":"饭"}]},
+		"金额":{"type":"number","number":35.5},
+		"空金额":{"type":"number","number":null},
+		"标签":{"type":"select","select":{"id":"x","name":"生活吃喝加买菜","color":"red"}},
+		"空标签":{"type":"select","select":null},
+		"多选":{"type":"multi_select","multi_select":[{"name":"a"},{"name":"b"}]},
+		"日期":{"type":"date","date":{"start":"2025-01-12","end":null}},
+		"备注":{"type":"rich_text","rich_text":[]}}}`), &page)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := page.Properties
+	if p["名称"].PlainText() != "午饭" || p["金额"].NumberValue() != 35.5 || p["标签"].SelectName() != "生活吃喝加买菜" ||
+		len(p["多选"].MultiSelectNames()) != 2 || p["日期"].DateStart() != "2025-01-12" {
+		t.Errorf("parsed = %+v", p)
+	}
+	// 属性不存在或类型不符时返回零值而不是panic
+	if p["空金额"].NumberValue() != 0 || p["空标签"].SelectName() != "" || p["备注"].PlainText() != "" ||
+		p["不存在"].PlainText() != "" || p["金额"].SelectName() != "" || p["标签"].DateStart() != "" {
+		t.Errorf("zero values = %+v", p)
+	}
+}
@@ -0,0 +1,201 @@
+package notion
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+const (
+	Type_Title        = "title"
+	Type_Rich_Text    = "rich_text"
+	Type_Number       = "number"
+	Type_Select       = "select"
+	Type_Multi_Select = "multi_select"
+	Type_Date         = "date"
+)
+
+// RichText 标题和文本属性的片段，写入时使用Text，读取时使用PlainText
+type RichText struct {
+	Type      string `json:"type,omitempty"`
+	Text      *Text  `json:"text,omitempty"`
+	PlainText string `json:"plain_text,omitempty"`
+}
+
+type Text struct {
+	Content string `json:"content"`
+}
+
+type SelectOption struct {
+	Id    string `json:"id,omitempty"`
+	Name  string `json:"name"`
+	Color string `json:"color,omitempty"`
+}
+
+// Date Start为 2006-01-02 或带时间的 RFC3339
+type Date struct {
+	Start string `json:"start"`
+	End   string `json:"end,omitempty"`
+}
+
+// Property 记录的属性值，用Title、Number等函数创建，用PlainText、NumberValue等方法读取，类型不符时返回零值
+type Property struct {
+	Id          string         `json:"id,omitempty"`
+	Type        string         `json:"type,omitempty"`
+	Title       []RichText     `json:"title,omitempty"`
+	RichText    []RichText     `json:"rich_text,omitempty"`
+	Number      *float64       `json:"number,omitempty"`
+	Select      *SelectOption  `json:"select,omitempty"`
+	MultiSelect []SelectOption `json:"multi_select,omitempty"`
+	Date        *Date          `json:"date,omitempty"`
+}
+
+// MarshalJSON 只输出Type对应的字段，空文本输出为 [] 而不是省略
+func (p Property) MarshalJSON() ([]byte, error) {
+	var value any
+	switch p.Type {
+	case Type_Title:
+		value = nonNil(p.Title)
+	case Type_Rich_Text:
+		value = nonNil(p.RichText)
+	case Type_Number:
+		value = p.Number
+	case Type_Select:
+		value = p.Select
+	case Type_Multi_Select:
+		value = nonNil(p.MultiSelect)
+	case Type_Date:
+		value = p.Date
+	default:
+		type raw Property
+		return json.Marshal(raw(p))
+	}
+	return json.Marshal(map[string]any{p.Type: value})
+}
+
+func nonNil[T any](list []T) []T {
+	if list == nil {
+		return []T{}
+	}
+	return list
+}
+
+func textList(s string) []RichText {
+	if s == "" {
+		return nil
+	}
+	return []RichText{{Text: &Text{Content: s}}}
+}
+
+func Title(s string) Property {
+	return Property{Type: Type_Title, Title: textList(s)}
+}
+
+func RichTextOf(s string) Property {
+	return Property{Type: Type_Rich_Text, RichText: textList(s)}
+}
+
+func Number(n float64) Property {
+	return Property{Type: Type_Number, Number: &n}
+}
+
+// Select name为空时清空选项
+func Select(name string) Property {
+	if name == "" {
+		return Property{Type: Type_Select}
+	}
+	return Property{Type: Type_Select, Select: &SelectOption{Name: name}}
+}
+
+func MultiSelect(names ...string) Property {
+	p := Property{Type: Type_Multi_Select}
+	for _, name := range names {
+		if name != "" {
+			p.MultiSelect = append(p.MultiSelect, SelectOption{Name: name})
+		}
+	}
+	return p
+}
+
+func DateOf(start string) Property {
+	return Property{Type: Type_Date, Date: &Date{Start: start}}
+}
+
+// PlainText 标题或文本属性的内容
+func (p Property) PlainText() string {
+	list := p.RichText
+	if p.Type == Type_Title || len(p.Title) > 0 {
+		list = p.Title
+	}
+	var sb strings.Builder
+	for _, t := range list {
+		if t.PlainText != "" {
+			sb.WriteString(t.PlainText)
+		} else if t.Text != nil {
+			sb.WriteString(t.Text.Content)
+		}
+	}
+	return sb.String()
+}
+
+func (p Property) NumberValue() float64 {
+	if p.Number == nil {
+		return 0
+	}
+	return *p.Number
+}
+
+func (p Property) SelectName() string {
+	if p.Select == nil {
+		return ""
+	}
+	return p.Select.Name
+}
+
+func (p Property) MultiSelectNames() []string {
+	var names []string
+	for _, o := range p.MultiSelect {
+		names = append(names, o.Name)
+	}
+	return names
+}
+
+// DateStart 日期属性的开始时间，没有时返回空字符串
+func (p Property) DateStart() string {
+	if p.Date == nil {
+		return ""
+	}
+	return p.Date.Start
+}
+
+// Filter 数据库查询的过滤条件，And、Or用于组合多个条件
+type Filter struct {
+	Property    string     `json:"property,omitempty"`
+	Title       *Condition `json:"title,omitempty"`
+	RichText    *Condition `json:"rich_text,omitempty"`
+	Number      *Condition `json:"number,omitempty"`
+	Select      *Condition `json:"select,omitempty"`
+	MultiSelect *Condition `json:"multi_select,omitempty"`
+	Date        *Condition `json:"date,omitempty"`
+	And         []Filter   `json:"and,omitempty"`
+	Or          []Filter   `json:"or,omitempty"`
+}
+
+type Condition struct {
+	Equals     any    `json:"equals,omitempty"`
+	Contains   string `json:"contains,omitempty"`
+	OnOrAfter  string `json:"on_or_after,omitempty"`
+	OnOrBefore string `json:"on_or_before,omitempty"`
+	Before     string `json:"before,omitempty"`
+}
+
+func And(filters ...Filter) *Filter {
+	return &Filter{And: filters}
+}
+
+func TitleEquals(property, value string) Filter {
+	return Filter{Property: property, Title: &Condition{Equals: value}}
+}
+
+func SelectEquals(property, value string) Filter {
+	return Filter{Property: property, Select: &Condition{Equals: value}}
+}
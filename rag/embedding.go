@@ -0,0 +1,73 @@
+package rag
+
+import (
+	"context"
+	"errors"
+
+	"github.com/pwh-pwh/aiwechat-vercel/config"
+	"github.com/sashabaranov/go-openai"
+)
+
+// 每次请求向量接口最多提交的文本数
+const embeddingBatchSize = 64
+
+// Embedder 把文本转换为向量
+type Embedder interface {
+	Embed(texts []string) ([][]float32, error)
+}
+
+// OpenAIEmbedder 使用openai兼容的/embeddings接口
+type OpenAIEmbedder struct {
+	client *openai.Client
+	model  string
+}
+
+func NewOpenAIEmbedder(url, token, model string) *OpenAIEmbedder {
+	cfg := openai.DefaultConfig(token)
+	cfg.BaseURL = url
+	return &OpenAIEmbedder{
+		client: openai.NewClientWithConfig(cfg),
+		model:  model,
+	}
+}
+
+func (e *OpenAIEmbedder) Embed(texts []string) ([][]float32, error) {
+	vectors := make([][]float32, 0, len(texts))
+	for start := 0; start < len(texts); start += embeddingBatchSize {
+		end := min(start+embeddingBatchSize, len(texts))
+		resp, err := e.client.CreateEmbeddings(context.Background(), openai.EmbeddingRequest{
+			Input: texts[start:end],
+			Model: openai.EmbeddingModel(e.model),
+		})
+		if err != nil {
+			return nil, err
+		}
+		if len(resp.Data) != end-start {
+			return nil, errors.New("向量接口返回的数量与请求不一致")
+		}
+		batch := make([][]float32, end-start)
+		for _, data := range resp.Data {
+			if data.Index < 0 || data.Index >= len(batch) {
+				return nil, errors.New("向量接口返回的下标错误")
+			}
+			batch[data.Index] = data.Embedding
+		}
+		vectors = append(vectors, batch...)
+	}
+	return vectors, nil
+}
+
+// EmbeddingBackend 不为空时替代配置中的向量接口，测试时可以替换为本地实现
+var EmbeddingBackend Embedder
+
+// getEmbedder 没有配置向量接口时返回nil，此时只使用BM25检索
+func getEmbedder() Embedder {
+	if EmbeddingBackend != nil {
+		return EmbeddingBackend
+	}
+	token := config.GetKbEmbeddingToken()
+	if token == "" {
+		return nil
+	}
+	return NewOpenAIEmbedder(config.GetKbEmbeddingUrl(), token, config.GetKbEmbeddingModel())
+}
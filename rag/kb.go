@@ -0,0 +1,195 @@
+package rag
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/bytedance/sonic"
+	"github.com/pwh-pwh/aiwechat-vercel/db"
+	"github.com/pwh-pwh/aiwechat-vercel/document"
+)
+
+const (
+	// 向量检索时相似度低于该值的片段视为不相关
+	Min_Vector_Score = 0.3
+	// 知识库在内存中缓存的时间，避免每条消息都读取redis
+	cacheDuration = time.Minute
+)
+
+// Chunk 知识库片段，配置了向量接口时Vector保存片段的向量
+type Chunk struct {
+	Source string
+	Title  string
+	Text   string
+	Vector []float32 `json:",omitempty"`
+}
+
+// Content 用于生成向量和BM25索引的文本
+func (c Chunk) Content() string {
+	if c.Title == "" {
+		return c.Text
+	}
+	return c.Title + "\n" + c.Text
+}
+
+var (
+	cacheLock   sync.Mutex
+	cacheChunks []Chunk
+	cacheTime   time.Time
+)
+
+// Add 切分并保存markdown文档，同名文档会被覆盖；生成向量失败时仍然保存，检索时使用BM25
+func Add(name, content string) (count int, warning string, err error) {
+	chunks := SplitMarkdown(name, content)
+	if len(chunks) == 0 {
+		return 0, "", fmt.Errorf("%s 没有内容", name)
+	}
+	if embedder := getEmbedder(); embedder != nil {
+		texts := make([]string, len(chunks))
+		for i, chunk := range chunks {
+			texts[i] = chunk.Content()
+		}
+		vectors, err := embedder.Embed(texts)
+		if err != nil {
+			warning = fmt.Sprintf("生成向量失败，将使用关键词检索: %v", err)
+		} else {
+			for i := range chunks {
+				chunks[i].Vector = vectors[i]
+			}
+		}
+	}
+	data, err := sonic.MarshalString(chunks)
+	if err != nil {
+		return 0, "", err
+	}
+	if err = db.SetKnowledge(name, data); err != nil {
+		return 0, "", err
+	}
+	resetCache()
+	return len(chunks), warning, nil
+}
+
+func Remove(name string) error {
+	defer resetCache()
+	return db.DeleteKnowledge(name)
+}
+
+// List 返回每个文档的片段数
+func List() (map[string]int, error) {
+	chunks, err := loadChunks()
+	if err != nil {
+		return nil, err
+	}
+	list := map[string]int{}
+	for _, chunk := range chunks {
+		list[chunk.Source]++
+	}
+	return list, nil
+}
+
+// Search 检索与query最相关的k个片段，所有片段都有向量且向量接口可用时使用向量检索，否则使用BM25
+func Search(query string, k int) ([]Chunk, error) {
+	chunks, err := loadChunks()
+	if err != nil || len(chunks) == 0 {
+		return nil, err
+	}
+	if embedder := getEmbedder(); embedder != nil && hasVectors(chunks) {
+		vectors, err := embedder.Embed([]string{query})
+		if err == nil && len(vectors) == 1 {
+			return vectorSearch(chunks, vectors[0], k), nil
+		}
+		fmt.Println("kb embedding error:", err)
+	}
+	texts := make([]string, len(chunks))
+	for i, chunk := range chunks {
+		texts[i] = chunk.Content()
+	}
+	var result []Chunk
+	for _, r := range document.NewIndex(texts).Search(query, k) {
+		result = append(result, chunks[r.Id])
+	}
+	return result, nil
+}
+
+func hasVectors(chunks []Chunk) bool {
+	for _, chunk := range chunks {
+		if len(chunk.Vector) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func vectorSearch(chunks []Chunk, query []float32, k int) []Chunk {
+	type scored struct {
+		chunk Chunk
+		score float64
+	}
+	var list []scored
+	for _, chunk := range chunks {
+		if score := cosine(chunk.Vector, query); score >= Min_Vector_Score {
+			list = append(list, scored{chunk, score})
+		}
+	}
+	sort.SliceStable(list, func(i, j int) bool {
+		return list[i].score > list[j].score
+	})
+	var result []Chunk
+	for i := 0; i < len(list) && i < k; i++ {
+		result = append(result, list[i].chunk)
+	}
+	return result
+}
+
+func cosine(a, b []float32) float64 {
+	if len(a) != len(b) {
+		return 0
+	}
+	var dot, na, nb float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		na += float64(a[i]) * float64(a[i])
+		nb += float64(b[i]) * float64(b[i])
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(na) * math.Sqrt(nb))
+}
+
+func loadChunks() ([]Chunk, error) {
+	cacheLock.Lock()
+	defer cacheLock.Unlock()
+	if !cacheTime.IsZero() && time.Since(cacheTime) < cacheDuration {
+		return cacheChunks, nil
+	}
+	kb, err := db.GetKnowledge()
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(kb))
+	for name := range kb {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var chunks []Chunk
+	for _, name := range names {
+		var list []Chunk
+		if err := sonic.UnmarshalString(kb[name], &list); err != nil {
+			fmt.Printf("kb %s unmarshal error: %v\n", name, err)
+			continue
+		}
+		chunks = append(chunks, list...)
+	}
+	cacheChunks, cacheTime = chunks, time.Now()
+	return chunks, nil
+}
+
+func resetCache() {
+	cacheLock.Lock()
+	defer cacheLock.Unlock()
+	cacheChunks, cacheTime = nil, time.Time{}
+}
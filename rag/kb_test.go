@@ -0,0 +1,99 @@
+package rag
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// fakeEmbedder 按关键词生成向量，每个关键词一个维度
+type fakeEmbedder struct {
+	keywords []string
+	err      error
+	calls    int
+}
+
+func (f *fakeEmbedder) Embed(texts []string) ([][]float32, error) {
+	f.calls++
+	if f.err != nil {
+		return nil, f.err
+	}
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		vectors[i] = make([]float32, len(f.keywords))
+		for j, keyword := range f.keywords {
+			if strings.Contains(text, keyword) {
+				vectors[i][j] = 1
+			}
+		}
+	}
+	return vectors, nil
+}
+
+func useEmbedder(t *testing.T, embedder Embedder) {
+	t.Helper()
+	EmbeddingBackend = embedder
+	t.Cleanup(func() {
+		EmbeddingBackend = nil
+		for name := range mustList(t) {
+			Remove(name)
+		}
+	})
+}
+
+func mustList(t *testing.T) map[string]int {
+	t.Helper()
+	list, err := List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return list
+}
+
+const faq = "# 保修\n本产品保修期为一年。\n# 退货\n收到商品七天内可以无理由退货。\n# 客服\n客服工作时间为每天9点到18点。\n"
+
+func TestKnowledgeVectorSearch(t *testing.T) {
+	embedder := &fakeEmbedder{keywords: []string{"保修", "退货", "客服"}}
+	useEmbedder(t, embedder)
+
+	count, warning, err := Add("faq.md", faq)
+	if err != nil || warning != "" || count != 3 {
+		t.Fatalf("Add = %d %q %v", count, warning, err)
+	}
+	if list := mustList(t); list["faq.md"] != 3 {
+		t.Errorf("List = %v", list)
+	}
+	chunks, err := Search("怎么退货", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(chunks) != 1 || chunks[0].Title != "退货" {
+		t.Errorf("Search = %+v", chunks)
+	}
+	if embedder.calls != 2 {
+		t.Errorf("embedder called %d times", embedder.calls)
+	}
+}
+
+func TestKnowledgeBM25Fallback(t *testing.T) {
+	useEmbedder(t, &fakeEmbedder{err: errors.New("offline")})
+
+	_, warning, err := Add("faq.md", faq)
+	if err != nil || !strings.Contains(warning, "offline") {
+		t.Fatalf("Add warning=%q err=%v", warning, err)
+	}
+	chunks, err := Search("客服几点上班", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(chunks) != 1 || chunks[0].Title != "客服" {
+		t.Errorf("Search = %+v", chunks)
+	}
+
+	if err = Remove("faq.md"); err != nil {
+		t.Fatal(err)
+	}
+	if chunks, _ = Search("客服", 1); len(chunks) != 0 {
+		t.Errorf("Search after remove = %+v", chunks)
+	}
+}
@@ -0,0 +1,60 @@
+package rag
+
+import (
+	"strings"
+
+	"github.com/pwh-pwh/aiwechat-vercel/document"
+)
+
+const (
+	Chunk_Size    = 500
+	Chunk_Overlap = 50
+)
+
+// SplitMarkdown 按标题把markdown切分为片段，片段的Title为所在的标题路径，如 "售后 > 退货"
+func SplitMarkdown(source, content string) []Chunk {
+	var chunks []Chunk
+	var headings []string
+	var body strings.Builder
+	flush := func() {
+		title := strings.Join(headings, " > ")
+		for _, c := range document.Split([]document.Page{{Number: 1, Text: body.String()}}, Chunk_Size, Chunk_Overlap) {
+			chunks = append(chunks, Chunk{Source: source, Title: title, Text: c.Text})
+		}
+		body.Reset()
+	}
+	inCode := false
+	for _, line := range strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") {
+			inCode = !inCode
+		}
+		if level := headingLevel(trimmed); level > 0 && !inCode {
+			flush()
+			if level <= len(headings) {
+				headings = headings[:level-1]
+			}
+			for len(headings) < level-1 {
+				headings = append(headings, "")
+			}
+			headings = append(headings, strings.TrimSpace(trimmed[level:]))
+			continue
+		}
+		body.WriteString(line)
+		body.WriteString("\n")
+	}
+	flush()
+	return chunks
+}
+
+// headingLevel 返回标题级别，不是标题时返回0
+func headingLevel(line string) int {
+	level := 0
+	for level < len(line) && line[level] == '#' {
+		level++
+	}
+	if level == 0 || level > 6 || level == len(line) || line[level] != ' ' {
+		return 0
+	}
+	return level
+}
@@ -0,0 +1,28 @@
+package rag
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestSplitMarkdown(t *testing.T) {
+	md := "简介\n# 产品\n## 保修\n保修期一年。\n\n```\n# 不是标题\n```\n## 退货\n七天无理由退货。\n# 联系我们\n#话题 不是标题\n"
+	chunks := SplitMarkdown("faq.md", md)
+	want := []Chunk{
+		{Source: "faq.md", Text: "简介"},
+		{Source: "faq.md", Title: "产品 > 保修", Text: "保修期一年。\n```\n# 不是标题\n```"},
+		{Source: "faq.md", Title: "产品 > 退货", Text: "七天无理由退货。"},
+		{Source: "faq.md", Title: "联系我们", Text: "#话题 不是标题"},
+	}
+	if fmt.Sprint(chunks) != fmt.Sprint(want) {
+		t.Errorf("chunks = %+v\nwant %+v", chunks, want)
+	}
+}
+
+func TestHeadingLevel(t *testing.T) {
+	for line, want := range map[string]int{"# a": 1, "### b": 3, "#": 0, "#c": 0, "####### d": 0, "text": 0} {
+		if got := headingLevel(line); got != want {
+			t.Errorf("headingLevel(%q) = %d, want %d", line, got, want)
+		}
+	}
+}
@@ -0,0 +1,206 @@
+package remind
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bytedance/sonic"
+	"github.com/pwh-pwh/aiwechat-vercel/config"
+	"github.com/pwh-pwh/aiwechat-vercel/db"
+	"github.com/pwh-pwh/aiwechat-vercel/timeparse"
+)
+
+// 每个用户最多保存的提醒数
+const Max_Reminders = 50
+
+// Reminder 定时提醒，Rule不为空时为重复提醒，Time为下一次提醒的时间
+type Reminder struct {
+	Id      int
+	UserId  string
+	Content string
+	Time    int64
+	Rule    *timeparse.Rule `json:",omitempty"`
+	Created int64
+}
+
+// Now 当前时间，测试时可以替换
+var Now = config.Now
+
+var (
+	ErrNoTime    = errors.New("没有识别到提醒时间，例如：明天9点 交房租、30分钟后 关火、每个工作日9点 打卡、每月1号 交房租")
+	ErrNoContent = errors.New("提醒内容不能为空")
+	ErrPast      = errors.New("提醒时间已经过了")
+)
+
+// Parse 解析提醒时间和内容，重复规则写在开头，单次提醒的时间写在开头或结尾
+func Parse(text string) (content string, t time.Time, rule *timeparse.Rule, err error) {
+	now := Now()
+	if r, rest, ok := timeparse.ParseRule(text); ok {
+		if rest == "" {
+			return "", time.Time{}, nil, ErrNoContent
+		}
+		return rest, r.Next(now), &r, nil
+	}
+	r, ok := timeparse.Parse(text, now)
+	if !ok {
+		return "", time.Time{}, nil, ErrNoTime
+	}
+	if r.Rest == "" {
+		return "", time.Time{}, nil, ErrNoContent
+	}
+	if !r.Time.After(now) {
+		return "", time.Time{}, nil, ErrPast
+	}
+	return r.Rest, r.Time, nil, nil
+}
+
+// Add 添加提醒，如 明天9点 交房租
+func Add(userId, text string) (*Reminder, error) {
+	content, t, rule, err := Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	reminders, err := List(userId)
+	if err != nil {
+		return nil, err
+	}
+	if len(reminders) >= Max_Reminders {
+		return nil, fmt.Errorf("最多只能设置%d个提醒", Max_Reminders)
+	}
+	id := 0
+	for _, r := range reminders {
+		id = max(id, r.Id)
+	}
+	reminder := &Reminder{Id: id + 1, UserId: userId, Content: content, Time: t.Unix(), Rule: rule, Created: Now().Unix()}
+	return reminder, Save(reminder)
+}
+
+// List 返回用户的提醒，按下一次提醒时间排序
+func List(userId string) ([]*Reminder, error) {
+	values, err := db.GetReminders(userId)
+	if err != nil {
+		return nil, err
+	}
+	reminders := make([]*Reminder, 0, len(values))
+	for id, value := range values {
+		var r Reminder
+		if err := sonic.UnmarshalString(value, &r); err != nil {
+			fmt.Printf("reminder %s of %s unmarshal error: %v\n", id, userId, err)
+			continue
+		}
+		reminders = append(reminders, &r)
+	}
+	sort.Slice(reminders, func(i, j int) bool {
+		if reminders[i].Time != reminders[j].Time {
+			return reminders[i].Time < reminders[j].Time
+		}
+		return reminders[i].Id < reminders[j].Id
+	})
+	return reminders, nil
+}
+
+func Get(userId string, id int) (*Reminder, error) {
+	values, err := db.GetReminders(userId)
+	if err != nil {
+		return nil, err
+	}
+	data, ok := values[strconv.Itoa(id)]
+	if !ok {
+		return nil, fmt.Errorf("提醒#%d不存在", id)
+	}
+	var r Reminder
+	return &r, sonic.UnmarshalString(data, &r)
+}
+
+func Delete(userId string, id int) (*Reminder, error) {
+	r, err := Get(userId, id)
+	if err != nil {
+		return nil, err
+	}
+	db.DeleteReminderDue(r.member())
+	return r, db.DeleteReminder(userId, strconv.Itoa(id))
+}
+
+// Save 保存提醒并更新提醒时间
+func Save(r *Reminder) error {
+	data, err := sonic.MarshalString(r)
+	if err != nil {
+		return err
+	}
+	if err = db.SetReminder(r.UserId, strconv.Itoa(r.Id), data); err != nil {
+		return err
+	}
+	return db.SetReminderDue(r.member(), r.Time)
+}
+
+// Due 返回提醒时间在before之前的提醒，返回的提醒已从提醒时间索引中取出，
+// 发送成功后调用Fired，失败时调用Retry放回索引
+func Due(before time.Time) ([]*Reminder, error) {
+	members, err := db.GetDueReminders(before.Unix())
+	if err != nil {
+		return nil, err
+	}
+	var reminders []*Reminder
+	for _, member := range members {
+		i := strings.LastIndex(member, ":")
+		id, err := strconv.Atoi(member[i+1:])
+		if i < 0 || err != nil {
+			db.DeleteReminderDue(member)
+			continue
+		}
+		r, err := Get(member[:i], id)
+		if err != nil {
+			db.DeleteReminderDue(member)
+			continue
+		}
+		// 定时任务重叠或重试时只有取出成功的一方发送
+		if ok, err := db.ClaimReminderDue(member); err != nil || !ok {
+			continue
+		}
+		reminders = append(reminders, r)
+	}
+	return reminders, nil
+}
+
+// Fired 提醒发送后调用，重复提醒计算下一次时间，单次提醒删除
+func Fired(r *Reminder) error {
+	if r.Rule != nil {
+		// 定时任务中断一段时间后只补发一次，下一次从现在开始计算
+		if next := r.Rule.Next(Now()); !next.IsZero() {
+			r.Time = next.Unix()
+			return Save(r)
+		}
+	}
+	db.DeleteReminderDue(r.member())
+	return db.DeleteReminder(r.UserId, strconv.Itoa(r.Id))
+}
+
+// Retry 发送失败时调用，在at之后再次提醒
+func Retry(r *Reminder, at time.Time) error {
+	return db.SetReminderDue(r.member(), at.Unix())
+}
+
+func (r *Reminder) member() string {
+	return fmt.Sprintf("%s:%d", r.UserId, r.Id)
+}
+
+// String 列表中显示的一行，如 #2 交房租 (每月1号 09:00，下次 06-01 09:00)
+func (r *Reminder) String() string {
+	t := FormatTime(time.Unix(r.Time, 0).In(Now().Location()))
+	if r.Rule != nil {
+		return fmt.Sprintf("#%d %s (%s，下次 %s)", r.Id, r.Content, r.Rule, t)
+	}
+	return fmt.Sprintf("#%d %s (%s)", r.Id, r.Content, t)
+}
+
+// FormatTime 今年的时间省略年份
+func FormatTime(t time.Time) string {
+	if t.Year() == Now().Year() {
+		return t.Format("01-02 15:04")
+	}
+	return t.Format("2006-01-02 15:04")
+}
@@ -0,0 +1,72 @@
+package remind
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pwh-pwh/aiwechat-vercel/config"
+)
+
+func TestRemind(t *testing.T) {
+	loc := config.GetLocation()
+	// 2024-05-15 是周三
+	now := time.Date(2024, 5, 15, 10, 0, 0, 0, loc)
+	Now = func() time.Time { return now }
+	t.Cleanup(func() {
+		Now = config.Now
+		reminders, _ := List("remind-u1")
+		for _, r := range reminders {
+			Delete("remind-u1", r.Id)
+		}
+	})
+
+	for text, want := range map[string]error{"明天9点": ErrNoContent, "交房租": ErrNoTime, "今天8点前 交房租": ErrPast} {
+		if _, err := Add("remind-u1", text); err != want {
+			t.Errorf("Add(%q) error = %v, want %v", text, err, want)
+		}
+	}
+	once, err := Add("remind-u1", "明天9点 交房租")
+	if err != nil || once.String() != "#1 交房租 (05-16 09:00)" {
+		t.Fatalf("Add = %v, %v", once, err)
+	}
+	weekly, err := Add("remind-u1", "工作日9:30 打卡")
+	if err != nil || weekly.String() != "#2 打卡 (每个工作日 09:30，下次 05-16 09:30)" {
+		t.Fatalf("Add = %v, %v", weekly, err)
+	}
+	if due, _ := Due(now); len(due) != 0 {
+		t.Errorf("Due = %v", due)
+	}
+
+	// 周五下午，两个提醒都到期
+	now = time.Date(2024, 5, 17, 15, 0, 0, 0, loc)
+	due, err := Due(now)
+	if err != nil || len(due) != 2 || due[0].Id != 1 || due[1].Id != 2 {
+		t.Fatalf("Due = %v, %v", due, err)
+	}
+	if again, _ := Due(now); len(again) != 0 {
+		t.Errorf("claimed reminders should not be due again: %v", again)
+	}
+	Retry(due[0], now)
+	if again, _ := Due(now); len(again) != 1 || again[0].Id != 1 {
+		t.Errorf("retried reminder = %v", again)
+	}
+	for _, r := range due {
+		if err = Fired(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+	reminders, _ := List("remind-u1")
+	if len(reminders) != 1 || reminders[0].String() != "#2 打卡 (每个工作日 09:30，下次 05-20 09:30)" {
+		t.Errorf("after fired = %v", reminders)
+	}
+	if due, _ = Due(now); len(due) != 0 {
+		t.Errorf("fired reminders should not be due: %v", due)
+	}
+
+	if _, err = Delete("remind-u1", 2); err != nil {
+		t.Fatal(err)
+	}
+	if due, _ = Due(time.Date(2024, 6, 1, 0, 0, 0, 0, loc)); len(due) != 0 {
+		t.Errorf("deleted reminder should not be due: %v", due)
+	}
+}
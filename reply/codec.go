@@ -0,0 +1,52 @@
+package reply
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/silenceper/wechat/v2/officialaccount/message"
+)
+
+type encodedReply struct {
+	MsgType message.MsgType
+	Data    json.RawMessage
+}
+
+// Marshal 把回复序列化为json，用于缓存回复
+func Marshal(r *message.Reply) (string, error) {
+	data, err := json.Marshal(r.MsgData)
+	if err != nil {
+		return "", err
+	}
+	out, err := json.Marshal(encodedReply{MsgType: r.MsgType, Data: data})
+	return string(out), err
+}
+
+// Unmarshal 按消息类型还原Marshal序列化的回复
+func Unmarshal(s string) (*message.Reply, error) {
+	var encoded encodedReply
+	if err := json.Unmarshal([]byte(s), &encoded); err != nil {
+		return nil, err
+	}
+	var data any
+	switch encoded.MsgType {
+	case message.MsgTypeText:
+		data = &message.Text{}
+	case message.MsgTypeImage:
+		data = &message.Image{}
+	case message.MsgTypeVoice:
+		data = &message.Voice{}
+	case message.MsgTypeVideo:
+		data = &message.Video{}
+	case message.MsgTypeMusic:
+		data = &message.Music{}
+	case message.MsgTypeNews:
+		data = &message.News{}
+	default:
+		return nil, fmt.Errorf("不支持的回复类型: %s", encoded.MsgType)
+	}
+	if err := json.Unmarshal(encoded.Data, data); err != nil {
+		return nil, err
+	}
+	return &message.Reply{MsgType: encoded.MsgType, MsgData: data}, nil
+}
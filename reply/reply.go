@@ -0,0 +1,102 @@
+package reply
+
+import (
+	"encoding/xml"
+	"errors"
+
+	"github.com/silenceper/wechat/v2/officialaccount/message"
+)
+
+// 被动回复的图文消息只能包含一篇文章
+const MaxNewsArticles = 1
+
+func Text(content string) *message.Reply {
+	return &message.Reply{MsgType: message.MsgTypeText, MsgData: message.NewText(content)}
+}
+
+// Image 回复图片，mediaId为上传到公众号素材库的图片id
+func Image(mediaId string) *message.Reply {
+	return &message.Reply{MsgType: message.MsgTypeImage, MsgData: message.NewImage(mediaId)}
+}
+
+// Voice 回复语音，mediaId为上传到公众号素材库的语音id
+func Voice(mediaId string) *message.Reply {
+	return &message.Reply{MsgType: message.MsgTypeVoice, MsgData: message.NewVoice(mediaId)}
+}
+
+// NewsBuilder 图文消息
+type NewsBuilder struct {
+	articles []*message.Article
+}
+
+func News() *NewsBuilder {
+	return &NewsBuilder{}
+}
+
+// Article 添加一篇文章，picUrl为缩略图链接，url为点击后打开的链接
+func (b *NewsBuilder) Article(title, description, picUrl, url string) *NewsBuilder {
+	b.articles = append(b.articles, message.NewArticle(title, description, picUrl, url))
+	return b
+}
+
+func (b *NewsBuilder) Build() (*message.Reply, error) {
+	if len(b.articles) == 0 {
+		return nil, errors.New("图文消息至少需要一篇文章")
+	}
+	if len(b.articles) > MaxNewsArticles {
+		return nil, errors.New("图文消息只能包含一篇文章")
+	}
+	return &message.Reply{MsgType: message.MsgTypeNews, MsgData: message.NewNews(b.articles)}, nil
+}
+
+// MusicBuilder 音乐消息
+type MusicBuilder struct {
+	music *message.Music
+}
+
+// Music 音乐消息必须有缩略图素材id
+func Music(title, musicUrl, thumbMediaId string) *MusicBuilder {
+	return &MusicBuilder{music: message.NewMusic(title, "", musicUrl, "", thumbMediaId)}
+}
+
+func (b *MusicBuilder) Description(description string) *MusicBuilder {
+	b.music.Music.Description = description
+	return b
+}
+
+// HQMusicUrl 高质量音乐链接，wifi环境下优先使用，不设置时使用普通链接
+func (b *MusicBuilder) HQMusicUrl(url string) *MusicBuilder {
+	b.music.Music.HQMusicURL = url
+	return b
+}
+
+func (b *MusicBuilder) Build() (*message.Reply, error) {
+	if b.music.Music.ThumbMediaID == "" {
+		return nil, errors.New("音乐消息需要缩略图素材id")
+	}
+	if b.music.Music.HQMusicURL == "" {
+		b.music.Music.HQMusicURL = b.music.Music.MusicURL
+	}
+	return &message.Reply{MsgType: message.MsgTypeMusic, MsgData: b.music}, nil
+}
+
+// tokenSetter 所有回复消息都内嵌了message.CommonToken
+type tokenSetter interface {
+	SetToUserName(toUserName message.CDATA)
+	SetFromUserName(fromUserName message.CDATA)
+	SetCreateTime(createTime int64)
+	SetMsgType(msgType message.MsgType)
+}
+
+// Render 生成回复的xml，与公众号server回复时的处理一致，用于测试和日志
+func Render(r *message.Reply, toUser, fromUser string, createTime int64) ([]byte, error) {
+	data, ok := r.MsgData.(tokenSetter)
+	if !ok {
+		return nil, message.ErrUnsupportReply
+	}
+	data.SetToUserName(message.CDATA(toUser))
+	data.SetFromUserName(message.CDATA(fromUser))
+	data.SetCreateTime(createTime)
+	data.SetMsgType(r.MsgType)
+	return xml.Marshal(r.MsgData)
+}
@@ -0,0 +1,117 @@
+package reply
+
+import (
+	"testing"
+
+	"github.com/silenceper/wechat/v2/officialaccount/message"
+)
+
+const header = "<xml><ToUserName><![CDATA[user]]></ToUserName><FromUserName><![CDATA[gh_account]]></FromUserName><CreateTime>1700000000</CreateTime>"
+
+func render(t *testing.T, r *message.Reply) string {
+	t.Helper()
+	data, err := Render(r, "user", "gh_account", 1700000000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestText(t *testing.T) {
+	want := header + "<MsgType>text</MsgType><Content><![CDATA[你好 <b>&</b>]]></Content></xml>"
+	if got := render(t, Text("你好 <b>&</b>")); got != want {
+		t.Errorf("got  %s\nwant %s", got, want)
+	}
+}
+
+func TestImage(t *testing.T) {
+	want := header + "<MsgType>image</MsgType><Image><MediaId>media-1</MediaId></Image></xml>"
+	if got := render(t, Image("media-1")); got != want {
+		t.Errorf("got  %s\nwant %s", got, want)
+	}
+}
+
+func TestVoice(t *testing.T) {
+	want := header + "<MsgType>voice</MsgType><Voice><MediaId>voice-1</MediaId></Voice></xml>"
+	if got := render(t, Voice("voice-1")); got != want {
+		t.Errorf("got  %s\nwant %s", got, want)
+	}
+}
+
+func TestNews(t *testing.T) {
+	r, err := News().Article("新品上市", "春季新品", "https://example.com/thumb.png", "https://example.com/a?x=1&y=2").Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := header + "<MsgType>news</MsgType><ArticleCount>1</ArticleCount><Articles><item>" +
+		"<Title>新品上市</Title><Description>春季新品</Description><PicUrl>https://example.com/thumb.png</PicUrl>" +
+		"<Url>https://example.com/a?x=1&amp;y=2</Url></item></Articles></xml>"
+	if got := render(t, r); got != want {
+		t.Errorf("got  %s\nwant %s", got, want)
+	}
+
+	if _, err = News().Build(); err == nil {
+		t.Error("expected error for empty news")
+	}
+	if _, err = News().Article("1", "", "", "").Article("2", "", "", "").Build(); err == nil {
+		t.Error("expected error for more than one article")
+	}
+}
+
+func TestMusic(t *testing.T) {
+	r, err := Music("晴天", "https://example.com/a.mp3", "thumb-1").Description("周杰伦").Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := header + "<MsgType>music</MsgType><Music><Title>晴天</Title><Description>周杰伦</Description>" +
+		"<MusicUrl>https://example.com/a.mp3</MusicUrl><HQMusicUrl>https://example.com/a.mp3</HQMusicUrl>" +
+		"<ThumbMediaId>thumb-1</ThumbMediaId></Music></xml>"
+	if got := render(t, r); got != want {
+		t.Errorf("got  %s\nwant %s", got, want)
+	}
+
+	if r, err = Music("晴天", "https://example.com/a.mp3", "thumb-1").HQMusicUrl("https://example.com/hq.flac").Build(); err != nil {
+		t.Fatal(err)
+	}
+	want = header + "<MsgType>music</MsgType><Music><Title>晴天</Title><Description></Description>" +
+		"<MusicUrl>https://example.com/a.mp3</MusicUrl><HQMusicUrl>https://example.com/hq.flac</HQMusicUrl>" +
+		"<ThumbMediaId>thumb-1</ThumbMediaId></Music></xml>"
+	if got := render(t, r); got != want {
+		t.Errorf("got  %s\nwant %s", got, want)
+	}
+
+	if _, err = Music("晴天", "https://example.com/a.mp3", "").Build(); err == nil {
+		t.Error("expected error without thumb media id")
+	}
+}
+
+func TestRenderUnsupported(t *testing.T) {
+	if _, err := Render(&message.Reply{MsgType: message.MsgTypeText, MsgData: "text"}, "u", "g", 0); err == nil {
+		t.Error("expected error for unsupported reply data")
+	}
+}
+
+func TestMarshalUnmarshal(t *testing.T) {
+	news, err := News().Article("标题", "描述", "https://example.com/a.png", "https://example.com").Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range []*message.Reply{Text("你好<b>"), Image("media1"), news} {
+		s, err := Marshal(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := Unmarshal(s)
+		if err != nil {
+			t.Fatalf("Unmarshal(%s): %v", s, err)
+		}
+		want, _ := Render(r, "u1", "gh", 1)
+		xml, err := Render(got, "u1", "gh", 1)
+		if err != nil || string(xml) != string(want) {
+			t.Errorf("round trip %s = %s, %v; want %s", r.MsgType, xml, err, want)
+		}
+	}
+	if _, err = Unmarshal(`{"MsgType":"transfer_customer_service"}`); err == nil {
+		t.Error("unsupported type should fail")
+	}
+}
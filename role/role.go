@@ -0,0 +1,91 @@
+package role
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/pwh-pwh/aiwechat-vercel/config"
+	"github.com/pwh-pwh/aiwechat-vercel/db"
+)
+
+const (
+	ADMIN   = "admin"
+	USER    = "user"
+	BLOCKED = "blocked"
+)
+
+var levels = map[string]int{
+	BLOCKED: 0,
+	USER:    1,
+	ADMIN:   2,
+}
+
+func IsValid(role string) bool {
+	_, ok := levels[role]
+	return ok
+}
+
+// Get 返回用户角色，ADMIN_OPENIDS中的用户始终是管理员，没有保存角色的用户是普通用户
+func Get(userId string) string {
+	if config.IsAdminOpenId(userId) {
+		return ADMIN
+	}
+	role, err := db.GetRole(userId)
+	if err != nil {
+		fmt.Println("get role error:", err)
+	}
+	if !IsValid(role) {
+		return USER
+	}
+	return role
+}
+
+// Set 修改用户角色，设置为普通用户时删除保存的角色
+func Set(userId, role string) error {
+	if !IsValid(role) {
+		return fmt.Errorf("不支持的角色: %s", role)
+	}
+	if userId == "" {
+		return errors.New("openid不能为空")
+	}
+	if config.IsAdminOpenId(userId) && role != ADMIN {
+		return errors.New("ADMIN_OPENIDS中的管理员不能修改角色")
+	}
+	if role == USER {
+		return db.DeleteRole(userId)
+	}
+	return db.SetRole(userId, role)
+}
+
+// Allow 用户角色是否满足要求，required为空时要求普通用户
+func Allow(userId, required string) bool {
+	if required == "" {
+		required = USER
+	}
+	return levels[Get(userId)] >= levels[required]
+}
+
+func IsAdmin(userId string) bool {
+	return Get(userId) == ADMIN
+}
+
+func IsBlocked(userId string) bool {
+	return Get(userId) == BLOCKED
+}
+
+// List 返回所有非普通用户的角色，包括ADMIN_OPENIDS中的管理员
+func List() (map[string]string, error) {
+	roles, err := db.GetRoles()
+	if err != nil {
+		return nil, err
+	}
+	for userId, role := range roles {
+		if !IsValid(role) || role == USER {
+			delete(roles, userId)
+		}
+	}
+	for _, userId := range config.GetAdminOpenIds() {
+		roles[userId] = ADMIN
+	}
+	return roles, nil
+}
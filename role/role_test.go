@@ -0,0 +1,51 @@
+package role
+
+import (
+	"testing"
+)
+
+func TestRole(t *testing.T) {
+	t.Setenv("ADMIN_OPENIDS", "boss, ops")
+	t.Cleanup(func() {
+		Set("u1", USER)
+		Set("u2", USER)
+	})
+
+	if Get("boss") != ADMIN || Get("ops") != ADMIN || Get("u1") != USER {
+		t.Errorf("bootstrap roles: boss=%s ops=%s u1=%s", Get("boss"), Get("ops"), Get("u1"))
+	}
+	if err := Set("boss", BLOCKED); err == nil {
+		t.Error("bootstrap admin should not be blocked")
+	}
+	if err := Set("u1", "root"); err == nil {
+		t.Error("unknown role should fail")
+	}
+
+	if err := Set("u1", BLOCKED); err != nil {
+		t.Fatal(err)
+	}
+	if err := Set("u2", ADMIN); err != nil {
+		t.Fatal(err)
+	}
+	if !IsBlocked("u1") || Allow("u1", "") || !IsAdmin("u2") || !Allow("u2", ADMIN) || Allow("u3", ADMIN) || !Allow("u3", USER) {
+		t.Errorf("roles: u1=%s u2=%s u3=%s", Get("u1"), Get("u2"), Get("u3"))
+	}
+
+	roles, err := List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"boss": ADMIN, "ops": ADMIN, "u1": BLOCKED, "u2": ADMIN}
+	if len(roles) != len(want) {
+		t.Errorf("List = %v", roles)
+	}
+	for userId, role := range want {
+		if roles[userId] != role {
+			t.Errorf("List()[%s] = %s, want %s", userId, roles[userId], role)
+		}
+	}
+
+	if err = Set("u1", USER); err != nil || Get("u1") != USER {
+		t.Errorf("unban: %v, %s", err, Get("u1"))
+	}
+}
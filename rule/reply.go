@@ -0,0 +1,42 @@
+package rule
+
+import (
+	"github.com/pwh-pwh/aiwechat-vercel/reply"
+	"github.com/silenceper/wechat/v2/officialaccount/message"
+)
+
+// Command command规则要执行的指令，指令可能调用模型，交给调用方按普通消息处理，与普通消息一样限流和审核
+func (r *Rule) Command() (string, bool) {
+	return r.Content, r.Reply == REPLY_COMMAND
+}
+
+// ToReply 生成规则对应的被动回复消息，command规则使用Command
+func (r *Rule) ToReply() *message.Reply {
+	switch r.Reply {
+	case REPLY_IMAGE:
+		return reply.Image(r.MediaId)
+	case REPLY_VOICE:
+		return reply.Voice(r.MediaId)
+	case REPLY_MUSIC:
+		rpn, err := reply.Music(r.Music.Title, r.Music.MusicUrl, r.MediaId).
+			Description(r.Music.Description).
+			HQMusicUrl(r.Music.HQMusicUrl).
+			Build()
+		if err != nil {
+			return reply.Text(err.Error())
+		}
+		return rpn
+	case REPLY_NEWS:
+		news := reply.News()
+		for _, a := range r.Articles {
+			news.Article(a.Title, a.Description, a.PicUrl, a.Url)
+		}
+		rpn, err := news.Build()
+		if err != nil {
+			return reply.Text(err.Error())
+		}
+		return rpn
+	default:
+		return reply.Text(r.Content)
+	}
+}
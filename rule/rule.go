@@ -0,0 +1,203 @@
+package rule
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bytedance/sonic"
+	"github.com/pwh-pwh/aiwechat-vercel/db"
+)
+
+const (
+	MATCH_EXACT  = "exact"
+	MATCH_PREFIX = "prefix"
+	MATCH_REGEX  = "regex"
+
+	REPLY_TEXT    = "text"
+	REPLY_IMAGE   = "image"
+	REPLY_NEWS    = "news"
+	REPLY_VOICE   = "voice"
+	REPLY_MUSIC   = "music"
+	REPLY_COMMAND = "command"
+
+	// 规则在内存中缓存的时间，修改后最多这么久在所有实例生效
+	cacheDuration = time.Minute
+)
+
+// Rule 自动回复规则，按Priority从大到小匹配，第一条匹配的规则生效
+// Reply为text时回复Content，image、voice时回复MediaId对应的素材，news时回复Articles，
+// music时回复Music(缩略图为MediaId对应的素材)，command时把Content作为指令执行
+type Rule struct {
+	Id       string
+	Match    string
+	Pattern  string
+	Priority int
+	Reply    string
+	Content  string    `json:",omitempty"`
+	MediaId  string    `json:",omitempty"`
+	Articles []Article `json:",omitempty"`
+	Music    *Music    `json:",omitempty"`
+	Disabled bool      `json:",omitempty"`
+
+	regex *regexp.Regexp
+}
+
+// Article 图文消息中的一篇文章
+type Article struct {
+	Title       string
+	Description string `json:",omitempty"`
+	PicUrl      string `json:",omitempty"`
+	Url         string `json:",omitempty"`
+}
+
+// Music 音乐回复，缩略图使用规则的MediaId
+type Music struct {
+	Title       string
+	Description string `json:",omitempty"`
+	MusicUrl    string
+	HQMusicUrl  string `json:",omitempty"`
+}
+
+var (
+	cacheLock  sync.Mutex
+	cacheRules []*Rule
+	cacheTime  time.Time
+)
+
+// Validate 检查规则是否完整，并编译正则
+func (r *Rule) Validate() error {
+	if r.Id == "" {
+		return errors.New("规则id不能为空")
+	}
+	if r.Pattern == "" {
+		return errors.New("匹配内容不能为空")
+	}
+	switch r.Match {
+	case MATCH_EXACT, MATCH_PREFIX:
+	case MATCH_REGEX:
+		regex, err := regexp.Compile(r.Pattern)
+		if err != nil {
+			return fmt.Errorf("正则表达式错误: %w", err)
+		}
+		r.regex = regex
+	default:
+		return fmt.Errorf("不支持的匹配方式: %s", r.Match)
+	}
+	switch r.Reply {
+	case REPLY_TEXT, REPLY_COMMAND:
+		if r.Content == "" {
+			return errors.New("回复内容不能为空")
+		}
+	case REPLY_IMAGE, REPLY_VOICE:
+		if r.MediaId == "" {
+			return errors.New("图片、语音回复需要填写MediaId")
+		}
+	case REPLY_MUSIC:
+		if r.Music == nil || r.Music.MusicUrl == "" || r.MediaId == "" {
+			return errors.New("音乐回复需要填写Music.MusicUrl和缩略图MediaId")
+		}
+	case REPLY_NEWS:
+		if len(r.Articles) == 0 {
+			return errors.New("图文回复需要至少一篇文章")
+		}
+		// 被动回复的图文消息只能包含一篇文章
+		if len(r.Articles) > 1 {
+			return errors.New("图文回复只支持一篇文章")
+		}
+	default:
+		return fmt.Errorf("不支持的回复类型: %s", r.Reply)
+	}
+	return nil
+}
+
+// IsMatch 精确匹配和前缀匹配忽略首尾空格和英文大小写
+func (r *Rule) IsMatch(msg string) bool {
+	switch r.Match {
+	case MATCH_EXACT:
+		return strings.EqualFold(strings.TrimSpace(msg), r.Pattern)
+	case MATCH_PREFIX:
+		msg = strings.TrimSpace(msg)
+		return len(msg) >= len(r.Pattern) && strings.EqualFold(msg[:len(r.Pattern)], r.Pattern)
+	case MATCH_REGEX:
+		return r.regex != nil && r.regex.MatchString(msg)
+	}
+	return false
+}
+
+// Save 新增或更新规则
+func Save(r *Rule) error {
+	if err := r.Validate(); err != nil {
+		return err
+	}
+	data, err := sonic.MarshalString(r)
+	if err != nil {
+		return err
+	}
+	defer resetCache()
+	return db.SetRule(r.Id, data)
+}
+
+func Delete(id string) error {
+	defer resetCache()
+	return db.DeleteRule(id)
+}
+
+// List 返回按优先级排序的全部规则
+func List() ([]*Rule, error) {
+	cacheLock.Lock()
+	defer cacheLock.Unlock()
+	if !cacheTime.IsZero() && time.Since(cacheTime) < cacheDuration {
+		return cacheRules, nil
+	}
+	values, err := db.GetRules()
+	if err != nil {
+		return nil, err
+	}
+	rules := make([]*Rule, 0, len(values))
+	for id, value := range values {
+		var r Rule
+		if err := sonic.UnmarshalString(value, &r); err != nil {
+			fmt.Printf("rule %s unmarshal error: %v\n", id, err)
+			continue
+		}
+		if err := r.Validate(); err != nil {
+			fmt.Printf("rule %s invalid: %v\n", id, err)
+			continue
+		}
+		rules = append(rules, &r)
+	}
+	sort.SliceStable(rules, func(i, j int) bool {
+		if rules[i].Priority != rules[j].Priority {
+			return rules[i].Priority > rules[j].Priority
+		}
+		return rules[i].Id < rules[j].Id
+	})
+	cacheRules, cacheTime = rules, time.Now()
+	return rules, nil
+}
+
+// Match 返回第一条匹配的规则，没有匹配时返回nil
+func Match(msg string) *Rule {
+	rules, err := List()
+	if err != nil {
+		fmt.Println("load rules error:", err)
+		return nil
+	}
+	for _, r := range rules {
+		if !r.Disabled && r.IsMatch(msg) {
+			return r
+		}
+	}
+	return nil
+}
+
+func resetCache() {
+	cacheLock.Lock()
+	defer cacheLock.Unlock()
+	cacheRules, cacheTime = nil, time.Time{}
+}
@@ -0,0 +1,90 @@
+package rule
+
+import (
+	"testing"
+
+	"github.com/silenceper/wechat/v2/officialaccount/message"
+)
+
+func saveRules(t *testing.T, rules ...*Rule) {
+	t.Helper()
+	for _, r := range rules {
+		if err := Save(r); err != nil {
+			t.Fatalf("save %s: %v", r.Id, err)
+		}
+	}
+	t.Cleanup(func() {
+		for _, r := range rules {
+			Delete(r.Id)
+		}
+	})
+}
+
+func TestValidate(t *testing.T) {
+	cases := []Rule{
+		{Id: "", Match: MATCH_EXACT, Pattern: "a", Reply: REPLY_TEXT, Content: "b"},
+		{Id: "1", Match: "fuzzy", Pattern: "a", Reply: REPLY_TEXT, Content: "b"},
+		{Id: "1", Match: MATCH_REGEX, Pattern: "(", Reply: REPLY_TEXT, Content: "b"},
+		{Id: "1", Match: MATCH_EXACT, Pattern: "a", Reply: REPLY_IMAGE},
+		{Id: "1", Match: MATCH_EXACT, Pattern: "a", Reply: REPLY_NEWS, Articles: []Article{{Title: "1"}, {Title: "2"}}},
+		{Id: "1", Match: MATCH_EXACT, Pattern: "a", Reply: "voice", Content: "b"},
+	}
+	for _, c := range cases {
+		if err := c.Validate(); err == nil {
+			t.Errorf("expected error for %+v", c)
+		}
+	}
+}
+
+func TestMatch(t *testing.T) {
+	saveRules(t,
+		&Rule{Id: "price", Match: MATCH_PREFIX, Pattern: "价格", Priority: 1, Reply: REPLY_TEXT, Content: "价格表"},
+		&Rule{Id: "vip", Match: MATCH_EXACT, Pattern: "价格 VIP", Priority: 10, Reply: REPLY_TEXT, Content: "VIP价格"},
+		&Rule{Id: "phone", Match: MATCH_REGEX, Pattern: `(电话|手机)号?`, Reply: REPLY_TEXT, Content: "400-000-0000"},
+		&Rule{Id: "off", Match: MATCH_EXACT, Pattern: "关闭的规则", Reply: REPLY_TEXT, Content: "x", Disabled: true},
+	)
+	cases := map[string]string{
+		" 价格 vip ": "vip",
+		"价格多少":     "price",
+		"客服电话是多少":  "phone",
+		"你好":       "",
+		"关闭的规则":    "",
+		"问一下价格多少钱": "",
+	}
+	for msg, want := range cases {
+		got := ""
+		if r := Match(msg); r != nil {
+			got = r.Id
+		}
+		if got != want {
+			t.Errorf("Match(%q) = %q, want %q", msg, got, want)
+		}
+	}
+}
+
+func TestToReply(t *testing.T) {
+	r := &Rule{Reply: REPLY_IMAGE, MediaId: "media-1"}
+	if reply := r.ToReply(); reply.MsgType != message.MsgTypeImage || reply.MsgData.(*message.Image).Image.MediaID != "media-1" {
+		t.Errorf("image reply = %+v", reply)
+	}
+
+	r = &Rule{Reply: REPLY_NEWS, Articles: []Article{{Title: "新品", Description: "介绍", PicUrl: "https://a/b.png", Url: "https://a"}}}
+	reply := r.ToReply()
+	news, ok := reply.MsgData.(*message.News)
+	if reply.MsgType != message.MsgTypeNews || !ok || news.ArticleCount != 1 || news.Articles[0].Title != "新品" {
+		t.Errorf("news reply = %+v", reply)
+	}
+
+	r = &Rule{Reply: REPLY_MUSIC, MediaId: "thumb-1", Music: &Music{Title: "晴天", MusicUrl: "https://a/b.mp3"}}
+	if reply := r.ToReply(); reply.MsgType != message.MsgTypeMusic || reply.MsgData.(*message.Music).Music.HQMusicURL != "https://a/b.mp3" {
+		t.Errorf("music reply = %+v", reply)
+	}
+
+	r = &Rule{Reply: REPLY_COMMAND, Content: "/help"}
+	if cmd, ok := r.Command(); !ok || cmd != "/help" {
+		t.Errorf("command = %q, %v", cmd, ok)
+	}
+	if _, ok := (&Rule{Reply: REPLY_TEXT, Content: "/help"}).Command(); ok {
+		t.Error("text rule is not a command")
+	}
+}
@@ -0,0 +1,42 @@
+package timeparse
+
+import (
+	"strconv"
+	"strings"
+)
+
+var cnDigits = map[rune]int{'零': 0, '〇': 0, '一': 1, '二': 2, '两': 2, '三': 3, '四': 4, '五': 5, '六': 6, '七': 7, '八': 8, '九': 9}
+
+// parseNumber 解析阿拉伯数字或一百以内的中文数字，如 12、十二、二十、两
+func parseNumber(s string) (int, bool) {
+	if s == "" {
+		return 0, false
+	}
+	if n, err := strconv.Atoi(s); err == nil {
+		return n, true
+	}
+	tens, ones, rest := 0, 0, s
+	if i := strings.IndexRune(rest, '十'); i >= 0 {
+		tens = 1
+		if i > 0 {
+			d, ok := cnDigits[[]rune(rest[:i])[0]]
+			if !ok || len([]rune(rest[:i])) != 1 {
+				return 0, false
+			}
+			tens = d
+		}
+		rest = rest[i+len("十"):]
+	}
+	runes := []rune(rest)
+	if len(runes) > 1 {
+		return 0, false
+	}
+	if len(runes) == 1 {
+		d, ok := cnDigits[runes[0]]
+		if !ok {
+			return 0, false
+		}
+		ones = d
+	}
+	return tens*10 + ones, true
+}
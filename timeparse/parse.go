@@ -0,0 +1,225 @@
+package timeparse
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Result 解析结果，Expr为识别出的时间表达式，Rest为去掉时间表达式后的内容
+type Result struct {
+	Time time.Time
+	Expr string
+	Rest string
+}
+
+const (
+	num = `(?:\d{1,2}|[零〇一二两三四五六七八九十]{1,3})`
+
+	relativeExpr = `(?P<rn>半|` + num + `)个?(?P<ru>分钟|分|小时|钟头|天|周|星期)(?:后|之后|以后)`
+	dateExpr     = `(?:(?P<y>\d{4})[-/年.](?P<m>\d{1,2})[-/月.](?P<d>\d{1,2})[日号]?` +
+		`|(?P<m2>` + num + `)月(?P<d2>` + num + `)[日号]?` +
+		`|(?P<day>大后天|后天|明天|明晚|明早|今天|今晚|今早)` +
+		`|(?P<wp>下下|下|这|本)?(?:周|星期|礼拜)(?P<wd>[一二三四五六日天1-7])` +
+		`|(?P<d3>` + num + `)[日号])`
+	clockExpr = `(?:(?P<p>早上|上午|中午|下午|晚上|凌晨|傍晚|早|晚)?` +
+		`(?:(?P<h>\d{1,2})[:：](?P<mi>\d{2})|(?P<h2>` + num + `)[点时](?:(?P<mi2>半|一刻|三刻|` + num + `)分?)?))`
+	anyExpr = `(?:` + relativeExpr + `|` + dateExpr + `\s*` + clockExpr + `?|` + clockExpr + `)(?:之前|以前|前)?`
+)
+
+var (
+	startRegex = regexp.MustCompile(`^\s*` + anyExpr)
+	endRegex   = regexp.MustCompile(anyExpr + `\s*$`)
+)
+
+// 只有日期时的默认时间
+const defaultHour = 9
+
+// Parse 识别文本开头或结尾的中文时间，如 明天下午3点、周五、10月1日 9:30、2小时后、晚上8点半
+// 只识别开头或结尾的时间，避免把内容中的数字当成时间
+func Parse(text string, now time.Time) (Result, bool) {
+	for _, re := range []*regexp.Regexp{startRegex, endRegex} {
+		loc := re.FindStringSubmatchIndex(text)
+		if loc == nil || loc[0] == loc[1] {
+			continue
+		}
+		groups := map[string]string{}
+		for i, name := range re.SubexpNames() {
+			if name != "" && loc[2*i] >= 0 {
+				groups[name] = text[loc[2*i]:loc[2*i+1]]
+			}
+		}
+		t, ok := resolve(groups, now)
+		if !ok {
+			continue
+		}
+		return Result{
+			Time: t,
+			Expr: strings.TrimSpace(text[loc[0]:loc[1]]),
+			Rest: strings.TrimSpace(text[:loc[0]] + " " + text[loc[1]:]),
+		}, true
+	}
+	return Result{}, false
+}
+
+func resolve(g map[string]string, now time.Time) (time.Time, bool) {
+	if g["ru"] != "" {
+		return resolveRelative(g, now)
+	}
+	date, hasDate, ok := resolveDate(g, now)
+	if !ok {
+		return time.Time{}, false
+	}
+	hour, minute, hasClock, ok := resolveClock(g)
+	if !ok {
+		return time.Time{}, false
+	}
+	if !hasDate && !hasClock {
+		return time.Time{}, false
+	}
+	if !hasClock {
+		hour, minute = defaultHour, 0
+		switch g["day"] {
+		case "今晚", "明晚":
+			hour = 20
+		case "今早", "明早":
+			hour = 8
+		}
+	}
+	if !hasDate {
+		date = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	}
+	t := date.Add(time.Duration(hour)*time.Hour + time.Duration(minute)*time.Minute)
+	// 只有时间且今天已经过了时，认为是明天
+	if !hasDate && !t.After(now) {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t, true
+}
+
+func resolveRelative(g map[string]string, now time.Time) (time.Time, bool) {
+	var d time.Duration
+	unit := map[string]time.Duration{
+		"分钟": time.Minute, "分": time.Minute, "小时": time.Hour, "钟头": time.Hour,
+		"天": 24 * time.Hour, "周": 7 * 24 * time.Hour, "星期": 7 * 24 * time.Hour,
+	}[g["ru"]]
+	if g["rn"] == "半" {
+		d = unit / 2
+	} else {
+		n, ok := parseNumber(g["rn"])
+		if !ok || n <= 0 {
+			return time.Time{}, false
+		}
+		d = time.Duration(n) * unit
+	}
+	return now.Add(d).Truncate(time.Minute), true
+}
+
+func resolveDate(g map[string]string, now time.Time) (time.Time, bool, bool) {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	switch {
+	case g["y"] != "":
+		y, _ := strconv.Atoi(g["y"])
+		m, _ := strconv.Atoi(g["m"])
+		d, _ := strconv.Atoi(g["d"])
+		return makeDate(y, m, d, now.Location())
+	case g["m2"] != "":
+		m, ok1 := parseNumber(g["m2"])
+		d, ok2 := parseNumber(g["d2"])
+		if !ok1 || !ok2 {
+			return time.Time{}, false, false
+		}
+		date, _, ok := makeDate(now.Year(), m, d, now.Location())
+		if ok && date.Before(today) {
+			date = date.AddDate(1, 0, 0)
+		}
+		return date, true, ok
+	case g["day"] != "":
+		offset := map[string]int{"今天": 0, "今晚": 0, "今早": 0, "明天": 1, "明晚": 1, "明早": 1, "后天": 2, "大后天": 3}[g["day"]]
+		return today.AddDate(0, 0, offset), true, true
+	case g["wd"] != "":
+		wd := strings.Index("一二三四五六日", g["wd"]) / len("一")
+		if g["wd"] == "天" {
+			wd = 6
+		} else if n, err := strconv.Atoi(g["wd"]); err == nil {
+			wd = n - 1
+		}
+		// 周一为每周第一天
+		monday := today.AddDate(0, 0, -(int(today.Weekday())+6)%7)
+		date := monday.AddDate(0, 0, wd)
+		switch g["wp"] {
+		case "下":
+			date = date.AddDate(0, 0, 7)
+		case "下下":
+			date = date.AddDate(0, 0, 14)
+		case "":
+			if date.Before(today) {
+				date = date.AddDate(0, 0, 7)
+			}
+		}
+		return date, true, true
+	case g["d3"] != "":
+		d, ok := parseNumber(g["d3"])
+		if !ok {
+			return time.Time{}, false, false
+		}
+		date, _, ok := makeDate(now.Year(), int(now.Month()), d, now.Location())
+		if ok && date.Before(today) {
+			date = date.AddDate(0, 1, 0)
+		}
+		return date, true, ok
+	}
+	return time.Time{}, false, true
+}
+
+func makeDate(y, m, d int, loc *time.Location) (time.Time, bool, bool) {
+	date := time.Date(y, time.Month(m), d, 0, 0, 0, 0, loc)
+	if m < 1 || m > 12 || date.Day() != d {
+		return time.Time{}, false, false
+	}
+	return date, true, true
+}
+
+func resolveClock(g map[string]string) (int, int, bool, bool) {
+	var hour, minute int
+	switch {
+	case g["h"] != "":
+		hour, _ = strconv.Atoi(g["h"])
+		minute, _ = strconv.Atoi(g["mi"])
+	case g["h2"] != "":
+		var ok bool
+		if hour, ok = parseNumber(g["h2"]); !ok {
+			return 0, 0, false, false
+		}
+		switch g["mi2"] {
+		case "":
+		case "半":
+			minute = 30
+		case "一刻":
+			minute = 15
+		case "三刻":
+			minute = 45
+		default:
+			if minute, ok = parseNumber(g["mi2"]); !ok {
+				return 0, 0, false, false
+			}
+		}
+	default:
+		return 0, 0, false, true
+	}
+	switch g["p"] {
+	case "下午", "晚上", "傍晚", "晚":
+		if hour < 12 {
+			hour += 12
+		}
+	case "中午":
+		if hour < 3 {
+			hour += 12
+		}
+	}
+	if hour > 23 || minute > 59 {
+		return 0, 0, false, false
+	}
+	return hour, minute, true, true
+}
@@ -0,0 +1,111 @@
+package timeparse
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	loc := time.FixedZone("CST", 8*3600)
+	// 2024-05-15 是周三
+	now := time.Date(2024, 5, 15, 10, 20, 30, 0, loc)
+	at := func(m time.Month, d, h, mi int) time.Time { return time.Date(2024, m, d, h, mi, 0, 0, loc) }
+	tests := []struct {
+		text string
+		want time.Time
+		rest string
+	}{
+		{"明天下午3点 交报告", at(5, 16, 15, 0), "交报告"},
+		{"明天交报告", at(5, 16, 9, 0), "交报告"},
+		{"交报告 明天下午3点", at(5, 16, 15, 0), "交报告"},
+		{"今晚 看电影", at(5, 15, 20, 0), "看电影"},
+		{"晚上8点半 跑步", at(5, 15, 20, 30), "跑步"},
+		{"9:30 开会", at(5, 16, 9, 30), "开会"},
+		{"十一点一刻 开会", at(5, 15, 11, 15), "开会"},
+		{"中午12点 吃饭", at(5, 15, 12, 0), "吃饭"},
+		{"周五前提交周报", at(5, 17, 9, 0), "提交周报"},
+		{"周一 例会", at(5, 20, 9, 0), "例会"},
+		{"下周三上午10点 评审", at(5, 22, 10, 0), "评审"},
+		{"星期日 休息", at(5, 19, 9, 0), "休息"},
+		{"6月1日 儿童节", at(6, 1, 9, 0), "儿童节"},
+		{"5月1号 劳动节", time.Date(2025, 5, 1, 9, 0, 0, 0, loc), "劳动节"},
+		{"2024-12-31 23:59 跨年", at(12, 31, 23, 59), "跨年"},
+		{"20号 还信用卡", at(5, 20, 9, 0), "还信用卡"},
+		{"1号 交房租", at(6, 1, 9, 0), "交房租"},
+		{"半小时后 关火", at(5, 15, 10, 50), "关火"},
+		{"2小时后 取快递", at(5, 15, 12, 20), "取快递"},
+		{"三天后 复查", at(5, 18, 10, 20), "复查"},
+	}
+	for _, tt := range tests {
+		r, ok := Parse(tt.text, now)
+		if !ok {
+			t.Errorf("Parse(%q) failed", tt.text)
+			continue
+		}
+		if !r.Time.Equal(tt.want) || r.Rest != tt.rest {
+			t.Errorf("Parse(%q) = %v %q, want %v %q", tt.text, r.Time, r.Rest, tt.want, tt.rest)
+		}
+	}
+
+	for _, text := range []string{"写3点建议给老板", "买牛奶", "2月30日 不存在", "25点 开会"} {
+		if r, ok := Parse(text, now); ok {
+			t.Errorf("Parse(%q) = %v %q, want no time", text, r.Time, r.Expr)
+		}
+	}
+}
+
+func TestParseNumber(t *testing.T) {
+	for s, want := range map[string]int{"8": 8, "十": 10, "十二": 12, "二十": 20, "二十三": 23, "两": 2, "零": 0} {
+		if n, ok := parseNumber(s); !ok || n != want {
+			t.Errorf("parseNumber(%q) = %d, %v", s, n, ok)
+		}
+	}
+	for _, s := range []string{"", "百", "十十", "一二"} {
+		if _, ok := parseNumber(s); ok {
+			t.Errorf("parseNumber(%q) should fail", s)
+		}
+	}
+}
+
+func TestParseRule(t *testing.T) {
+	loc := time.FixedZone("CST", 8*3600)
+	// 2024-05-15 是周三
+	now := time.Date(2024, 5, 15, 10, 20, 30, 0, loc)
+	at := func(m time.Month, d, h, mi int) time.Time { return time.Date(2024, m, d, h, mi, 0, 0, loc) }
+	tests := []struct {
+		text string
+		rule string
+		next time.Time
+		rest string
+	}{
+		{"每天8点 吃药", "每天 08:00", at(5, 16, 8, 0), "吃药"},
+		{"每天晚上10点半 睡觉", "每天 22:30", at(5, 15, 22, 30), "睡觉"},
+		{"工作日9:30 打卡", "每个工作日 09:30", at(5, 16, 9, 30), "打卡"},
+		{"每周一三五晚上7点 健身", "每周一、三、五 19:00", at(5, 15, 19, 0), "健身"},
+		{"每周日 大扫除", "每周日 09:00", at(5, 19, 9, 0), "大扫除"},
+		{"每月1号 交房租", "每月1号 09:00", at(6, 1, 9, 0), "交房租"},
+		{"每个月十五号下午3点 还信用卡", "每月15号 15:00", at(5, 15, 15, 0), "还信用卡"},
+	}
+	for _, tt := range tests {
+		rule, rest, ok := ParseRule(tt.text)
+		if !ok {
+			t.Errorf("ParseRule(%q) failed", tt.text)
+			continue
+		}
+		if rule.String() != tt.rule || rest != tt.rest || !rule.Next(now).Equal(tt.next) {
+			t.Errorf("ParseRule(%q) = %s %v %q, want %s %v %q", tt.text, rule, rule.Next(now), rest, tt.rule, tt.next, tt.rest)
+		}
+	}
+	if _, _, ok := ParseRule("明天9点 交房租"); ok {
+		t.Error("one-off time should not be a rule")
+	}
+
+	// 工作日跳过周末，每月31号在小月为最后一天
+	friday := at(5, 17, 10, 0)
+	if next := (Rule{Kind: RULE_WORKDAY, Hour: 9}).Next(friday); !next.Equal(at(5, 20, 9, 0)) {
+		t.Errorf("workday next = %v", next)
+	}
+	if next := (Rule{Kind: RULE_MONTHLY, Day: 31, Hour: 9}).Next(at(5, 31, 10, 0)); !next.Equal(at(6, 30, 9, 0)) {
+		t.Errorf("monthly next = %v", next)
+	}
+}
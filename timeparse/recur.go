@@ -0,0 +1,142 @@
+package timeparse
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	RULE_DAILY   = "daily"
+	RULE_WORKDAY = "workday"
+	RULE_WEEKLY  = "weekly"
+	RULE_MONTHLY = "monthly"
+)
+
+// Rule 重复规则，Weekdays用于每周，Day用于每月，Day大于当月天数时为当月最后一天
+type Rule struct {
+	Kind     string
+	Weekdays []time.Weekday `json:",omitempty"`
+	Day      int            `json:",omitempty"`
+	Hour     int
+	Minute   int
+}
+
+const weekdayChars = `[一二三四五六日天1-7]`
+
+var ruleRegex = regexp.MustCompile(`^\s*(?:(?P<daily>每天|每日|天天)` +
+	`|(?P<workday>每个?工作日|工作日)` +
+	`|每(?:个)?(?:周|星期|礼拜)(?P<wds>` + weekdayChars + `(?:[、,，和]?` + weekdayChars + `)*)` +
+	`|每个?月(?P<md>` + num + `)[日号])\s*` + clockExpr + `?`)
+
+// ParseRule 识别文本开头的重复规则，如 每天8点、工作日9:30、每周一三五晚上7点、每月1号
+// 没有写时间时为9点，Rest为去掉规则后的内容
+func ParseRule(text string) (Rule, string, bool) {
+	loc := ruleRegex.FindStringSubmatchIndex(text)
+	if loc == nil {
+		return Rule{}, "", false
+	}
+	g := map[string]string{}
+	for i, name := range ruleRegex.SubexpNames() {
+		if name != "" && loc[2*i] >= 0 {
+			g[name] = text[loc[2*i]:loc[2*i+1]]
+		}
+	}
+	var rule Rule
+	switch {
+	case g["daily"] != "":
+		rule.Kind = RULE_DAILY
+	case g["workday"] != "":
+		rule.Kind = RULE_WORKDAY
+	case g["wds"] != "":
+		rule.Kind = RULE_WEEKLY
+		seen := map[time.Weekday]bool{}
+		for _, c := range g["wds"] {
+			i := strings.IndexRune("日一二三四五六", c)
+			switch {
+			case c == '天' || c == '7':
+				i = 0
+			case c >= '1' && c <= '6':
+				i = int(c - '0')
+			default:
+				i /= len("一")
+			}
+			if i >= 0 && !seen[time.Weekday(i)] {
+				seen[time.Weekday(i)] = true
+				rule.Weekdays = append(rule.Weekdays, time.Weekday(i))
+			}
+		}
+		// 周一为每周第一天
+		slices.SortFunc(rule.Weekdays, func(a, b time.Weekday) int { return int((a+6)%7) - int((b+6)%7) })
+	case g["md"] != "":
+		d, ok := parseNumber(g["md"])
+		if !ok || d < 1 || d > 31 {
+			return Rule{}, "", false
+		}
+		rule.Kind, rule.Day = RULE_MONTHLY, d
+	}
+	hour, minute, hasClock, ok := resolveClock(g)
+	if !ok {
+		return Rule{}, "", false
+	}
+	if !hasClock {
+		hour, minute = defaultHour, 0
+	}
+	rule.Hour, rule.Minute = hour, minute
+	return rule, strings.TrimSpace(text[loc[1]:]), true
+}
+
+func (r Rule) match(day time.Time) bool {
+	switch r.Kind {
+	case RULE_DAILY:
+		return true
+	case RULE_WORKDAY:
+		return day.Weekday() != time.Saturday && day.Weekday() != time.Sunday
+	case RULE_WEEKLY:
+		for _, wd := range r.Weekdays {
+			if day.Weekday() == wd {
+				return true
+			}
+		}
+	case RULE_MONTHLY:
+		last := time.Date(day.Year(), day.Month()+1, 0, 0, 0, 0, 0, day.Location()).Day()
+		return day.Day() == min(r.Day, last)
+	}
+	return false
+}
+
+// Next 返回after之后下一次触发的时间，规则无效时返回零值
+func (r Rule) Next(after time.Time) time.Time {
+	day := time.Date(after.Year(), after.Month(), after.Day(), 0, 0, 0, 0, after.Location())
+	// 最长的间隔是每月31号之间的两个月
+	for i := 0; i < 62; i++ {
+		d := day.AddDate(0, 0, i)
+		t := time.Date(d.Year(), d.Month(), d.Day(), r.Hour, r.Minute, 0, 0, d.Location())
+		if t.After(after) && r.match(d) {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// String 显示的规则，如 每周一、三 09:00
+func (r Rule) String() string {
+	clock := fmt.Sprintf("%02d:%02d", r.Hour, r.Minute)
+	switch r.Kind {
+	case RULE_DAILY:
+		return "每天 " + clock
+	case RULE_WORKDAY:
+		return "每个工作日 " + clock
+	case RULE_WEEKLY:
+		names := make([]string, 0, len(r.Weekdays))
+		for _, wd := range r.Weekdays {
+			names = append(names, string([]rune("日一二三四五六")[wd]))
+		}
+		return "每周" + strings.Join(names, "、") + " " + clock
+	case RULE_MONTHLY:
+		return fmt.Sprintf("每月%d号 %s", r.Day, clock)
+	}
+	return clock
+}
@@ -0,0 +1,296 @@
+package todo
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bytedance/sonic"
+	"github.com/pwh-pwh/aiwechat-vercel/config"
+	"github.com/pwh-pwh/aiwechat-vercel/db"
+	"github.com/pwh-pwh/aiwechat-vercel/timeparse"
+)
+
+const (
+	PRIORITY_NORMAL = 0
+	PRIORITY_HIGH   = 1
+	PRIORITY_URGENT = 2
+)
+
+var priorityNames = []string{"", "重要", "紧急"}
+
+// Item 待办事项，Due为0时没有截止时间，截止时会通过客服消息提醒
+type Item struct {
+	Id       int
+	Content  string
+	Priority int `json:",omitempty"`
+	Created  int64
+	Due      int64 `json:",omitempty"`
+	Done     bool  `json:",omitempty"`
+	DoneAt   int64 `json:",omitempty"`
+	Reminded bool  `json:",omitempty"`
+}
+
+// Now 当前时间，测试时可以替换
+var Now = config.Now
+
+// Parse 从文本中解析优先级和截止时间，! 表示重要，!! 表示紧急，时间写在开头或结尾，如 !! 明天下午3点 交报告
+func Parse(text string) (content string, priority int, due time.Time) {
+	content, priority, _, due = parse(text)
+	return
+}
+
+// parse 同Parse，marked表示文本中是否带有优先级标记
+func parse(text string) (content string, priority int, marked bool, due time.Time) {
+	var words []string
+	for _, word := range strings.Fields(text) {
+		switch strings.ReplaceAll(word, "！", "!") {
+		case "!":
+			priority, marked = PRIORITY_HIGH, true
+		case "!!":
+			priority, marked = PRIORITY_URGENT, true
+		default:
+			words = append(words, word)
+		}
+	}
+	content = strings.Join(words, " ")
+	if r, ok := timeparse.Parse(content, Now()); ok && r.Rest != "" {
+		content, due = r.Rest, r.Time
+	}
+	return
+}
+
+// List 返回用户的待办，未完成的在前，按优先级、截止时间和id排序
+func List(userId string) ([]*Item, error) {
+	if err := migrate(userId); err != nil {
+		return nil, err
+	}
+	values, err := db.GetTodos(userId)
+	if err != nil {
+		return nil, err
+	}
+	items := make([]*Item, 0, len(values))
+	for id, value := range values {
+		var item Item
+		if err := sonic.UnmarshalString(value, &item); err != nil {
+			fmt.Printf("todo %s of %s unmarshal error: %v\n", id, userId, err)
+			continue
+		}
+		items = append(items, &item)
+	}
+	sort.Slice(items, func(i, j int) bool {
+		a, b := items[i], items[j]
+		if a.Done != b.Done {
+			return !a.Done
+		}
+		if a.Priority != b.Priority {
+			return a.Priority > b.Priority
+		}
+		if a.Due != b.Due {
+			// 没有截止时间的排在后面
+			return b.Due == 0 || (a.Due != 0 && a.Due < b.Due)
+		}
+		return a.Id < b.Id
+	})
+	return items, nil
+}
+
+func Get(userId string, id int) (*Item, error) {
+	items, err := List(userId)
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range items {
+		if item.Id == id {
+			return item, nil
+		}
+	}
+	return nil, fmt.Errorf("待办#%d不存在", id)
+}
+
+// Add 添加待办，text中可以包含优先级和截止时间
+func Add(userId, text string) (*Item, error) {
+	content, priority, due := Parse(text)
+	if content == "" {
+		return nil, errors.New("待办内容不能为空")
+	}
+	items, err := List(userId)
+	if err != nil {
+		return nil, err
+	}
+	item := &Item{Id: nextId(items), Content: content, Priority: priority, Created: Now().Unix()}
+	if !due.IsZero() {
+		item.Due = due.Unix()
+	}
+	return item, Save(userId, item)
+}
+
+// Edit 修改待办内容，新内容中带有优先级或时间时一并修改
+func Edit(userId string, id int, text string) (*Item, error) {
+	item, err := Get(userId, id)
+	if err != nil {
+		return nil, err
+	}
+	content, priority, marked, due := parse(text)
+	if content == "" {
+		return nil, errors.New("待办内容不能为空")
+	}
+	item.Content = content
+	if marked {
+		item.Priority = priority
+	}
+	if !due.IsZero() {
+		item.Due, item.Reminded = due.Unix(), false
+	}
+	return item, Save(userId, item)
+}
+
+// Done 标记完成，完成的待办不再提醒
+func Done(userId string, id int) (*Item, error) {
+	item, err := Get(userId, id)
+	if err != nil {
+		return nil, err
+	}
+	item.Done, item.DoneAt = true, Now().Unix()
+	return item, Save(userId, item)
+}
+
+func Delete(userId string, id int) (*Item, error) {
+	item, err := Get(userId, id)
+	if err != nil {
+		return nil, err
+	}
+	db.DeleteTodoDue(dueMember(userId, id))
+	return item, db.DeleteTodo(userId, strconv.Itoa(id))
+}
+
+// Save 保存待办，并维护截止时间索引
+func Save(userId string, item *Item) error {
+	data, err := sonic.MarshalString(item)
+	if err != nil {
+		return err
+	}
+	if err = db.SetTodo(userId, strconv.Itoa(item.Id), data); err != nil {
+		return err
+	}
+	if item.Due == 0 || item.Done || item.Reminded {
+		return db.DeleteTodoDue(dueMember(userId, item.Id))
+	}
+	return db.SetTodoDue(dueMember(userId, item.Id), item.Due)
+}
+
+// Reminder 到期需要提醒的待办
+type Reminder struct {
+	UserId string
+	Item   *Item
+}
+
+// DueReminders 返回截止时间在before之前且还没有提醒的待办，返回的待办已从截止时间索引中取出，
+// 发送成功后调用MarkReminded，失败时调用Retry放回索引
+func DueReminders(before time.Time) ([]Reminder, error) {
+	members, err := db.GetDueTodos(before.Unix())
+	if err != nil {
+		return nil, err
+	}
+	var reminders []Reminder
+	for _, member := range members {
+		i := strings.LastIndex(member, ":")
+		id, err := strconv.Atoi(member[i+1:])
+		if i < 0 || err != nil {
+			db.DeleteTodoDue(member)
+			continue
+		}
+		userId := member[:i]
+		item, err := Get(userId, id)
+		if err != nil || item.Done || item.Reminded {
+			db.DeleteTodoDue(member)
+			continue
+		}
+		// 定时任务重叠或重试时只有取出成功的一方发送
+		if ok, err := db.ClaimTodoDue(member); err != nil || !ok {
+			continue
+		}
+		reminders = append(reminders, Reminder{UserId: userId, Item: item})
+	}
+	return reminders, nil
+}
+
+// MarkReminded 提醒发送后调用，不再重复提醒
+func MarkReminded(userId string, item *Item) error {
+	item.Reminded = true
+	return Save(userId, item)
+}
+
+// Retry 发送失败时调用，在at之后再次提醒
+func Retry(userId string, item *Item, at time.Time) error {
+	return db.SetTodoDue(dueMember(userId, item.Id), at.Unix())
+}
+
+func dueMember(userId string, id int) string {
+	return fmt.Sprintf("%s:%d", userId, id)
+}
+
+func nextId(items []*Item) int {
+	id := 0
+	for _, item := range items {
+		id = max(id, item.Id)
+	}
+	return id + 1
+}
+
+// migrate 把旧版本用|拼接的待办转换为结构化的待办
+func migrate(userId string) error {
+	legacy := db.PopLegacyTodoList(userId)
+	if legacy == "" {
+		return nil
+	}
+	values, err := db.GetTodos(userId)
+	if err != nil {
+		return err
+	}
+	id := len(values)
+	for _, content := range strings.Split(legacy, "|") {
+		if content = strings.TrimSpace(content); content == "" {
+			continue
+		}
+		id++
+		data, _ := sonic.MarshalString(&Item{Id: id, Content: content, Created: Now().Unix()})
+		if err = db.SetTodo(userId, strconv.Itoa(id), data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// String 列表中显示的一行，如 #3 [紧急] 交报告 (截止 05-16 15:00)
+func (item *Item) String() string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("#%d ", item.Id))
+	if item.Done {
+		sb.WriteString("✅ ")
+	}
+	if item.Priority > 0 && item.Priority < len(priorityNames) {
+		sb.WriteString(fmt.Sprintf("[%s] ", priorityNames[item.Priority]))
+	}
+	sb.WriteString(item.Content)
+	if item.Due != 0 {
+		due := time.Unix(item.Due, 0).In(Now().Location())
+		sb.WriteString(fmt.Sprintf(" (截止 %s", FormatTime(due)))
+		if !item.Done && due.Before(Now()) {
+			sb.WriteString("，已过期")
+		}
+		sb.WriteString(")")
+	}
+	return sb.String()
+}
+
+// FormatTime 今年的时间省略年份
+func FormatTime(t time.Time) string {
+	if t.Year() == Now().Year() {
+		return t.Format("01-02 15:04")
+	}
+	return t.Format("2006-01-02 15:04")
+}
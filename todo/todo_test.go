@@ -0,0 +1,157 @@
+package todo
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pwh-pwh/aiwechat-vercel/config"
+	"github.com/pwh-pwh/aiwechat-vercel/db"
+)
+
+func useClock(t *testing.T, now time.Time) *time.Time {
+	t.Helper()
+	clock := now
+	Now = func() time.Time { return clock }
+	t.Cleanup(func() { Now = config.Now })
+	return &clock
+}
+
+func cleanup(t *testing.T, userId string) {
+	t.Cleanup(func() {
+		items, _ := List(userId)
+		for _, item := range items {
+			Delete(userId, item.Id)
+		}
+	})
+}
+
+func TestParse(t *testing.T) {
+	useClock(t, time.Date(2024, 5, 15, 10, 0, 0, 0, time.Local))
+	content, priority, due := Parse("!! 明天下午3点 交报告")
+	if content != "交报告" || priority != PRIORITY_URGENT || !due.Equal(time.Date(2024, 5, 16, 15, 0, 0, 0, time.Local)) {
+		t.Errorf("Parse = %q %d %v", content, priority, due)
+	}
+	content, priority, due = Parse("买牛奶 ！")
+	if content != "买牛奶" || priority != PRIORITY_HIGH || !due.IsZero() {
+		t.Errorf("Parse = %q %d %v", content, priority, due)
+	}
+	// 只有时间没有内容时把时间当作内容
+	if content, _, due = Parse("明天"); content != "明天" || !due.IsZero() {
+		t.Errorf("Parse = %q %v", content, due)
+	}
+}
+
+func TestTodo(t *testing.T) {
+	clock := useClock(t, time.Date(2024, 5, 15, 10, 0, 0, 0, time.Local))
+	cleanup(t, "todo-u1")
+
+	for _, text := range []string{"买牛奶", "! 周五前提交周报", "!! 明天下午3点 交报告", "a|b 包含竖线"} {
+		if _, err := Add("todo-u1", text); err != nil {
+			t.Fatal(err)
+		}
+	}
+	items, err := List("todo-u1")
+	if err != nil || len(items) != 4 {
+		t.Fatalf("List = %v, %v", items, err)
+	}
+	var order []int
+	for _, item := range items {
+		order = append(order, item.Id)
+	}
+	if want := []int{3, 2, 1, 4}; !equal(order, want) {
+		t.Errorf("order = %v, want %v", order, want)
+	}
+	if s := items[0].String(); s != "#3 [紧急] 交报告 (截止 05-16 15:00)" {
+		t.Errorf("String = %q", s)
+	}
+
+	if _, err = Done("todo-u1", 1); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = Edit("todo-u1", 4, "下午5点 a|b 已修改"); err != nil {
+		t.Fatal(err)
+	}
+	// 删除一项不影响其它待办
+	if _, err = Delete("todo-u1", 2); err != nil {
+		t.Fatal(err)
+	}
+	items, _ = List("todo-u1")
+	if len(items) != 3 || items[1].Id != 4 || items[1].Content != "a|b 已修改" || !items[2].Done {
+		t.Errorf("after edit = %v", items)
+	}
+	if _, err = Done("todo-u1", 2); err == nil {
+		t.Error("deleted todo should not exist")
+	}
+	// 新增的编号不复用已删除的最大编号之前的编号
+	if item, _ := Add("todo-u1", "新的"); item.Id != 5 {
+		t.Errorf("next id = %d", item.Id)
+	}
+
+	*clock = time.Date(2024, 5, 16, 15, 0, 0, 0, time.Local)
+	reminders, err := DueReminders(Now())
+	if err != nil || len(reminders) != 2 {
+		t.Fatalf("DueReminders = %v, %v", reminders, err)
+	}
+	// 已被取出的待办不会被同时运行的定时任务再次返回
+	if again, _ := DueReminders(Now()); len(again) != 0 {
+		t.Errorf("claimed todos should not be returned again: %v", again)
+	}
+	// 发送失败放回索引后重新提醒
+	Retry(reminders[0].UserId, reminders[0].Item, Now().Add(time.Hour))
+	*clock = clock.Add(time.Hour)
+	if again, _ := DueReminders(Now()); len(again) != 1 || again[0].Item.Id != reminders[0].Item.Id {
+		t.Errorf("retried todo = %v", again)
+	}
+	for _, r := range reminders {
+		MarkReminded(r.UserId, r.Item)
+	}
+	if reminders, _ = DueReminders(Now()); len(reminders) != 0 {
+		t.Errorf("reminded todos should not be returned again: %v", reminders)
+	}
+}
+
+func TestEditPriority(t *testing.T) {
+	useClock(t, time.Date(2024, 5, 15, 10, 0, 0, 0, time.Local))
+	cleanup(t, "todo-u3")
+
+	item, err := Add("todo-u3", "!! 明天 交报告")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 新内容没有优先级和时间时保留原来的
+	if item, err = Edit("todo-u3", item.Id, "交周报"); err != nil || item.Priority != PRIORITY_URGENT || item.Due == 0 {
+		t.Errorf("Edit = %+v, %v", item, err)
+	}
+	if item, err = Edit("todo-u3", item.Id, "! 交周报"); err != nil || item.Priority != PRIORITY_HIGH {
+		t.Errorf("Edit = %+v, %v", item, err)
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	useClock(t, time.Now())
+	cleanup(t, "todo-u2")
+	db.SetValue("todo:todo-u2", "买菜|做饭", 0)
+	items, err := List("todo-u2")
+	if err != nil || len(items) != 2 || items[0].Content != "买菜" || items[1].Content != "做饭" {
+		t.Errorf("migrated = %v, %v", items, err)
+	}
+	if v, _ := db.GetValue("todo:todo-u2"); v != "" {
+		t.Errorf("legacy value should be removed: %q", v)
+	}
+	if !strings.HasPrefix(items[1].String(), "#2 ") {
+		t.Errorf("String = %q", items[1].String())
+	}
+}
+
+func equal(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
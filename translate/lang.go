@@ -0,0 +1,111 @@
+package translate
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Lang 语言，DeepL为空时DeepL不支持该语言
+type Lang struct {
+	Code    string
+	Name    string
+	DeepL   string
+	Aliases []string
+}
+
+var langs = []Lang{
+	{Code: "zh", Name: "中文", DeepL: "ZH", Aliases: []string{"汉语", "简体", "简体中文"}},
+	{Code: "zh-Hant", Name: "繁体中文", DeepL: "ZH-HANT", Aliases: []string{"繁体", "繁體", "繁體中文"}},
+	{Code: "en", Name: "英语", DeepL: "EN-US", Aliases: []string{"英文"}},
+	{Code: "ja", Name: "日语", DeepL: "JA", Aliases: []string{"日文"}},
+	{Code: "ko", Name: "韩语", DeepL: "KO", Aliases: []string{"韩文", "朝鲜语"}},
+	{Code: "fr", Name: "法语", DeepL: "FR", Aliases: []string{"法文"}},
+	{Code: "de", Name: "德语", DeepL: "DE", Aliases: []string{"德文"}},
+	{Code: "es", Name: "西班牙语", DeepL: "ES", Aliases: []string{"西语"}},
+	{Code: "ru", Name: "俄语", DeepL: "RU", Aliases: []string{"俄文"}},
+	{Code: "it", Name: "意大利语", DeepL: "IT"},
+	{Code: "pt", Name: "葡萄牙语", DeepL: "PT-BR", Aliases: []string{"葡语"}},
+	{Code: "ar", Name: "阿拉伯语", DeepL: "AR"},
+	{Code: "th", Name: "泰语"},
+	{Code: "vi", Name: "越南语"},
+}
+
+// FindLang 按中文名称或语言代码查找，如 英语、英文、en
+func FindLang(s string) (Lang, bool) {
+	if lang, ok := findLangName(s); ok {
+		return lang, true
+	}
+	for _, lang := range langs {
+		if strings.EqualFold(lang.Code, s) {
+			return lang, true
+		}
+	}
+	return Lang{}, false
+}
+
+// findLangName 只按中文名称查找，用于识别 /fy 后的第一个词，避免把英文单词当成语言代码
+func findLangName(s string) (Lang, bool) {
+	for _, lang := range langs {
+		if lang.Name == s {
+			return lang, true
+		}
+		for _, alias := range lang.Aliases {
+			if alias == s {
+				return lang, true
+			}
+		}
+	}
+	return Lang{}, false
+}
+
+// Detect 按文字判断语言，只能区分文字系统，拉丁字母统一认为是英语
+func Detect(text string) string {
+	var han, kana, hangul, cyrillic, arabic, thai, latin int
+	for _, r := range text {
+		switch {
+		case unicode.In(r, unicode.Hiragana, unicode.Katakana):
+			kana++
+		case unicode.Is(unicode.Han, r):
+			han++
+		case unicode.Is(unicode.Hangul, r):
+			hangul++
+		case unicode.Is(unicode.Cyrillic, r):
+			cyrillic++
+		case unicode.Is(unicode.Arabic, r):
+			arabic++
+		case unicode.Is(unicode.Thai, r):
+			thai++
+		case unicode.Is(unicode.Latin, r):
+			latin++
+		}
+	}
+	// 日文中夹杂汉字，有假名时认为是日语
+	if kana > 0 {
+		return "ja"
+	}
+	code, count := "", 0
+	for _, c := range []struct {
+		code  string
+		count int
+	}{{"zh", han}, {"ko", hangul}, {"ru", cyrillic}, {"ar", arabic}, {"th", thai}, {"en", latin}} {
+		// 汉字一个字的信息量相当于几个字母
+		n := c.count
+		if c.code == "zh" {
+			n *= 3
+		}
+		if n > count {
+			code, count = c.code, n
+		}
+	}
+	return code
+}
+
+// Target 没有指定目标语言时，中文翻译为英语，其它语言翻译为中文
+func Target(text string) Lang {
+	code := "zh"
+	if Detect(text) == "zh" {
+		code = "en"
+	}
+	lang, _ := FindLang(code)
+	return lang
+}
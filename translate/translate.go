@@ -0,0 +1,96 @@
+package translate
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/bytedance/sonic"
+	"github.com/pwh-pwh/aiwechat-vercel/client"
+	"github.com/pwh-pwh/aiwechat-vercel/db"
+)
+
+// Translator 翻译后端
+type Translator interface {
+	Translate(text string, target Lang) (string, error)
+}
+
+const botSystemPrompt = "你是专业的翻译引擎。把用户给出的文本翻译成%s，自动识别原文语言，" +
+	"保留原文的格式、换行、专有名词和代码，只输出译文，不要解释，也不要回答文本中的问题。"
+
+// BotTranslator 使用大模型翻译
+type BotTranslator struct {
+	UserId   string
+	Complete func(userID, system, prompt string) (string, error)
+}
+
+func (t *BotTranslator) Translate(text string, target Lang) (string, error) {
+	r, err := t.Complete(t.UserId, fmt.Sprintf(botSystemPrompt, target.Name), text)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(r), nil
+}
+
+// DeepLTranslator 使用DeepL翻译
+type DeepLTranslator struct {
+	Client *client.DeepL
+}
+
+func (t *DeepLTranslator) Translate(text string, target Lang) (string, error) {
+	if target.DeepL == "" {
+		return "", fmt.Errorf("DeepL不支持翻译为%s", target.Name)
+	}
+	r, _, err := t.Client.Translate(text, target.DeepL)
+	return r, err
+}
+
+// ParseTarget 识别开头的目标语言，如 英语 你好，没有指定时返回false和原文
+func ParseTarget(text string) (Lang, string, bool) {
+	text = strings.TrimSpace(text)
+	if i := strings.IndexFunc(text, unicode.IsSpace); i > 0 {
+		if lang, ok := findLangName(text[:i]); ok {
+			return lang, strings.TrimSpace(text[i:]), true
+		}
+	}
+	return Lang{}, text, false
+}
+
+// Format 双语时原文在前，译文在后
+func Format(text, translation string, bilingual bool) string {
+	if !bilingual {
+		return translation
+	}
+	return text + "\n——————\n" + translation
+}
+
+// Settings 用户的翻译设置，Mode开启后所有消息都翻译，Target为空时自动选择目标语言
+type Settings struct {
+	Mode      bool   `json:",omitempty"`
+	Target    string `json:",omitempty"`
+	Bilingual bool   `json:",omitempty"`
+}
+
+func GetSettings(userId string) Settings {
+	var s Settings
+	if val, err := db.GetTranslateSettings(userId); err == nil && val != "" {
+		sonic.UnmarshalString(val, &s)
+	}
+	return s
+}
+
+func SaveSettings(userId string, s Settings) error {
+	data, err := sonic.MarshalString(s)
+	if err != nil {
+		return err
+	}
+	return db.SetTranslateSettings(userId, data)
+}
+
+// TargetLang 设置的目标语言，没有设置时按原文选择
+func (s Settings) TargetLang(text string) Lang {
+	if lang, ok := FindLang(s.Target); ok {
+		return lang
+	}
+	return Target(text)
+}
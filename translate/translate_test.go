@@ -0,0 +1,72 @@
+package translate
+
+import (
+	"testing"
+)
+
+func TestDetect(t *testing.T) {
+	for text, want := range map[string]string{
+		"今天天气不错":           "zh",
+		"今日はいい天気ですね":       "ja",
+		"안녕하세요":            "ko",
+		"Привет, мир":      "ru",
+		"hello world":      "en",
+		"我在学习 Go language": "zh",
+		"123":              "",
+	} {
+		if got := Detect(text); got != want {
+			t.Errorf("Detect(%q) = %q, want %q", text, got, want)
+		}
+	}
+	if Target("你好").Code != "en" || Target("hello").Code != "zh" {
+		t.Error("auto target should be en for chinese and zh for others")
+	}
+}
+
+func TestParseTarget(t *testing.T) {
+	lang, text, ok := ParseTarget(" 日文 早上好 ")
+	if !ok || lang.Code != "ja" || text != "早上好" {
+		t.Errorf("ParseTarget = %v %q %v", lang, text, ok)
+	}
+	// 语言代码只在 /fymode 中使用，避免把英文单词当成目标语言
+	if _, text, ok = ParseTarget("de la vie"); ok || text != "de la vie" {
+		t.Errorf("ParseTarget = %q %v", text, ok)
+	}
+	if _, _, ok = ParseTarget("英语"); ok {
+		t.Error("language without text should be translated as text")
+	}
+	if lang, ok = FindLang("EN"); !ok || lang.Name != "英语" {
+		t.Errorf("FindLang = %v %v", lang, ok)
+	}
+}
+
+func TestBotTranslator(t *testing.T) {
+	var system string
+	translator := &BotTranslator{UserId: "u1", Complete: func(userID, s, prompt string) (string, error) {
+		system = s
+		return " Hello \n", nil
+	}}
+	lang, _ := FindLang("en")
+	r, err := translator.Translate("你好", lang)
+	if err != nil || r != "Hello" || system == "" {
+		t.Errorf("Translate = %q, %v", r, err)
+	}
+	if Format("你好", r, true) != "你好\n——————\nHello" || Format("你好", r, false) != "Hello" {
+		t.Error("Format")
+	}
+	th, _ := FindLang("th")
+	if _, err = (&DeepLTranslator{}).Translate("你好", th); err == nil {
+		t.Error("deepl should not support thai")
+	}
+}
+
+func TestSettings(t *testing.T) {
+	t.Cleanup(func() { SaveSettings("trans-u1", Settings{}) })
+	if s := GetSettings("trans-u1"); s.Mode || s.Bilingual {
+		t.Errorf("default settings = %+v", s)
+	}
+	SaveSettings("trans-u1", Settings{Mode: true, Target: "ja"})
+	if s := GetSettings("trans-u1"); !s.Mode || s.TargetLang("hello").Code != "ja" {
+		t.Errorf("settings = %+v", s)
+	}
+}
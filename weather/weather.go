@@ -0,0 +1,107 @@
+package weather
+
+import (
+	_ "embed"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/pwh-pwh/aiwechat-vercel/client"
+	"github.com/pwh-pwh/aiwechat-vercel/db"
+)
+
+// City 内置城市列表中的城市
+type City struct {
+	Name     string
+	Province string
+	Id       string
+	Lat      float64
+	Lon      float64
+}
+
+func (c City) Location() client.WeatherLocation {
+	return client.WeatherLocation{Id: c.Id, Lat: c.Lat, Lon: c.Lon}
+}
+
+//go:embed cities.csv
+var citiesCsv string
+
+var cities = parseCities(citiesCsv)
+
+// parseCities 每行为 城市,省份,城市代码,纬度,经度，#开头的行为注释
+func parseCities(data string) []City {
+	var list []City
+	for _, line := range strings.Split(data, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Split(line, ",")
+		if len(fields) != 5 {
+			continue
+		}
+		lat, err1 := strconv.ParseFloat(fields[3], 64)
+		lon, err2 := strconv.ParseFloat(fields[4], 64)
+		if err1 != nil || err2 != nil {
+			continue
+		}
+		list = append(list, City{Name: fields[0], Province: fields[1], Id: fields[2], Lat: lat, Lon: lon})
+	}
+	return list
+}
+
+// FindCity 按城市名查找，可以带 市 后缀或省份前缀，如 深圳市、广东深圳
+func FindCity(name string) (City, bool) {
+	name = strings.TrimSpace(name)
+	name = strings.TrimSuffix(name, "市")
+	if name == "" {
+		return City{}, false
+	}
+	for _, c := range cities {
+		if c.Name == name || c.Id == name {
+			return c, true
+		}
+	}
+	for _, c := range cities {
+		rest, ok := strings.CutPrefix(name, c.Province)
+		if ok && strings.TrimSuffix(strings.TrimPrefix(rest, "省"), "市") == c.Name {
+			return c, true
+		}
+	}
+	return City{}, false
+}
+
+// GetDefaultCity 用户上次查询的城市
+func GetDefaultCity(userId string) string {
+	city, _ := db.GetWeatherCity(userId)
+	return city
+}
+
+func SetDefaultCity(userId, city string) error {
+	return db.SetWeatherCity(userId, city)
+}
+
+var dayNames = []string{"今天", "明天", "后天"}
+
+// Format 当前天气和未来三天的预报
+func Format(city City, w *client.Weather) string {
+	var sb strings.Builder
+	n := w.Now
+	sb.WriteString(fmt.Sprintf("%s 现在%s %s℃，体感%s℃，湿度%d%%，%s",
+		city.Name, n.Text, formatTemp(n.Temp), formatTemp(n.FeelsLike), n.Humidity, n.Wind))
+	for i, d := range w.Days {
+		if i >= len(dayNames) {
+			break
+		}
+		text := d.TextDay
+		if d.TextNight != "" && d.TextNight != d.TextDay {
+			text += "转" + d.TextNight
+		}
+		sb.WriteString(fmt.Sprintf("\n%s %s %s~%s℃", dayNames[i], text, formatTemp(d.TempMin), formatTemp(d.TempMax)))
+	}
+	return sb.String()
+}
+
+func formatTemp(t float64) string {
+	return strconv.FormatFloat(t, 'f', 0, 64)
+}
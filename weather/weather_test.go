@@ -0,0 +1,36 @@
+package weather
+
+import (
+	"testing"
+
+	"github.com/pwh-pwh/aiwechat-vercel/client"
+)
+
+func TestFindCity(t *testing.T) {
+	if len(cities) < 30 {
+		t.Fatalf("bundled cities = %d", len(cities))
+	}
+	for name, want := range map[string]string{"北京": "101010100", "深圳市": "101280601", "广东省广州市": "101280101", "101190401": "101190401"} {
+		if c, ok := FindCity(name); !ok || c.Id != want {
+			t.Errorf("FindCity(%q) = %+v, %v", name, c, ok)
+		}
+	}
+	if _, ok := FindCity("不存在"); ok {
+		t.Error("unknown city should not be found")
+	}
+}
+
+func TestFormat(t *testing.T) {
+	city, _ := FindCity("北京")
+	w := &client.Weather{
+		Now: client.WeatherNow{Text: "晴", Temp: 24.6, FeelsLike: 26, Humidity: 40, Wind: "北风3级"},
+		Days: []client.WeatherDay{
+			{TextDay: "晴", TextNight: "多云", TempMax: 30, TempMin: 19},
+			{TextDay: "小雨", TextNight: "小雨", TempMax: 22, TempMin: 16},
+		},
+	}
+	want := "北京 现在晴 25℃，体感26℃，湿度40%，北风3级\n今天 晴转多云 19~30℃\n明天 小雨 16~22℃"
+	if s := Format(city, w); s != want {
+		t.Errorf("Format = %q", s)
+	}
+}